@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/config"
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/policy"
 	"github.com/ZacharyZcR/NLUI/gateway"
 	"github.com/ZacharyZcR/NLUI/mcp"
 )
@@ -31,6 +33,44 @@ func (r *Router) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 	return "", fmt.Errorf("unknown tool: %s", toolName)
 }
 
+// Preview resolves HTTP tool calls for dry runs. MCP tools can't be
+// previewed and return nil.
+func (r *Router) Preview(ctx context.Context, toolName, argsJSON, authToken string) (interface{}, error) {
+	if !r.HttpCaller.HasTool(toolName) {
+		return nil, nil
+	}
+	p, err := r.HttpCaller.Preview(ctx, toolName, argsJSON, authToken)
+	if p == nil {
+		return nil, err // keep the interface nil
+	}
+	return p, nil
+}
+
+// ToolGroup returns the module group of an HTTP tool, so tool retrieval can
+// match on it. MCP tools have none.
+func (r *Router) ToolGroup(name string) string {
+	return r.HttpCaller.ToolGroup(name)
+}
+
+// DescribeTool describes HTTP tools by endpoint and MCP tools by client and
+// annotations, so the tool policy can match on them.
+func (r *Router) DescribeTool(name string) policy.ToolInfo {
+	if r.HttpCaller.HasTool(name) {
+		return r.HttpCaller.DescribeTool(name)
+	}
+	info := policy.ToolInfo{Name: name}
+	parts := strings.SplitN(name, "__", 2)
+	if len(parts) == 2 {
+		if client, ok := r.McpClients[parts[0]]; ok {
+			info.Target = parts[0]
+			if t, ok := client.Tool(parts[1]); ok {
+				info.Annotations = t.Annotations.Hints()
+			}
+		}
+	}
+	return info
+}
+
 var promptTemplates = map[string]struct {
 	intro   string
 	tools   string
@@ -220,6 +260,7 @@ func Run(cfg *config.Config, onTarget OnTargetFunc) (*Result, error) {
 	fmt.Fprintf(os.Stderr, "Total tools: %d\n", len(allTools))
 
 	caller := gateway.NewCaller(allEndpoints)
+	caller.SetCacheTTL(time.Duration(cfg.Tools.CacheTTLSec) * time.Second)
 	router := &Router{HttpCaller: caller, McpClients: mcpClients}
 	systemPrompt := BuildSystemPrompt(cfg.Language, cfg.Targets, allTools)
 
@@ -0,0 +1,19 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/config"
+	"github.com/ZacharyZcR/NLUI/core/toolloop"
+)
+
+// TurnBudget converts the budget section of cfg into tool loop limits.
+func TurnBudget(cfg *config.Config) toolloop.Budget {
+	b := cfg.Budget
+	return toolloop.Budget{
+		MaxIterations: b.MaxIterations,
+		MaxToolCalls:  b.MaxToolCalls,
+		MaxTokens:     b.MaxTokens,
+		MaxDuration:   time.Duration(b.MaxDurationSec) * time.Second,
+	}
+}
@@ -0,0 +1,113 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/config"
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// NewLLMClient builds the LLM backend described by cfg, wrapped with retries.
+// When llm.fallbacks is set the primary and its fallbacks form a failover chain.
+// llm.cassette records the resulting client's traffic, or replaces it entirely.
+func NewLLMClient(cfg *config.Config) (llm.LLMClient, error) {
+	cas := cfg.LLM.Cassette
+	switch cas.Mode {
+	case "":
+		return newLLMChain(cfg), nil
+	case llm.CassetteRecord:
+		if cas.Path == "" {
+			return nil, fmt.Errorf("llm.cassette.path is required")
+		}
+		return llm.NewCassetteRecorder(newLLMChain(cfg), cas.Path), nil
+	case llm.CassetteReplay:
+		if cas.Path == "" {
+			return nil, fmt.Errorf("llm.cassette.path is required")
+		}
+		return llm.NewCassettePlayer(cas.Path)
+	}
+	return nil, fmt.Errorf("unknown llm.cassette.mode %q (want record or replay)", cas.Mode)
+}
+
+func newLLMChain(cfg *config.Config) llm.LLMClient {
+	c := cfg.LLM
+	primary := newBackend(c.BackendConfig, cfg.Proxy, c.IsStream())
+	if len(c.Fallbacks) == 0 {
+		return llm.NewRetryClient(primary.Client, retryPolicy(c.Retry))
+	}
+
+	backends := []llm.Backend{primary}
+	for _, fb := range c.Fallbacks {
+		stream := c.IsStream()
+		if fb.Stream != nil {
+			stream = *fb.Stream
+		}
+		// Sampling settings not overridden by the fallback carry over from the primary.
+		fb.SamplingConfig = c.SamplingConfig.Merge(fb.SamplingConfig)
+		backends = append(backends, newBackend(fb, cfg.Proxy, stream))
+	}
+	chain := llm.NewFailoverClient(backends, time.Duration(c.FailoverCooldownSec)*time.Second)
+	return llm.NewRetryClient(chain, retryPolicy(c.Retry))
+}
+
+func newBackend(c config.BackendConfig, proxy string, stream bool) llm.Backend {
+	name := c.Name
+	if name == "" {
+		name = c.Model
+	}
+	azure := llm.AzureOptions{Deployment: c.Deployment, APIVersion: c.APIVersion}
+	return llm.Backend{
+		Name:   name,
+		Model:  c.Model,
+		Client: llm.NewAutoClient(c.Provider, c.APIBase, c.APIKey, c.Model, proxy, stream, generationParams(c.SamplingConfig), azure),
+	}
+}
+
+// NewEmbedder returns an embedder for tools.retrieval.embedding_model on the
+// primary LLM backend, or nil when no model is set or the backend has no
+// embeddings API, in which case tool retrieval ranks by keywords only.
+func NewEmbedder(cfg *config.Config) llm.Embedder {
+	model := cfg.Tools.Retrieval.EmbeddingModel
+	if model == "" {
+		return nil
+	}
+	c := cfg.LLM
+	azure := llm.AzureOptions{Deployment: model, APIVersion: c.APIVersion}
+	emb, err := llm.NewEmbeddingClient(c.Provider, c.APIBase, c.APIKey, model, cfg.Proxy, azure)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARN: tools.retrieval: %v; ranking tools by keywords only\n", err)
+		return nil
+	}
+	return emb
+}
+
+func generationParams(s config.SamplingConfig) llm.GenerationParams {
+	p := llm.GenerationParams{
+		Temperature:       s.Temperature,
+		TopP:              s.TopP,
+		Seed:              s.Seed,
+		ParallelToolCalls: s.ParallelToolCalls,
+	}
+	if s.MaxTokens != nil {
+		p.MaxTokens = *s.MaxTokens
+	}
+	if s.ToolChoice != nil {
+		p.ToolChoice = *s.ToolChoice
+	}
+	return p
+}
+
+func retryPolicy(rc config.RetryConfig) llm.RetryPolicy {
+	jitter := llm.DefaultRetryPolicy.Jitter
+	if rc.Jitter != nil {
+		jitter = *rc.Jitter
+	}
+	return llm.RetryPolicy{
+		MaxAttempts: rc.MaxAttempts,
+		BaseDelay:   time.Duration(rc.BaseDelayMs) * time.Millisecond,
+		MaxDelay:    time.Duration(rc.MaxDelayMs) * time.Millisecond,
+		Jitter:      jitter,
+	}
+}
@@ -0,0 +1,23 @@
+package bootstrap
+
+import (
+	"github.com/ZacharyZcR/NLUI/config"
+	"github.com/ZacharyZcR/NLUI/core/policy"
+)
+
+// NewPolicy builds the tool policy from the policy section of cfg.
+func NewPolicy(cfg *config.Config) (*policy.Policy, error) {
+	rules := make([]policy.Rule, 0, len(cfg.Policy.Rules))
+	for _, r := range cfg.Policy.Rules {
+		rules = append(rules, policy.Rule{
+			Name:        r.Name,
+			Action:      policy.Action(r.Action),
+			Tools:       r.Tools,
+			Targets:     r.Targets,
+			Methods:     r.Methods,
+			Paths:       r.Paths,
+			Annotations: r.Annotations,
+		})
+	}
+	return policy.New(rules, policy.Action(cfg.Policy.Default))
+}
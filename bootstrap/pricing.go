@@ -0,0 +1,15 @@
+package bootstrap
+
+import (
+	"github.com/ZacharyZcR/NLUI/config"
+	"github.com/ZacharyZcR/NLUI/core/pricing"
+)
+
+// NewPriceTable builds the price table from the pricing section of cfg.
+func NewPriceTable(cfg *config.Config) *pricing.Table {
+	overrides := make(map[string]pricing.Price, len(cfg.Pricing.Models))
+	for model, p := range cfg.Pricing.Models {
+		overrides[model] = pricing.Price{Input: p.Input, Output: p.Output}
+	}
+	return pricing.NewTable(cfg.Pricing.Currency, overrides)
+}
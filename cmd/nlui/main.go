@@ -8,6 +8,7 @@ import (
 	"github.com/ZacharyZcR/NLUI/bootstrap"
 	"github.com/ZacharyZcR/NLUI/config"
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
 	"github.com/ZacharyZcR/NLUI/engine"
 	"github.com/ZacharyZcR/NLUI/mcp"
 	"github.com/ZacharyZcR/NLUI/server"
@@ -17,6 +18,11 @@ func main() {
 	fmt.Println("NLUI - Natural Language User Interface")
 	fmt.Println("=====================")
 
+	if len(os.Args) > 1 && os.Args[1] == "mock-llm" {
+		runMockLLM(os.Args[2:])
+		return
+	}
+
 	// Parse args: nlui [--mcp|--mcp-sse PORT] [config-path]
 	mcpStdio := false
 	mcpSSEPort := 0
@@ -70,12 +76,32 @@ func main() {
 	}
 
 	// Default: HTTP chat server
+	llmClient, err := bootstrap.NewLLMClient(cfg)
+	if err != nil {
+		log.Fatalf("llm: %v", err)
+	}
+	toolPolicy, err := bootstrap.NewPolicy(cfg)
+	if err != nil {
+		log.Fatalf("tool policy: %v", err)
+	}
 	eng := engine.New(engine.Config{
-		LLM:          llm.NewClient(cfg.LLM.APIBase, cfg.LLM.APIKey, cfg.LLM.Model, cfg.Proxy),
-		Executor:     res.Router,
-		Tools:        res.Tools,
-		SystemPrompt: res.SystemPrompt,
-		MaxCtxTokens: cfg.LLM.MaxCtxTokens,
+		LLM:           llmClient,
+		Executor:      res.Router,
+		Tools:         res.Tools,
+		SystemPrompt:  res.SystemPrompt,
+		MaxCtxTokens:  cfg.LLM.MaxCtxTokens,
+		Compact:       cfg.LLM.SummarizesContext(),
+		TokenCounter:  tokenizer.ForModel(cfg.LLM.Model),
+		Model:         cfg.LLM.Model,
+		Prices:        bootstrap.NewPriceTable(cfg),
+		ToolWorkers:   cfg.Tools.Concurrency,
+		ResultLimit:   cfg.Tools.ResultLimit,
+		ResultLimits:  cfg.Tools.ResultLimits,
+		LoopThreshold: cfg.Tools.LoopThreshold,
+		RetrieveTools: cfg.Tools.Retrieval.TopK,
+		Embedder:      bootstrap.NewEmbedder(cfg),
+		Budget:        bootstrap.TurnBudget(cfg),
+		Policy:        toolPolicy,
 	})
 
 	// Optionally also start MCP SSE server in background
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ZacharyZcR/NLUI/mockllm"
+)
+
+// runMockLLM implements `nlui mock-llm [--port N] script.yaml`.
+func runMockLLM(args []string) {
+	fs := flag.NewFlagSet("mock-llm", flag.ExitOnError)
+	port := fs.Int("port", 9100, "listen port")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: nlui mock-llm [--port N] script.yaml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	script, err := mockllm.LoadScript(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("load script: %v", err)
+	}
+	if err := mockllm.NewServer(script).Run(*port); err != nil {
+		log.Fatalf("mock-llm: %v", err)
+	}
+}
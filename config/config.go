@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -8,20 +10,218 @@ import (
 )
 
 type Config struct {
-	Language string       `yaml:"language"`
-	Proxy    string       `yaml:"proxy"`
-	LLM      LLMConfig    `yaml:"llm"`
-	Targets  []Target     `yaml:"targets"`
-	Server   ServerConfig `yaml:"server"`
-	MCP      MCPConfig    `yaml:"mcp"`
+	Language string        `yaml:"language"`
+	Proxy    string        `yaml:"proxy"`
+	LLM      LLMConfig     `yaml:"llm"`
+	Targets  []Target      `yaml:"targets"`
+	Server   ServerConfig  `yaml:"server"`
+	MCP      MCPConfig     `yaml:"mcp"`
+	Tools    ToolsConfig   `yaml:"tools,omitempty"`
+	Budget   BudgetConfig  `yaml:"budget,omitempty"`
+	Pricing  PricingConfig `yaml:"pricing,omitempty"`
+	Policy   PolicyConfig  `yaml:"policy,omitempty"`
 }
 
 type LLMConfig struct {
-	APIBase      string `yaml:"api_base"`
-	APIKey       string `yaml:"api_key"`
-	Model        string `yaml:"model"`
-	MaxCtxTokens int    `yaml:"max_context_tokens"`
-	Stream       *bool  `yaml:"stream,omitempty"`
+	BackendConfig `yaml:",inline"`
+
+	MaxCtxTokens int         `yaml:"max_context_tokens"`
+	ContextMode  string      `yaml:"context_mode,omitempty"` // truncate (default) | summarize: what happens to history beyond max_context_tokens
+	Retry        RetryConfig `yaml:"retry,omitempty"`
+
+	// Failover: backends tried in order when this one is down or rate limited.
+	Fallbacks           []BackendConfig `yaml:"fallbacks,omitempty"`
+	FailoverCooldownSec int             `yaml:"failover_cooldown_sec,omitempty"` // skip a failed backend this long (default 60)
+
+	Cassette CassetteConfig `yaml:"cassette,omitempty"`
+}
+
+// BackendConfig is one LLM backend: the primary, or an entry of
+// llm.fallbacks. Retries, failover and cassettes apply to the whole chain
+// and are configured on llm only.
+type BackendConfig struct {
+	Provider string `yaml:"provider,omitempty"` // openai | azure | gemini | anthropic; "" = detect from api_base
+	APIBase  string `yaml:"api_base"`
+	APIKey   string `yaml:"api_key"`
+	Model    string `yaml:"model"`
+	Stream   *bool  `yaml:"stream,omitempty"`
+	Name     string `yaml:"name,omitempty"` // label reported in usage events; defaults to model
+
+	SamplingConfig `yaml:",inline"`
+
+	AzureConfig `yaml:",inline"`
+}
+
+// CassetteConfig records LLM exchanges to a file, or replays them from it
+// instead of calling the backend, for offline regression tests.
+type CassetteConfig struct {
+	Mode string `yaml:"mode,omitempty"` // record | replay; "" = off
+	Path string `yaml:"path,omitempty"`
+}
+
+// RetryConfig tunes retries of rate-limited or failed LLM calls. Zero and
+// unset values use defaults, except jitter, where 0 turns randomization off.
+type RetryConfig struct {
+	MaxAttempts int      `yaml:"max_attempts,omitempty"`  // total attempts; 1 disables retries (default 3)
+	BaseDelayMs int      `yaml:"base_delay_ms,omitempty"` // first backoff, doubled per retry (default 1000)
+	MaxDelayMs  int      `yaml:"max_delay_ms,omitempty"`  // cap for backoff and Retry-After (default 30000)
+	Jitter      *float64 `yaml:"jitter,omitempty"`        // 0..1 randomization of the delay; 0 = off (default 0.2)
+}
+
+// AzureConfig addresses an Azure OpenAI deployment (provider: azure). api_base
+// is then the resource endpoint, e.g. https://<resource>.openai.azure.com.
+type AzureConfig struct {
+	Deployment string `yaml:"deployment,omitempty" json:"deployment,omitempty"`   // defaults to model
+	APIVersion string `yaml:"api_version,omitempty" json:"api_version,omitempty"` // default 2024-10-21
+}
+
+// SamplingConfig holds optional generation parameters sent with every LLM
+// request. Unset (nil) fields are left to the backend's defaults.
+type SamplingConfig struct {
+	Temperature       *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	MaxTokens         *int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	TopP              *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	Seed              *int     `yaml:"seed,omitempty" json:"seed,omitempty"`
+	ParallelToolCalls *bool    `yaml:"parallel_tool_calls,omitempty" json:"parallel_tool_calls,omitempty"`
+	ToolChoice        *string  `yaml:"tool_choice,omitempty" json:"tool_choice,omitempty"` // auto | none | required | <tool name>
+}
+
+// Merge returns s with every field that is set in o overriding it.
+func (s SamplingConfig) Merge(o SamplingConfig) SamplingConfig {
+	if o.Temperature != nil {
+		s.Temperature = o.Temperature
+	}
+	if o.MaxTokens != nil {
+		s.MaxTokens = o.MaxTokens
+	}
+	if o.TopP != nil {
+		s.TopP = o.TopP
+	}
+	if o.Seed != nil {
+		s.Seed = o.Seed
+	}
+	if o.ParallelToolCalls != nil {
+		s.ParallelToolCalls = o.ParallelToolCalls
+	}
+	if o.ToolChoice != nil {
+		s.ToolChoice = o.ToolChoice
+	}
+	return s
+}
+
+// samplingFields are the JSON names of the SamplingConfig fields.
+var samplingFields = []string{"temperature", "max_tokens", "top_p", "seed", "parallel_tool_calls", "tool_choice"}
+
+// SamplingUpdate changes a SamplingConfig. Decoded from JSON, fields sent
+// with a value go to Set and fields sent as null to Clear; omitted fields
+// are left alone.
+type SamplingUpdate struct {
+	Set   SamplingConfig
+	Clear []string // JSON names of fields to unset, e.g. "temperature"
+}
+
+func (u *SamplingUpdate) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &u.Set); err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	u.Clear = nil
+	for _, name := range samplingFields {
+		if v, ok := raw[name]; ok && string(v) == "null" {
+			u.Clear = append(u.Clear, name)
+		}
+	}
+	return nil
+}
+
+// Apply returns s with u's set fields overriding it and its cleared fields
+// unset.
+func (s SamplingConfig) Apply(u SamplingUpdate) SamplingConfig {
+	s = s.Merge(u.Set)
+	for _, name := range u.Clear {
+		switch name {
+		case "temperature":
+			s.Temperature = nil
+		case "max_tokens":
+			s.MaxTokens = nil
+		case "top_p":
+			s.TopP = nil
+		case "seed":
+			s.Seed = nil
+		case "parallel_tool_calls":
+			s.ParallelToolCalls = nil
+		case "tool_choice":
+			s.ToolChoice = nil
+		}
+	}
+	return s
+}
+
+// ToolsConfig tunes how the tool loop executes tool calls.
+type ToolsConfig struct {
+	Concurrency   int             `yaml:"concurrency,omitempty"`    // parallel calls per assistant message; 1 = sequential (default 4)
+	ResultLimit   int             `yaml:"result_limit,omitempty"`   // bytes of a tool result the model sees (default 4000)
+	ResultLimits  map[string]int  `yaml:"result_limits,omitempty"`  // per tool name or glob, e.g. "github__*": 8000
+	LoopThreshold int             `yaml:"loop_threshold,omitempty"` // identical calls in a row that count as a loop (default 3, negative = off)
+	CacheTTLSec   int             `yaml:"cache_ttl_sec,omitempty"`  // reuse GET/HEAD results within a turn for this long (0 = off)
+	Retrieval     RetrievalConfig `yaml:"retrieval,omitempty"`
+}
+
+// RetrievalConfig narrows large toolsets: each turn only the top_k tools most
+// relevant to the conversation are sent, plus a search_tools meta-tool the
+// model can use to find the rest.
+type RetrievalConfig struct {
+	TopK           int    `yaml:"top_k,omitempty"`           // tools sent per turn; 0 = send all tools
+	EmbeddingModel string `yaml:"embedding_model,omitempty"` // adds embedding similarity to keyword ranking, using the llm backend
+}
+
+// BudgetConfig limits each chat turn. When a limit is reached the model is
+// asked for a final summary instead of continuing. Zero = unlimited, except
+// max_iterations which defaults to 25.
+type BudgetConfig struct {
+	MaxIterations  int `yaml:"max_iterations,omitempty"`   // LLM calls per turn
+	MaxToolCalls   int `yaml:"max_tool_calls,omitempty"`   // tool executions per turn
+	MaxTokens      int `yaml:"max_tokens,omitempty"`       // total tokens per turn
+	MaxDurationSec int `yaml:"max_duration_sec,omitempty"` // wall clock per turn
+}
+
+// PolicyConfig decides which tool calls run, need confirmation or are denied.
+// Rules are tried in order before the built-in ones; the first match wins.
+type PolicyConfig struct {
+	Default string       `yaml:"default,omitempty"` // allow | confirm | deny when no rule matches (default allow)
+	Rules   []PolicyRule `yaml:"rules,omitempty"`
+}
+
+// PolicyRule matches when every field that is set matches. tools, targets and
+// paths take globs (* within a path segment, ** across segments).
+type PolicyRule struct {
+	Name        string          `yaml:"name,omitempty"`
+	Action      string          `yaml:"action"` // allow | confirm | deny
+	Tools       []string        `yaml:"tools,omitempty"`
+	Targets     []string        `yaml:"targets,omitempty"` // target or MCP client names
+	Methods     []string        `yaml:"methods,omitempty"`
+	Paths       []string        `yaml:"paths,omitempty"`
+	Annotations map[string]bool `yaml:"annotations,omitempty"` // MCP hints, e.g. destructiveHint: true
+}
+
+// PricingConfig converts token usage into cost. Prices are per million tokens.
+type PricingConfig struct {
+	Currency string                `yaml:"currency,omitempty"` // default USD; built-in prices only apply to USD
+	Models   map[string]ModelPrice `yaml:"models,omitempty"`   // model name or prefix → price, overriding the built-in table
+}
+
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// SummarizesContext reports whether history beyond the context budget is
+// summarized rather than dropped.
+func (c LLMConfig) SummarizesContext() bool {
+	return c.ContextMode == "summarize"
 }
 
 func (c LLMConfig) IsStream() bool {
@@ -102,6 +302,9 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	if err := checkFallbacks(data); err != nil {
+		return nil, err
+	}
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 9000
 	}
@@ -110,3 +313,27 @@ func Load(path string) (*Config, error) {
 	}
 	return &cfg, nil
 }
+
+// chainSettings apply to the whole failover chain and are only read from llm.
+var chainSettings = []string{"max_context_tokens", "context_mode", "retry", "fallbacks", "failover_cooldown_sec", "cassette"}
+
+// checkFallbacks rejects chain settings on llm.fallbacks entries, which would
+// otherwise be ignored without notice.
+func checkFallbacks(data []byte) error {
+	var raw struct {
+		LLM struct {
+			Fallbacks []map[string]interface{} `yaml:"fallbacks"`
+		} `yaml:"llm"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for i, fb := range raw.LLM.Fallbacks {
+		for _, key := range chainSettings {
+			if _, ok := fb[key]; ok {
+				return fmt.Errorf("llm.fallbacks[%d].%s: set it on llm instead; it applies to every backend", i, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSamplingUpdateClearsNullFields(t *testing.T) {
+	temp, maxTokens, choice := 0.2, 512, "auto"
+	current := SamplingConfig{Temperature: &temp, MaxTokens: &maxTokens, ToolChoice: &choice}
+
+	var u SamplingUpdate
+	if err := json.Unmarshal([]byte(`{"api_base":"x","temperature":null,"top_p":0.9,"tool_choice":null}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	got := current.Apply(u)
+
+	if got.Temperature != nil || got.ToolChoice != nil {
+		t.Errorf("null fields not cleared: temperature %v, tool_choice %v", got.Temperature, got.ToolChoice)
+	}
+	if got.MaxTokens == nil || *got.MaxTokens != 512 {
+		t.Errorf("omitted max_tokens changed: %v", got.MaxTokens)
+	}
+	if got.TopP == nil || *got.TopP != 0.9 {
+		t.Errorf("top_p not set: %v", got.TopP)
+	}
+}
@@ -22,6 +22,35 @@ type Conversation struct {
 	UpdatedAt      time.Time     `json:"updated_at"`
 	EnabledSources []string      `json:"enabled_sources,omitempty"` // 启用的 source（MCP/Target），空表示全部启用
 	DisabledTools  []string      `json:"disabled_tools,omitempty"`  // 单独禁用的工具（完整名 source__tool）
+	Budget         *Budget       `json:"budget,omitempty"`          // 覆盖引擎的单轮预算
+	DryRun         bool          `json:"dry_run,omitempty"`         // 只预览工具请求，不真正发送
+	Summary        *Summary      `json:"summary,omitempty"`         // 超出上下文预算的早期历史摘要
+	Usage          *UsageTotals  `json:"usage,omitempty"`           // 累计 token 用量与费用
+	UsageLog       []UsageRecord `json:"usage_log,omitempty"`       // 每轮对话的用量明细
+}
+
+// Budget overrides the engine's per-turn limits for one conversation. Zero
+// fields keep the engine's value.
+type Budget struct {
+	MaxIterations  int `json:"max_iterations,omitempty"`
+	MaxToolCalls   int `json:"max_tool_calls,omitempty"`
+	MaxTokens      int `json:"max_tokens,omitempty"`
+	MaxDurationSec int `json:"max_duration_sec,omitempty"`
+}
+
+// Summary condenses the messages before Until (after the system prompt) once
+// they no longer fit the context. Messages stay stored in full.
+type Summary struct {
+	Text      string    `json:"text"`
+	Until     int       `json:"until"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// dropStaleSummary forgets the summary when a message it covers changes.
+func (c *Conversation) dropStaleSummary(index int) {
+	if c.Summary != nil && index < c.Summary.Until {
+		c.Summary = nil
+	}
 }
 
 type Manager struct {
@@ -109,6 +138,7 @@ func (m *Manager) UpdateMessages(id string, messages []llm.Message) {
 	defer m.mu.Unlock()
 	if conv, ok := m.convs[id]; ok {
 		conv.Messages = messages
+		conv.dropStaleSummary(len(messages))
 		conv.UpdatedAt = time.Now()
 		m.saveLocked(conv)
 	}
@@ -137,6 +167,7 @@ func (m *Manager) EditMessage(id string, index int, newContent string) error {
 	}
 	conv.Messages[index].Content = newContent
 	conv.Messages = conv.Messages[:index+1]
+	conv.dropStaleSummary(index)
 	conv.UpdatedAt = time.Now()
 	m.saveLocked(conv)
 	return nil
@@ -154,6 +185,7 @@ func (m *Manager) DeleteMessagesFrom(id string, index int) error {
 		return fmt.Errorf("invalid message index")
 	}
 	conv.Messages = conv.Messages[:index]
+	conv.dropStaleSummary(index)
 	conv.UpdatedAt = time.Now()
 	m.saveLocked(conv)
 	return nil
@@ -171,6 +203,7 @@ func (m *Manager) DeleteMessage(id string, index int) error {
 		return fmt.Errorf("invalid message index")
 	}
 	conv.Messages = append(conv.Messages[:index], conv.Messages[index+1:]...)
+	conv.dropStaleSummary(index)
 	conv.UpdatedAt = time.Now()
 	m.saveLocked(conv)
 	return nil
@@ -191,6 +224,55 @@ func (m *Manager) UpdateToolConfig(id string, enabledSources, disabledTools []st
 	return nil
 }
 
+// UpdateBudget sets the conversation's budget override; nil removes it.
+func (m *Manager) UpdateBudget(id string, budget *Budget) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv, ok := m.convs[id]
+	if !ok {
+		return fmt.Errorf("conversation not found")
+	}
+	conv.Budget = budget
+	conv.UpdatedAt = time.Now()
+	m.saveLocked(conv)
+	return nil
+}
+
+// UpdateDryRun turns dry-run mode on or off for the conversation.
+func (m *Manager) UpdateDryRun(id string, on bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv, ok := m.convs[id]
+	if !ok {
+		return fmt.Errorf("conversation not found")
+	}
+	conv.DryRun = on
+	conv.UpdatedAt = time.Now()
+	m.saveLocked(conv)
+	return nil
+}
+
+// SetSummary stores the compacted history summary; nil removes it.
+func (m *Manager) SetSummary(id string, summary *Summary) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conv, ok := m.convs[id]; ok {
+		conv.Summary = summary
+		m.saveLocked(conv)
+	}
+}
+
+// DropSummaryFrom forgets the conversation's summary when it covers the
+// message at index or later, e.g. before those messages are regenerated.
+func (m *Manager) DropSummaryFrom(id string, index int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conv, ok := m.convs[id]; ok && conv.Summary != nil && index < conv.Summary.Until {
+		conv.dropStaleSummary(index)
+		m.saveLocked(conv)
+	}
+}
+
 // --- persistence ---
 
 func (m *Manager) saveLocked(conv *Conversation) {
@@ -1,7 +1,9 @@
 package conversation
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/core/llm"
 )
@@ -178,3 +180,70 @@ func TestEditMessageNotFound(t *testing.T) {
 		t.Fatal("expected error for not found")
 	}
 }
+
+func TestPersistAttachments(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+	conv := m.Create("test", "")
+	m.UpdateMessages(conv.ID, []llm.Message{{
+		Role:    "user",
+		Content: "what is this error?",
+		Parts: []llm.ContentPart{
+			{Type: "image_url", ImageURL: &llm.ImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+			{Type: "file", File: &llm.FilePart{Filename: "trace.txt", FileData: "data:text/plain;base64,Ym9vbQ=="}},
+		},
+	}})
+
+	reloaded := NewManager(dir).Get(conv.ID)
+	if reloaded == nil || len(reloaded.Messages) != 1 {
+		t.Fatal("conversation not reloaded")
+	}
+	parts := reloaded.Messages[0].Parts
+	if len(parts) != 2 || parts[0].ImageURL == nil || parts[1].File == nil || parts[1].File.Filename != "trace.txt" {
+		t.Fatalf("parts not persisted: %+v", parts)
+	}
+}
+
+func TestUsageReport(t *testing.T) {
+	m := NewManager("")
+	a := m.Create("a", "")
+	b := m.Create("b", "")
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+
+	m.AddUsage(a.ID, UsageRecord{Time: day1, Model: "gpt-4o", TotalTokens: 100, Cost: 0.5, Currency: "USD"})
+	m.AddUsage(a.ID, UsageRecord{Time: day2, Model: "gpt-4o-mini", TotalTokens: 50, Cost: 0.1, Currency: "USD"})
+	m.AddUsage(b.ID, UsageRecord{Time: day2, Model: "gpt-4o", TotalTokens: 10, Cost: 1, Currency: "USD"})
+
+	if u := m.Get(a.ID).Usage; u == nil || u.Turns != 2 || u.TotalTokens != 150 {
+		t.Fatalf("conversation totals = %+v", u)
+	}
+
+	rep := m.UsageReport(time.Time{}, time.Time{})
+	if rep.Total.Turns != 3 || math.Abs(rep.Total.Cost-1.6) > 1e-9 || rep.Total.Currency != "USD" {
+		t.Errorf("total = %+v", rep.Total)
+	}
+	if len(rep.ByDay) != 2 || rep.ByDay[0].Key != "2026-03-01" || rep.ByDay[1].Turns != 2 {
+		t.Errorf("by day = %+v", rep.ByDay)
+	}
+	if len(rep.ByModel) != 2 || rep.ByModel[0].Key != "gpt-4o" || rep.ByModel[0].TotalTokens != 110 {
+		t.Errorf("by model = %+v", rep.ByModel)
+	}
+	if len(rep.ByConversation) != 2 || rep.ByConversation[0].Title != "b" {
+		t.Errorf("by conversation = %+v", rep.ByConversation)
+	}
+
+	rep = m.UsageReport(day2, time.Time{})
+	if rep.Total.Turns != 2 {
+		t.Errorf("from filter: %d turns, want 2", rep.Total.Turns)
+	}
+
+	// A turn that failed over is split between the models that served it.
+	m.AddUsage(b.ID, UsageRecord{Time: day2, Model: "gpt-4o-mini", TotalTokens: 30, Cost: 0.3, Currency: "USD",
+		Models: []ModelUsage{{Model: "gpt-4o", TotalTokens: 20, Cost: 0.25}, {Model: "gpt-4o-mini", TotalTokens: 10, Cost: 0.05}}})
+	rep = m.UsageReport(time.Time{}, time.Time{})
+	if len(rep.ByModel) != 2 || rep.ByModel[0].TotalTokens != 130 || rep.ByModel[1].TotalTokens != 60 ||
+		math.Abs(rep.ByModel[0].Cost-1.75) > 1e-9 {
+		t.Errorf("by model after failover = %+v", rep.ByModel)
+	}
+}
@@ -0,0 +1,167 @@
+package conversation
+
+import (
+	"sort"
+	"time"
+)
+
+// UsageRecord is the token usage and cost of one chat turn.
+type UsageRecord struct {
+	Time             time.Time    `json:"time"`
+	Model            string       `json:"model,omitempty"` // last model used
+	PromptTokens     int          `json:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens"`
+	TotalTokens      int          `json:"total_tokens"`
+	Cost             float64      `json:"cost,omitempty"`
+	Currency         string       `json:"currency,omitempty"`
+	Models           []ModelUsage `json:"models,omitempty"` // split by the model serving each call
+}
+
+// ModelUsage is the part of a turn's usage served by one model.
+type ModelUsage struct {
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+}
+
+// byModel splits r per model. Records without a breakdown are attributed
+// to their model as a whole.
+func (r UsageRecord) byModel() []UsageRecord {
+	if len(r.Models) == 0 {
+		return []UsageRecord{r}
+	}
+	parts := make([]UsageRecord, len(r.Models))
+	for i, m := range r.Models {
+		parts[i] = UsageRecord{
+			Time:             r.Time,
+			Model:            m.Model,
+			PromptTokens:     m.PromptTokens,
+			CompletionTokens: m.CompletionTokens,
+			TotalTokens:      m.TotalTokens,
+			Cost:             m.Cost,
+			Currency:         r.Currency,
+		}
+	}
+	return parts
+}
+
+// UsageTotals sums usage records. Currency is "mixed" when records were
+// priced in different currencies.
+type UsageTotals struct {
+	Turns            int     `json:"turns"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	Currency         string  `json:"currency,omitempty"`
+}
+
+func (t *UsageTotals) add(r UsageRecord) {
+	t.Turns++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.TotalTokens += r.TotalTokens
+	t.Cost += r.Cost
+	switch {
+	case r.Currency == "" || r.Currency == t.Currency:
+	case t.Currency == "":
+		t.Currency = r.Currency
+	default:
+		t.Currency = "mixed"
+	}
+}
+
+// UsageGroup is one row of a UsageReport breakdown.
+type UsageGroup struct {
+	Key   string `json:"key"`             // day (YYYY-MM-DD), model or conversation ID
+	Title string `json:"title,omitempty"` // conversation title
+	UsageTotals
+}
+
+// UsageReport aggregates usage across conversations.
+type UsageReport struct {
+	Total          UsageTotals  `json:"total"`
+	ByDay          []UsageGroup `json:"by_day"`
+	ByModel        []UsageGroup `json:"by_model"`
+	ByConversation []UsageGroup `json:"by_conversation"`
+}
+
+// AddUsage records a turn's usage on the conversation.
+func (m *Manager) AddUsage(id string, r UsageRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conv, ok := m.convs[id]; ok {
+		conv.UsageLog = append(conv.UsageLog, r)
+		if conv.Usage == nil {
+			conv.Usage = &UsageTotals{}
+		}
+		conv.Usage.add(r)
+		m.saveLocked(conv)
+	}
+}
+
+// UsageReport sums the usage of turns in [from, to); zero bounds are open.
+// Days are in local time. Usage of deleted conversations is not included.
+func (m *Manager) UsageReport(from, to time.Time) UsageReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rep UsageReport
+	days := make(map[string]*UsageGroup)
+	models := make(map[string]*UsageGroup)
+	convs := make(map[string]*UsageGroup)
+	group := func(groups map[string]*UsageGroup, key string) *UsageGroup {
+		g, ok := groups[key]
+		if !ok {
+			g = &UsageGroup{Key: key}
+			groups[key] = g
+		}
+		return g
+	}
+
+	for _, conv := range m.convs {
+		for _, r := range conv.UsageLog {
+			if (!from.IsZero() && r.Time.Before(from)) || (!to.IsZero() && !r.Time.Before(to)) {
+				continue
+			}
+			rep.Total.add(r)
+			group(days, r.Time.Local().Format("2006-01-02")).add(r)
+			for _, part := range r.byModel() {
+				group(models, part.Model).add(part)
+			}
+			g := group(convs, conv.ID)
+			g.Title = conv.Title
+			g.add(r)
+		}
+	}
+
+	rep.ByDay = sortedGroups(days, func(a, b *UsageGroup) bool { return a.Key < b.Key })
+	rep.ByModel = sortedGroups(models, byCost)
+	rep.ByConversation = sortedGroups(convs, byCost)
+	return rep
+}
+
+func byCost(a, b *UsageGroup) bool {
+	if a.Cost != b.Cost {
+		return a.Cost > b.Cost
+	}
+	if a.TotalTokens != b.TotalTokens {
+		return a.TotalTokens > b.TotalTokens
+	}
+	return a.Key < b.Key
+}
+
+func sortedGroups(groups map[string]*UsageGroup, less func(a, b *UsageGroup) bool) []UsageGroup {
+	list := make([]*UsageGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return less(list[i], list[j]) })
+	out := make([]UsageGroup, len(list))
+	for i, g := range list {
+		out[i] = *g
+	}
+	return out
+}
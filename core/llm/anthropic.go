@@ -0,0 +1,490 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+
+	// anthropicEmptyTurn stands in for an assistant message with neither
+	// text nor tool calls, which would otherwise leave a gap in the
+	// alternating turns; the API rejects empty text blocks.
+	anthropicEmptyTurn = "(no answer)"
+)
+
+// AnthropicClient talks to Anthropic's Messages API natively.
+type AnthropicClient struct {
+	apiBase    string
+	apiKey     string
+	model      string
+	stream     bool
+	params     GenerationParams
+	httpClient *http.Client
+}
+
+func NewAnthropicClient(apiBase, apiKey, model, proxy string) *AnthropicClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		if proxyURL, err := url.Parse(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	return &AnthropicClient{
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		stream:     true,
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+// ── Anthropic request/response types ─────────────────────────────────
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
+
+	Temperature *float64             `json:"temperature,omitempty"`
+	TopP        *float64             `json:"top_p,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"` // auto | any | none | tool
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// thinking (responses only; never sent back)
+	Thinking string `json:"thinking,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// image / document
+	Source *anthropicSource `json:"source,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"` // "base64" | "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Role       string           `json:"role"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      *anthropicUsage  `json:"usage,omitempty"`
+}
+
+// anthropicEvent is the union of all SSE event payloads; the "type" field
+// discriminates message_start, content_block_*, message_delta, error, etc.
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text,omitempty"`
+		Thinking    string `json:"thinking,omitempty"`
+		PartialJSON string `json:"partial_json,omitempty"`
+		StopReason  string `json:"stop_reason,omitempty"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ── ChatStreamWithTools ──────────────────────────────────────────────
+
+func (a *AnthropicClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	reqBody := a.buildRequest(ctx, messages, tools)
+	reqBody.Stream = a.stream
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.apiBase+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	if a.apiKey != "" {
+		httpReq.Header.Set("x-api-key", a.apiKey)
+	}
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, newAPIError("anthropic", resp)
+	}
+
+	if a.stream {
+		return a.parseSSE(resp.Body, onDelta, onReasoning)
+	}
+	return a.parseJSON(resp.Body, onDelta, onReasoning)
+}
+
+// ── internal helpers ─────────────────────────────────────────────────
+
+func (a *AnthropicClient) buildRequest(ctx context.Context, messages []Message, tools []Tool) anthropicRequest {
+	req := anthropicRequest{
+		Model:     a.model,
+		MaxTokens: anthropicMaxTokens,
+	}
+
+	for _, t := range tools {
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	// appendBlocks merges consecutive same-role turns, since tool results
+	// must be sent as user content and the API expects alternating roles.
+	appendBlocks := func(role string, blocks ...anthropicBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			return
+		}
+		req.Messages = append(req.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	var system []string
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			if m.Content != "" {
+				system = append(system, m.Content)
+			}
+
+		case "user":
+			var blocks []anthropicBlock
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			blocks = append(blocks, anthropicAttachmentBlocks(m.Parts)...)
+			appendBlocks("user", blocks...)
+
+		case "assistant":
+			var blocks []anthropicBlock
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			if len(blocks) == 0 {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: anthropicEmptyTurn})
+			}
+			appendBlocks("assistant", blocks...)
+
+		case "tool":
+			appendBlocks("user", anthropicBlock{
+				Type:      "tool_result",
+				ToolUseID: m.ToolCallID,
+				Content:   m.Content,
+				IsError:   m.IsError,
+			})
+		}
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	a.applyParams(&req, a.params.forContext(ctx))
+	return req
+}
+
+// applyParams translates GenerationParams into Messages API fields. The API
+// has no seed; parallel_tool_calls=false maps to disable_parallel_tool_use.
+func (a *AnthropicClient) applyParams(req *anthropicRequest, p GenerationParams) {
+	if p.MaxTokens > 0 {
+		req.MaxTokens = p.MaxTokens
+	}
+	req.Temperature = p.Temperature
+	req.TopP = p.TopP
+	if len(req.Tools) == 0 {
+		return
+	}
+	disableParallel := p.ParallelToolCalls != nil && !*p.ParallelToolCalls
+	if p.ToolChoice == "" && !disableParallel {
+		return
+	}
+	tc := &anthropicToolChoice{Type: "auto"}
+	switch p.ToolChoice {
+	case "", ToolChoiceAuto:
+	case ToolChoiceNone:
+		tc.Type = "none"
+	case ToolChoiceRequired:
+		tc.Type = "any"
+	default:
+		tc.Type = "tool"
+		tc.Name = p.ToolChoice
+	}
+	if tc.Type != "none" {
+		tc.DisableParallelToolUse = disableParallel
+	}
+	req.ToolChoice = tc
+}
+
+// anthropicAttachmentBlocks maps content parts to image/document blocks.
+func anthropicAttachmentBlocks(parts []ContentPart) []anthropicBlock {
+	var out []anthropicBlock
+	for _, p := range parts {
+		if p.Type == "text" {
+			out = append(out, anthropicBlock{Type: "text", Text: p.Text})
+			continue
+		}
+		u := p.URL()
+		if u == "" {
+			continue
+		}
+		blockType := "document"
+		if strings.HasPrefix(p.MIMEType(), "image/") {
+			blockType = "image"
+		}
+		src := &anthropicSource{Type: "url", URL: u}
+		if mimeType, data, ok := ParseDataURL(u); ok {
+			src = &anthropicSource{Type: "base64", MediaType: mimeType, Data: data}
+		}
+		out = append(out, anthropicBlock{Type: blockType, Source: src})
+	}
+	return out
+}
+
+func (a *AnthropicClient) parseSSE(r io.Reader, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	assembled := &Message{Role: "assistant"}
+	usage := &Usage{}
+	// content block index → position in assembled.ToolCalls
+	toolIdx := make(map[int]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+			continue
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil && ev.Message.Usage != nil {
+				usage.PromptTokens = ev.Message.Usage.InputTokens
+				usage.CompletionTokens = ev.Message.Usage.OutputTokens
+			}
+
+		case "content_block_start":
+			if ev.ContentBlock == nil {
+				continue
+			}
+			switch ev.ContentBlock.Type {
+			case "text":
+				if ev.ContentBlock.Text != "" {
+					assembled.Content += ev.ContentBlock.Text
+					if onDelta != nil {
+						onDelta(ev.ContentBlock.Text)
+					}
+				}
+			case "tool_use":
+				toolIdx[ev.Index] = len(assembled.ToolCalls)
+				assembled.ToolCalls = append(assembled.ToolCalls, ToolCall{
+					ID:       ev.ContentBlock.ID,
+					Type:     "function",
+					Function: FunctionCall{Name: ev.ContentBlock.Name},
+				})
+			}
+
+		case "content_block_delta":
+			if ev.Delta == nil {
+				continue
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				assembled.Content += ev.Delta.Text
+				if onDelta != nil && ev.Delta.Text != "" {
+					onDelta(ev.Delta.Text)
+				}
+			case "thinking_delta":
+				if onReasoning != nil && ev.Delta.Thinking != "" {
+					onReasoning(ev.Delta.Thinking)
+				}
+			case "input_json_delta":
+				if i, ok := toolIdx[ev.Index]; ok {
+					assembled.ToolCalls[i].Function.Arguments += ev.Delta.PartialJSON
+				}
+			}
+
+		case "message_delta":
+			if ev.Usage != nil {
+				usage.CompletionTokens = ev.Usage.OutputTokens
+			}
+
+		case "message_stop":
+			// The stream may stay open briefly after message_stop; stop reading.
+			return finishAnthropic(assembled), usageOrNil(usage), nil
+
+		case "error":
+			if ev.Error != nil {
+				return nil, usageOrNil(usage), &APIError{
+					Provider:   "anthropic",
+					StatusCode: anthropicErrorStatus(ev.Error.Type),
+					Body:       ev.Error.Type + ": " + ev.Error.Message,
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, usageOrNil(usage), err
+	}
+
+	return finishAnthropic(assembled), usageOrNil(usage), nil
+}
+
+func (a *AnthropicClient) parseJSON(r io.Reader, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	var resp anthropicResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	assembled := &Message{Role: "assistant"}
+	for _, b := range resp.Content {
+		switch b.Type {
+		case "text":
+			assembled.Content += b.Text
+		case "thinking":
+			if onReasoning != nil && b.Thinking != "" {
+				onReasoning(b.Thinking)
+			}
+		case "tool_use":
+			args := string(b.Input)
+			assembled.ToolCalls = append(assembled.ToolCalls, ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+
+	if assembled.Content != "" && onDelta != nil {
+		onDelta(assembled.Content)
+	}
+
+	var usage *Usage
+	if resp.Usage != nil {
+		usage = &Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		}
+	}
+	return finishAnthropic(assembled), usage, nil
+}
+
+// finishAnthropic gives tool calls without any streamed input "{}" arguments,
+// which is what the executors expect for parameterless tools.
+func finishAnthropic(msg *Message) *Message {
+	for i := range msg.ToolCalls {
+		if msg.ToolCalls[i].Function.Arguments == "" {
+			msg.ToolCalls[i].Function.Arguments = "{}"
+		}
+	}
+	return msg
+}
+
+// usageOrNil returns nil when the stream never reported token counts.
+func usageOrNil(u *Usage) *Usage {
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return nil
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// anthropicErrorStatus maps an in-stream error type to the HTTP status the
+// API would have returned for it, so retry logic can treat both alike.
+func anthropicErrorStatus(errType string) int {
+	switch errType {
+	case "overloaded_error":
+		return statusOverloaded
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "api_error":
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
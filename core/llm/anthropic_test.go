@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicBuildRequest(t *testing.T) {
+	a := NewAnthropicClient("https://api.anthropic.com/v1", "k", "claude-test", "")
+	req := a.buildRequest(context.Background(), []Message{
+		{Role: "system", Content: "be nice"},
+		{Role: "user", Content: "list pets"},
+		{Role: "assistant", Content: "sure", ToolCalls: []ToolCall{
+			{ID: "tu_1", Function: FunctionCall{Name: "listPets", Arguments: `{"limit":2}`}},
+			{ID: "tu_2", Function: FunctionCall{Name: "countPets", Arguments: ""}},
+		}},
+		{Role: "tool", ToolCallID: "tu_1", Content: "[1,2]"},
+		{Role: "tool", ToolCallID: "tu_2", Content: "2"},
+		{Role: "user", Content: "thanks"},
+	}, []Tool{{Type: "function", Function: ToolFunction{Name: "listPets", Description: "List pets"}}})
+
+	if req.System != "be nice" {
+		t.Errorf("system = %q, want hoisted system prompt", req.System)
+	}
+	if req.MaxTokens <= 0 {
+		t.Error("max_tokens must be set")
+	}
+	if len(req.Tools) != 1 || req.Tools[0].InputSchema == nil {
+		t.Fatalf("tools not mapped: %+v", req.Tools)
+	}
+
+	// user, assistant, user(tool_result x2 + text)
+	if len(req.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d: %+v", len(req.Messages), req.Messages)
+	}
+	asst := req.Messages[1]
+	if asst.Role != "assistant" || len(asst.Content) != 3 {
+		t.Fatalf("assistant turn = %+v", asst)
+	}
+	if asst.Content[1].Type != "tool_use" || string(asst.Content[1].Input) != `{"limit":2}` {
+		t.Errorf("tool_use block = %+v", asst.Content[1])
+	}
+	if string(asst.Content[2].Input) != "{}" {
+		t.Errorf("empty args should become {}, got %s", asst.Content[2].Input)
+	}
+	results := req.Messages[2]
+	if results.Role != "user" || len(results.Content) != 3 {
+		t.Fatalf("tool results turn = %+v", results)
+	}
+	if results.Content[0].Type != "tool_result" || results.Content[0].ToolUseID != "tu_1" {
+		t.Errorf("tool_result block = %+v", results.Content[0])
+	}
+	if results.Content[2].Type != "text" || results.Content[2].Text != "thanks" {
+		t.Errorf("trailing user text = %+v", results.Content[2])
+	}
+}
+
+func TestAnthropicBuildRequestKeepsTurnsValid(t *testing.T) {
+	a := NewAnthropicClient("https://api.anthropic.com/v1", "k", "claude-test", "")
+	req := a.buildRequest(context.Background(), []Message{
+		{Role: "user", Content: "delete pet 7"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "tu_1", Function: FunctionCall{Name: "deletePet", Arguments: `{"id":7}`}}}},
+		{Role: "tool", ToolCallID: "tu_1", Content: "Operation canceled by user", IsError: true},
+		{Role: "assistant"}, // a wrap-up answer whose tool calls were dropped
+		{Role: "user", Content: "why?"},
+	}, nil)
+
+	roles := make([]string, len(req.Messages))
+	for i, m := range req.Messages {
+		roles[i] = m.Role
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,user,assistant,user" {
+		t.Fatalf("roles = %s", got)
+	}
+	if b := req.Messages[2].Content[0]; b.Type != "tool_result" || !b.IsError {
+		t.Errorf("failed tool result = %+v, want is_error", b)
+	}
+	if b := req.Messages[3].Content; len(b) != 1 || b[0].Type != "text" || b[0].Text == "" {
+		t.Errorf("empty assistant turn = %+v, want a placeholder text block", b)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"m1","role":"assistant","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"getPet","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"id\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"7}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	var gotHeaders http.Header
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		gotHeaders = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			var head struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(ev), &head)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", head.Type, ev)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewAutoClient("anthropic", srv.URL+"/v1", "secret", "claude-test", "", true, GenerationParams{}, AzureOptions{})
+	var deltas []string
+	msg, usage, err := client.ChatStreamWithTools(context.Background(),
+		[]Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}}, nil,
+		func(d string) { deltas = append(deltas, d) }, nil)
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools: %v", err)
+	}
+
+	if gotHeaders.Get("x-api-key") != "secret" || gotHeaders.Get("anthropic-version") == "" {
+		t.Errorf("auth headers missing: %v", gotHeaders)
+	}
+	if gotBody["stream"] != true || gotBody["system"] != "sys" {
+		t.Errorf("request body = %v", gotBody)
+	}
+	if msg.Content != "Hello" || strings.Join(deltas, "|") != "Hel|lo" {
+		t.Errorf("content = %q, deltas = %v", msg.Content, deltas)
+	}
+	if len(msg.ToolCalls) != 1 {
+		t.Fatalf("expected 1 tool call, got %d", len(msg.ToolCalls))
+	}
+	tc := msg.ToolCalls[0]
+	if tc.ID != "tu_1" || tc.Function.Name != "getPet" || tc.Function.Arguments != `{"id":7}` {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 20 || usage.TotalTokens != 32 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestAnthropicNonStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"m1","role":"assistant","content":[
+			{"type":"text","text":"Deleting"},
+			{"type":"tool_use","id":"tu_9","name":"deletePet","input":{"id":3}}
+		],"stop_reason":"tool_use","usage":{"input_tokens":5,"output_tokens":4}}`)
+	}))
+	defer srv.Close()
+
+	client := NewAutoClient("anthropic", srv.URL, "k", "claude-test", "", false, GenerationParams{}, AzureOptions{})
+	msg, usage, err := client.ChatStreamWithTools(context.Background(), []Message{{Role: "user", Content: "rm 3"}}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools: %v", err)
+	}
+	if msg.Content != "Deleting" || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"id":3}` {
+		t.Errorf("message = %+v", msg)
+	}
+	if usage == nil || usage.TotalTokens != 9 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestAnthropicErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient(srv.URL, "k", "claude-test", "")
+	if _, _, err := client.ChatStreamWithTools(context.Background(), nil, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("expected 400 error, got %v", err)
+	}
+}
+
+func TestDetectProvider(t *testing.T) {
+	cases := []struct {
+		provider, apiBase, want string
+	}{
+		{"", "https://api.anthropic.com/v1", ProviderAnthropic},
+		{"", "https://generativelanguage.googleapis.com/v1beta", ProviderGemini},
+		{"", "https://generativelanguage.googleapis.com/v1beta/openai", ProviderOpenAI},
+		{"", "http://localhost:11434/v1", ProviderOpenAI},
+		{"Anthropic", "https://my-gateway.internal/v1", ProviderAnthropic},
+		{"openai", "https://api.anthropic.com/v1", ProviderOpenAI},
+		{"", "https://my-resource.openai.azure.com", ProviderAzure},
+		{"Azure", "https://gateway.example.com", ProviderAzure},
+	}
+	for _, c := range cases {
+		if got := DetectProvider(c.provider, c.apiBase); got != c.want {
+			t.Errorf("DetectProvider(%q, %q) = %q, want %q", c.provider, c.apiBase, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAzureDeploymentRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/prod-gpt4o/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if v := r.URL.Query().Get("api-version"); v != DefaultAzureAPIVersion {
+			t.Errorf("api-version = %q", v)
+		}
+		if r.Header.Get("api-key") != "secret" || r.Header.Get("Authorization") != "" {
+			t.Errorf("auth headers: api-key=%q authorization=%q", r.Header.Get("api-key"), r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`)
+	}))
+	defer srv.Close()
+
+	// A pasted URL with /openai/... is reduced to the resource endpoint.
+	client := NewAutoClient("azure", srv.URL+"/openai/", "secret", "gpt-4o", "", false, GenerationParams{}, AzureOptions{Deployment: "prod-gpt4o"})
+	msg, _, err := client.ChatStreamWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools: %v", err)
+	}
+	if msg.Content != "hi" {
+		t.Errorf("content = %q", msg.Content)
+	}
+}
+
+func TestAzureDeploymentDefaultsToModel(t *testing.T) {
+	c := NewAzureClient("https://res.openai.azure.com", "k", "gpt-4o-mini", "", AzureOptions{APIVersion: "2025-01-01-preview"})
+	want := "https://res.openai.azure.com/openai/deployments/gpt-4o-mini/chat/completions?api-version=2025-01-01-preview"
+	if got := c.chatURL(); got != want {
+		t.Errorf("chatURL = %s, want %s", got, want)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/openai/deployments":
+			if r.Header.Get("api-key") != "k" {
+				t.Errorf("azure api-key = %q", r.Header.Get("api-key"))
+			}
+			fmt.Fprint(w, `{"data":[{"id":"prod-gpt4o","model":"gpt-4o"}]}`)
+		case r.URL.Path == "/v1beta/models":
+			if r.URL.Query().Get("key") != "k" {
+				t.Errorf("gemini key = %q", r.URL.Query().Get("key"))
+			}
+			fmt.Fprint(w, `{"models":[{"name":"models/gemini-2.5-flash","supportedGenerationMethods":["generateContent"]},{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}]}`)
+		case r.URL.Path == "/v1/models":
+			if r.Header.Get("Authorization") != "Bearer k" {
+				t.Errorf("openai auth = %q", r.Header.Get("Authorization"))
+			}
+			fmt.Fprint(w, `{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		provider, apiBase, want string
+	}{
+		{"azure", srv.URL, "prod-gpt4o"},
+		{"gemini", srv.URL + "/v1beta", "gemini-2.5-flash"},
+		{"", srv.URL + "/v1", "gpt-4o,gpt-4o-mini"},
+	}
+	for _, c := range cases {
+		models, err := ListModels(context.Background(), srv.Client(), c.provider, c.apiBase, "k")
+		if err != nil {
+			t.Errorf("%s: %v", c.provider, err)
+			continue
+		}
+		if got := strings.Join(models, ","); got != c.want {
+			t.Errorf("%s: models = %s, want %s", c.provider, got, c.want)
+		}
+	}
+
+	if _, err := ListModels(context.Background(), srv.Client(), "", srv.URL+"/nope", ""); err == nil {
+		t.Error("expected error for 404")
+	}
+}
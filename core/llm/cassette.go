@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette modes for CassetteClient.
+const (
+	CassetteRecord = "record" // call the wrapped client and save every exchange
+	CassetteReplay = "replay" // serve saved exchanges, never touch the network
+)
+
+// Interaction is one recorded ChatStreamWithTools call.
+type Interaction struct {
+	Key       string    `json:"key"` // RequestKey of the request
+	Messages  []Message `json:"messages"`
+	Tools     []string  `json:"tools,omitempty"` // tool names, for readability; the key covers full schemas
+	Deltas    []string  `json:"deltas,omitempty"`
+	Reasoning []string  `json:"reasoning,omitempty"`
+	Response  *Message  `json:"response,omitempty"`
+	Usage     *Usage    `json:"usage,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// CassetteClient records LLM exchanges to a JSON file, or replays them from
+// one, so conversations can be regression-tested offline. Replayed calls are
+// matched by RequestKey; identical requests replay in recorded order.
+type CassetteClient struct {
+	inner LLMClient // nil in replay mode
+	path  string
+	mode  string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	served       map[string]int // replay: key → interactions already served
+}
+
+// NewCassetteRecorder wraps inner and writes every call to path, replacing
+// any previous recording.
+func NewCassetteRecorder(inner LLMClient, path string) *CassetteClient {
+	return &CassetteClient{inner: inner, path: path, mode: CassetteRecord}
+}
+
+// NewCassettePlayer loads a recording made by NewCassetteRecorder.
+func NewCassettePlayer(path string) (*CassetteClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	return &CassetteClient{
+		path:         path,
+		mode:         CassetteReplay,
+		interactions: f.Interactions,
+		served:       make(map[string]int),
+	}, nil
+}
+
+// RequestKey hashes the messages and tool definitions of a request.
+func RequestKey(messages []Message, tools []Tool) string {
+	data, _ := json.Marshal(struct {
+		Messages []Message `json:"messages"`
+		Tools    []Tool    `json:"tools"`
+	}{messages, tools})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *CassetteClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	if c.mode == CassetteReplay {
+		return c.replay(RequestKey(messages, tools), onDelta, onReasoning)
+	}
+	return c.record(ctx, messages, tools, onDelta, onReasoning)
+}
+
+func (c *CassetteClient) record(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	it := Interaction{
+		Key:      RequestKey(messages, tools),
+		Messages: messages,
+	}
+	for _, t := range tools {
+		it.Tools = append(it.Tools, t.Function.Name)
+	}
+
+	msg, usage, err := c.inner.ChatStreamWithTools(ctx, messages, tools, func(d string) {
+		it.Deltas = append(it.Deltas, d)
+		if onDelta != nil {
+			onDelta(d)
+		}
+	}, func(d string) {
+		it.Reasoning = append(it.Reasoning, d)
+		if onReasoning != nil {
+			onReasoning(d)
+		}
+	})
+	it.Response = msg
+	it.Usage = usage
+	if err != nil {
+		it.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, it)
+	saveErr := c.save()
+	c.mu.Unlock()
+	if err == nil && saveErr != nil {
+		err = saveErr
+	}
+	return msg, usage, err
+}
+
+func (c *CassetteClient) replay(key string, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	c.mu.Lock()
+	var it *Interaction
+	skip := c.served[key]
+	for i := range c.interactions {
+		if c.interactions[i].Key != key {
+			continue
+		}
+		if skip == 0 {
+			it = &c.interactions[i]
+			break
+		}
+		skip--
+	}
+	if it != nil {
+		c.served[key]++
+	}
+	c.mu.Unlock()
+
+	if it == nil {
+		return nil, nil, fmt.Errorf("cassette %s: no recorded response for request %s", c.path, key[:12])
+	}
+	for _, d := range it.Reasoning {
+		if onReasoning != nil {
+			onReasoning(d)
+		}
+	}
+	for _, d := range it.Deltas {
+		if onDelta != nil {
+			onDelta(d)
+		}
+	}
+	var msg *Message
+	if it.Response != nil {
+		m := *it.Response
+		msg = &m
+	}
+	var usage *Usage
+	if it.Usage != nil {
+		u := *it.Usage
+		usage = &u
+	}
+	if it.Error != "" {
+		return msg, usage, errors.New(it.Error)
+	}
+	return msg, usage, nil
+}
+
+// save rewrites the cassette file atomically. Caller holds c.mu.
+func (c *CassetteClient) save() error {
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cassette: %w", err)
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("write cassette: %w", err)
+		}
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
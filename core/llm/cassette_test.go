@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoClient streams "re: <last message>" and reports fixed usage.
+type echoClient struct{ calls int }
+
+func (e *echoClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	e.calls++
+	last := messages[len(messages)-1].Content
+	onReasoning("thinking")
+	onDelta("re: ")
+	onDelta(last)
+	msg := &Message{Role: "assistant", Content: "re: " + last}
+	if len(tools) > 0 {
+		msg.ToolCalls = []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: tools[0].Function.Name, Arguments: "{}"}}}
+	}
+	return msg, &Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}, nil
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "list_pets"}}}
+	hi := []Message{{Role: "user", Content: "hi"}}
+	bye := []Message{{Role: "user", Content: "bye"}}
+
+	inner := &echoClient{}
+	rec := NewCassetteRecorder(inner, path)
+	for _, msgs := range [][]Message{hi, bye, hi} {
+		if _, _, err := rec.ChatStreamWithTools(context.Background(), msgs, tools, func(string) {}, func(string) {}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	player, err := NewCassettePlayer(path)
+	if err != nil {
+		t.Fatalf("NewCassettePlayer: %v", err)
+	}
+	var deltas, reasoning []string
+	msg, usage, err := player.ChatStreamWithTools(context.Background(), bye, tools,
+		func(d string) { deltas = append(deltas, d) },
+		func(d string) { reasoning = append(reasoning, d) })
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if msg.Content != "re: bye" || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "list_pets" {
+		t.Errorf("replayed message = %+v", msg)
+	}
+	if strings.Join(deltas, "") != "re: bye" || strings.Join(reasoning, "") != "thinking" {
+		t.Errorf("deltas = %v, reasoning = %v", deltas, reasoning)
+	}
+	if usage == nil || usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	// "hi" was recorded twice and replays twice, then runs out.
+	for i := 0; i < 2; i++ {
+		if _, _, err := player.ChatStreamWithTools(context.Background(), hi, tools, nil, nil); err != nil {
+			t.Fatalf("replay hi #%d: %v", i+1, err)
+		}
+	}
+	if _, _, err := player.ChatStreamWithTools(context.Background(), hi, tools, nil, nil); err == nil {
+		t.Fatal("expected a miss once recordings are used up")
+	}
+	if inner.calls != 3 {
+		t.Errorf("replay must not call the backend: %d calls", inner.calls)
+	}
+}
+
+func TestCassetteMissOnChangedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	rec := NewCassetteRecorder(&echoClient{}, path)
+	rec.ChatStreamWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+
+	player, err := NewCassettePlayer(path)
+	if err != nil {
+		t.Fatalf("NewCassettePlayer: %v", err)
+	}
+	// A changed system prompt or toolset must not silently match an old recording.
+	_, _, err = player.ChatStreamWithTools(context.Background(),
+		[]Message{{Role: "system", Content: "new prompt"}, {Role: "user", Content: "hi"}}, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Fatalf("expected miss, got %v", err)
+	}
+}
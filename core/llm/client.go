@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -17,9 +16,21 @@ type Client struct {
 	apiKey     string
 	model      string
 	stream     bool
+	params     GenerationParams
+	azure      *AzureOptions // deployment-style endpoint with api-key auth
 	httpClient *http.Client
 }
 
+// DefaultAzureAPIVersion is the Azure OpenAI api-version used when none is configured.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureOptions address an Azure OpenAI deployment. apiBase is the resource
+// endpoint, e.g. https://my-resource.openai.azure.com.
+type AzureOptions struct {
+	Deployment string // default: the model name
+	APIVersion string // default: DefaultAzureAPIVersion
+}
+
 func NewClient(apiBase, apiKey, model, proxy string) *Client {
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	if proxy != "" {
@@ -36,31 +47,73 @@ func NewClient(apiBase, apiKey, model, proxy string) *Client {
 	}
 }
 
+// NewAzureClient creates a client for an Azure OpenAI deployment, which is
+// addressed by URL instead of by the model field and authenticates with an
+// api-key header.
+func NewAzureClient(endpoint, apiKey, model, proxy string, opts AzureOptions) *Client {
+	if opts.Deployment == "" {
+		opts.Deployment = model
+	}
+	if opts.APIVersion == "" {
+		opts.APIVersion = DefaultAzureAPIVersion
+	}
+	c := NewClient(azureEndpoint(endpoint), apiKey, model, proxy)
+	c.azure = &opts
+	return c
+}
+
+// azureEndpoint reduces a pasted Azure URL to the resource endpoint.
+func azureEndpoint(apiBase string) string {
+	base := strings.TrimRight(apiBase, "/")
+	if i := strings.Index(base, "/openai"); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
 func (c *Client) SetStream(v bool) { c.stream = v }
 
+func (c *Client) chatURL() string {
+	if c.azure == nil {
+		return c.apiBase + "/chat/completions"
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		c.apiBase, url.PathEscape(c.azure.Deployment), url.QueryEscape(c.azure.APIVersion))
+}
+
+func (c *Client) setAuth(req *http.Request) {
+	if c.apiKey == "" {
+		return
+	}
+	if c.azure != nil {
+		req.Header.Set("api-key", c.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
 func (c *Client) Chat(ctx context.Context, messages []Message, tools []Tool) (*ChatResponse, error) {
 	req := ChatRequest{
 		Model:    c.model,
-		Messages: messages,
+		Messages: toRequestMessages(messages),
 		Stream:   false,
 	}
 	if len(tools) > 0 {
 		req.Tools = tools
 	}
+	c.params.forContext(ctx).applyTo(&req)
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.apiBase+"/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.chatURL(), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
+	c.setAuth(httpReq)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -69,8 +122,7 @@ func (c *Client) Chat(ctx context.Context, messages []Message, tools []Tool) (*C
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("LLM API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, newAPIError("LLM", resp)
 	}
 
 	var chatResp ChatResponse
@@ -81,35 +133,35 @@ func (c *Client) Chat(ctx context.Context, messages []Message, tools []Tool) (*C
 }
 
 // ChatStreamWithTools sends a request with tools support.
-// When stream=true, assembles the full Message from SSE deltas and calls onDelta for each text chunk.
+// When stream=true, assembles the full Message from SSE deltas and calls onDelta for each text chunk
+// and onReasoning for each reasoning chunk.
 // When stream=false, sends a single request and returns the complete response.
-func (c *Client) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta func(string)) (*Message, *Usage, error) {
+func (c *Client) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
 	if !c.stream {
-		return c.chatNonStream(ctx, messages, tools, onDelta)
+		return c.chatNonStream(ctx, messages, tools, onDelta, onReasoning)
 	}
 	req := ChatRequest{
 		Model:         c.model,
-		Messages:      messages,
+		Messages:      toRequestMessages(messages),
 		Stream:        true,
 		StreamOptions: &StreamOptions{IncludeUsage: true},
 	}
 	if len(tools) > 0 {
 		req.Tools = tools
 	}
+	c.params.forContext(ctx).applyTo(&req)
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.apiBase+"/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.chatURL(), bytes.NewReader(body))
 	if err != nil {
 		return nil, nil, fmt.Errorf("create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
+	c.setAuth(httpReq)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -118,8 +170,7 @@ func (c *Client) ChatStreamWithTools(ctx context.Context, messages []Message, to
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, nil, fmt.Errorf("LLM API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, nil, newAPIError("LLM", resp)
 	}
 
 	// Assemble message from stream deltas
@@ -152,6 +203,11 @@ func (c *Client) ChatStreamWithTools(ctx context.Context, messages []Message, to
 		}
 		d := chunk.Choices[0].Delta
 
+		// Reasoning delta — streamed separately, never part of Content
+		if r := d.ReasoningText(); r != "" && onReasoning != nil {
+			onReasoning(r)
+		}
+
 		// Content delta
 		if d.Content != "" {
 			assembled.Content += d.Content
@@ -206,7 +262,7 @@ func (c *Client) ChatStreamWithTools(ctx context.Context, messages []Message, to
 	return assembled, usage, nil
 }
 
-func (c *Client) chatNonStream(ctx context.Context, messages []Message, tools []Tool, onDelta func(string)) (*Message, *Usage, error) {
+func (c *Client) chatNonStream(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
 	resp, err := c.Chat(ctx, messages, tools)
 	if err != nil {
 		return nil, nil, err
@@ -214,30 +270,82 @@ func (c *Client) chatNonStream(ctx context.Context, messages []Message, tools []
 	if len(resp.Choices) == 0 {
 		return &Message{Role: "assistant"}, resp.Usage, nil
 	}
-	msg := &resp.Choices[0].Message
+	choice := resp.Choices[0].Message
+	if r := choice.ReasoningText(); r != "" && onReasoning != nil {
+		onReasoning(r)
+	}
+	msg := &choice.Message
 	if msg.Content != "" && onDelta != nil {
 		onDelta(msg.Content)
 	}
 	return msg, resp.Usage, nil
 }
 
-// NewAutoClient picks the right backend by URL: Gemini native for googleapis.com
-// (excluding the /openai compatibility layer), OpenAI-compatible for everything else.
-func NewAutoClient(apiBase, apiKey, model, proxy string, stream bool) LLMClient {
-	if strings.Contains(apiBase, "googleapis.com") && !strings.Contains(apiBase, "/openai") {
+// NewAutoClient picks the right backend. An explicit provider ("openai",
+// "azure", "gemini", "anthropic") wins; otherwise it is inferred from the URL:
+// Azure OpenAI for *.openai.azure.com, Gemini native for googleapis.com
+// (excluding the /openai compatibility layer), Anthropic for anthropic.com,
+// OpenAI-compatible for everything else. params are translated to each
+// backend's own request fields; azure is only used by Azure OpenAI.
+func NewAutoClient(provider, apiBase, apiKey, model, proxy string, stream bool, params GenerationParams, azure AzureOptions) LLMClient {
+	switch DetectProvider(provider, apiBase) {
+	case ProviderAzure:
+		c := NewAzureClient(apiBase, apiKey, model, proxy, azure)
+		c.stream = stream
+		c.params = params
+		return c
+	case ProviderGemini:
 		c := NewGeminiClient(apiBase, apiKey, model, proxy)
 		c.stream = stream
+		c.params = params
+		return c
+	case ProviderAnthropic:
+		c := NewAnthropicClient(apiBase, apiKey, model, proxy)
+		c.stream = stream
+		c.params = params
 		return c
 	}
 	c := NewClient(apiBase, apiKey, model, proxy)
 	c.stream = stream
+	c.params = params
 	return c
 }
 
+// Supported provider identifiers for NewAutoClient.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAzure     = "azure"
+	ProviderGemini    = "gemini"
+	ProviderAnthropic = "anthropic"
+)
+
+// DetectProvider normalizes an explicit provider name, falling back to URL heuristics.
+func DetectProvider(provider, apiBase string) string {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case ProviderGemini:
+		return ProviderGemini
+	case ProviderAnthropic, "claude":
+		return ProviderAnthropic
+	case ProviderOpenAI:
+		return ProviderOpenAI
+	case ProviderAzure, "azure_openai", "azure-openai":
+		return ProviderAzure
+	}
+	switch {
+	case strings.Contains(apiBase, ".openai.azure.com") || strings.Contains(apiBase, ".cognitiveservices.azure.com"):
+		return ProviderAzure
+	case strings.Contains(apiBase, "googleapis.com") && !strings.Contains(apiBase, "/openai"):
+		return ProviderGemini
+	case strings.Contains(apiBase, "anthropic.com"):
+		return ProviderAnthropic
+	}
+	return ProviderOpenAI
+}
+
 func (c *Client) ChatStream(ctx context.Context, messages []Message, onChunk func(StreamChunk)) error {
 	req := ChatRequest{
 		Model:    c.model,
-		Messages: messages,
+		Messages: toRequestMessages(messages),
 		Stream:   true,
 	}
 
@@ -246,14 +354,12 @@ func (c *Client) ChatStream(ctx context.Context, messages []Message, onChunk fun
 		return fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.apiBase+"/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.chatURL(), bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
+	c.setAuth(httpReq)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -262,8 +368,7 @@ func (c *Client) ChatStream(ctx context.Context, messages []Message, onChunk fun
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("LLM API error %d: %s", resp.StatusCode, string(respBody))
+		return newAPIError("LLM", resp)
 	}
 
 	scanner := bufio.NewScanner(resp.Body)
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"encoding/base64"
+	"mime"
+	"path"
+	"strings"
+)
+
+// ContentPart is one non-plain-text piece of a message, in OpenAI's
+// content-part shape so it can be sent to compatible backends as-is.
+type ContentPart struct {
+	Type     string    `json:"type"` // "text" | "image_url" | "file"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+	File     *FilePart `json:"file,omitempty"`
+}
+
+type ImageURL struct {
+	URL    string `json:"url"` // https URL or data:<mime>;base64,<data>
+	Detail string `json:"detail,omitempty"`
+}
+
+type FilePart struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"` // data:<mime>;base64,<data>
+}
+
+// PartFromURL builds an attachment part from a URL or data URL:
+// images become image_url parts, anything else a file part.
+func PartFromURL(u, filename string) ContentPart {
+	mimeType := attachmentMIME(u, filename)
+	if strings.HasPrefix(mimeType, "image/") {
+		return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: u}}
+	}
+	return ContentPart{Type: "file", File: &FilePart{Filename: filename, FileData: u}}
+}
+
+// DataURL encodes raw bytes as a base64 data URL.
+func DataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// ParseDataURL splits a base64 data URL into its MIME type and payload.
+func ParseDataURL(u string) (mimeType, data string, ok bool) {
+	if !strings.HasPrefix(u, "data:") {
+		return "", "", false
+	}
+	meta, payload, found := strings.Cut(strings.TrimPrefix(u, "data:"), ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	mimeType = strings.TrimSuffix(meta, ";base64")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return mimeType, payload, true
+}
+
+// URL returns the image URL or file data URL carried by the part.
+func (p ContentPart) URL() string {
+	switch {
+	case p.ImageURL != nil:
+		return p.ImageURL.URL
+	case p.File != nil:
+		return p.File.FileData
+	}
+	return ""
+}
+
+// MIMEType returns the part's media type, from the data URL or the file extension.
+func (p ContentPart) MIMEType() string {
+	filename := ""
+	if p.File != nil {
+		filename = p.File.Filename
+	}
+	return attachmentMIME(p.URL(), filename)
+}
+
+func attachmentMIME(u, filename string) string {
+	if m, _, ok := ParseDataURL(u); ok {
+		return m
+	}
+	for _, name := range []string{filename, u} {
+		if name == "" {
+			continue
+		}
+		if i := strings.IndexAny(name, "?#"); i >= 0 {
+			name = name[:i]
+		}
+		if m := mime.TypeByExtension(path.Ext(name)); m != "" {
+			m, _, _ = strings.Cut(m, ";")
+			return m
+		}
+	}
+	return "application/octet-stream"
+}
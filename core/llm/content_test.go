@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPartFromURL(t *testing.T) {
+	img := PartFromURL("data:image/png;base64,AAAA", "shot.png")
+	if img.Type != "image_url" || img.ImageURL == nil {
+		t.Errorf("data image = %+v", img)
+	}
+	remote := PartFromURL("https://example.com/a.jpg?x=1", "")
+	if remote.Type != "image_url" {
+		t.Errorf("remote image = %+v", remote)
+	}
+	doc := PartFromURL("data:application/pdf;base64,AAAA", "spec.pdf")
+	if doc.Type != "file" || doc.File.Filename != "spec.pdf" || doc.MIMEType() != "application/pdf" {
+		t.Errorf("pdf = %+v", doc)
+	}
+}
+
+func TestOpenAIWireContent(t *testing.T) {
+	msgs := toRequestMessages([]Message{
+		{Role: "user", Content: "plain"},
+		{Role: "user", Content: "look", Parts: []ContentPart{PartFromURL("data:image/png;base64,AAAA", "")}},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Type: "function"}}},
+	})
+	data, _ := json.Marshal(msgs)
+	s := string(data)
+	if !strings.Contains(s, `"content":"plain"`) {
+		t.Errorf("text-only content must stay a string: %s", s)
+	}
+	if !strings.Contains(s, `"content":[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]`) {
+		t.Errorf("multimodal content must be a part array: %s", s)
+	}
+	if strings.Contains(s, `"role":"assistant","content"`) {
+		t.Errorf("empty assistant content must be omitted: %s", s)
+	}
+}
+
+func TestGeminiInlineData(t *testing.T) {
+	g := NewGeminiClient("https://generativelanguage.googleapis.com/v1beta", "k", "gemini-test", "")
+	req := g.buildRequest(context.Background(), []Message{{Role: "user", Content: "hi", Parts: []ContentPart{
+		PartFromURL("data:image/jpeg;base64,BBBB", ""),
+		PartFromURL("https://example.com/doc.pdf", ""),
+	}}}, nil)
+
+	parts := req.Contents[0].Parts
+	if len(parts) != 3 || parts[0].Text != "hi" {
+		t.Fatalf("parts = %+v", parts)
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/jpeg" || parts[1].InlineData.Data != "BBBB" {
+		t.Errorf("inlineData = %+v", parts[1].InlineData)
+	}
+	if parts[2].FileData == nil || parts[2].FileData.MimeType != "application/pdf" {
+		t.Errorf("fileData = %+v", parts[2].FileData)
+	}
+}
+
+func TestAnthropicImageBlocks(t *testing.T) {
+	a := NewAnthropicClient("https://api.anthropic.com/v1", "k", "claude-test", "")
+	req := a.buildRequest(context.Background(), []Message{{Role: "user", Content: "hi", Parts: []ContentPart{
+		PartFromURL("data:image/png;base64,CCCC", ""),
+		PartFromURL("data:application/pdf;base64,DDDD", "a.pdf"),
+	}}}, nil)
+
+	blocks := req.Messages[0].Content
+	if len(blocks) != 3 || blocks[1].Type != "image" || blocks[2].Type != "document" {
+		t.Fatalf("blocks = %+v", blocks)
+	}
+	if blocks[1].Source.Type != "base64" || blocks[1].Source.MediaType != "image/png" || blocks[1].Source.Data != "CCCC" {
+		t.Errorf("image source = %+v", blocks[1].Source)
+	}
+}
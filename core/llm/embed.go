@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// Embedder turns texts into vectors for similarity search.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingClient calls an OpenAI-compatible /embeddings endpoint (or an
+// Azure OpenAI embedding deployment).
+type EmbeddingClient struct {
+	*Client
+}
+
+// NewEmbeddingClient returns an embedder for model on the given backend.
+// Gemini and Anthropic backends are not supported.
+func NewEmbeddingClient(provider, apiBase, apiKey, model, proxy string, azure AzureOptions) (*EmbeddingClient, error) {
+	switch DetectProvider(provider, apiBase) {
+	case ProviderAzure:
+		return &EmbeddingClient{NewAzureClient(apiBase, apiKey, model, proxy, azure)}, nil
+	case ProviderGemini, ProviderAnthropic:
+		return nil, fmt.Errorf("embeddings are not supported for provider %s", DetectProvider(provider, apiBase))
+	}
+	return &EmbeddingClient{NewClient(apiBase, apiKey, model, proxy)}, nil
+}
+
+func (c *EmbeddingClient) embeddingsURL() string {
+	if c.azure == nil {
+		return c.apiBase + "/embeddings"
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+		c.apiBase, url.PathEscape(c.azure.Deployment), url.QueryEscape(c.azure.APIVersion))
+}
+
+func (c *EmbeddingClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": c.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.embeddingsURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuth(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("embeddings", resp)
+	}
+
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d texts", len(out.Data), len(texts))
+	}
+	sort.Slice(out.Data, func(i, j int) bool { return out.Data[i].Index < out.Data[j].Index })
+	vecs := make([][]float32, len(out.Data))
+	for i, d := range out.Data {
+		vecs[i] = d.Embedding
+	}
+	return vecs, nil
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("authorization = %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		// Out of order on purpose: vectors are matched by index.
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer srv.Close()
+
+	emb, err := NewEmbeddingClient("", srv.URL+"/v1", "k", "text-embedding-3-small", "", AzureOptions{})
+	if err != nil {
+		t.Fatalf("NewEmbeddingClient: %v", err)
+	}
+	vecs, err := emb.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vecs) != 2 || vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Errorf("vectors = %v", vecs)
+	}
+}
+
+func TestEmbeddingProviders(t *testing.T) {
+	if _, err := NewEmbeddingClient("anthropic", "https://api.anthropic.com/v1", "k", "m", "", AzureOptions{}); err == nil {
+		t.Error("anthropic: want error")
+	}
+	c, err := NewEmbeddingClient("azure", "https://res.openai.azure.com", "k", "m", "", AzureOptions{Deployment: "embed", APIVersion: "2024-10-21"})
+	if err != nil {
+		t.Fatalf("azure: %v", err)
+	}
+	want := "https://res.openai.azure.com/openai/deployments/embed/embeddings?api-version=2024-10-21"
+	if got := c.embeddingsURL(); got != want {
+		t.Errorf("embeddingsURL = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusOverloaded is Anthropic's non-standard "overloaded" status.
+const statusOverloaded = 529
+
+// APIError is returned when a backend answers with a non-200 status.
+type APIError struct {
+	Provider   string // message prefix, e.g. "LLM", "gemini"
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // parsed Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the status is worth retrying (rate limit or transient server error).
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, statusOverloaded:
+		return true
+	}
+	return false
+}
+
+// newAPIError drains resp.Body into an APIError.
+func newAPIError(provider string, resp *http.Response) *APIError {
+	respBody, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds or an HTTP-date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
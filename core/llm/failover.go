@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverCooldown is how long a failed backend is skipped.
+const DefaultFailoverCooldown = 60 * time.Second
+
+// Backend is one named entry in a FailoverClient chain.
+type Backend struct {
+	Name   string
+	Model  string // reported in Usage.Model, for pricing
+	Client LLMClient
+}
+
+// FailoverClient tries an ordered list of backends and moves on to the next
+// one when a backend fails with a transport error, 5xx or rate limit. A failed
+// backend is put on cooldown so later calls skip it until it has had time to
+// recover. The backend that served the call is reported in Usage.Backend.
+type FailoverClient struct {
+	backends []Backend
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	downUntil map[int]time.Time // backend index → end of cooldown
+}
+
+func NewFailoverClient(backends []Backend, cooldown time.Duration) *FailoverClient {
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+	return &FailoverClient{
+		backends:  backends,
+		cooldown:  cooldown,
+		now:       time.Now,
+		downUntil: make(map[int]time.Time),
+	}
+}
+
+func (f *FailoverClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	if len(f.backends) == 0 {
+		return nil, nil, fmt.Errorf("no LLM backends configured")
+	}
+
+	var lastErr error
+	for _, idx := range f.order() {
+		b := f.backends[idx]
+		streamed := false
+		msg, usage, err := b.Client.ChatStreamWithTools(ctx, messages, tools, func(d string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(d)
+			}
+		}, func(d string) {
+			streamed = true
+			if onReasoning != nil {
+				onReasoning(d)
+			}
+		})
+		if err == nil {
+			f.markUp(idx)
+			if usage == nil {
+				usage = &Usage{}
+			}
+			usage.Backend = b.Name
+			usage.Model = b.Model
+			return msg, usage, nil
+		}
+		// Once text reached the caller, switching backends would duplicate it.
+		if streamed || !IsRetryable(ctx, err) {
+			return msg, usage, err
+		}
+		f.markDown(idx)
+		lastErr = fmt.Errorf("%s: %w", b.Name, err)
+	}
+	return nil, nil, lastErr
+}
+
+// order returns backend indices to try: healthy ones in configured order,
+// then those still cooling down, soonest-recovering first. Nothing is ever
+// excluded — when everything is down, trying is better than failing outright.
+func (f *FailoverClient) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	var healthy, cooling []int
+	for i := range f.backends {
+		if until, ok := f.downUntil[i]; ok && now.Before(until) {
+			cooling = append(cooling, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	sort.SliceStable(cooling, func(a, b int) bool {
+		return f.downUntil[cooling[a]].Before(f.downUntil[cooling[b]])
+	})
+	return append(healthy, cooling...)
+}
+
+func (f *FailoverClient) markDown(idx int) {
+	f.mu.Lock()
+	f.downUntil[idx] = f.now().Add(f.cooldown)
+	f.mu.Unlock()
+}
+
+func (f *FailoverClient) markUp(idx int) {
+	f.mu.Lock()
+	delete(f.downUntil, idx)
+	f.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailoverSkipsFailedBackend(t *testing.T) {
+	primary := &scriptedClient{errs: []error{&APIError{StatusCode: 503}}}
+	backup := &scriptedClient{}
+	f := NewFailoverClient([]Backend{{Name: "primary", Client: primary}, {Name: "backup", Client: backup}}, time.Minute)
+	now := time.Unix(1000, 0)
+	f.now = func() time.Time { return now }
+
+	msg, usage, err := f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Content != "ok" || usage == nil || usage.Backend != "backup" {
+		t.Fatalf("msg = %+v, usage = %+v", msg, usage)
+	}
+
+	// primary is cooling down: the next call goes straight to backup
+	f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil)
+	if primary.calls != 1 || backup.calls != 2 {
+		t.Fatalf("primary calls = %d, backup calls = %d", primary.calls, backup.calls)
+	}
+
+	// after the cooldown primary is tried first again
+	now = now.Add(2 * time.Minute)
+	_, usage, _ = f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil)
+	if usage.Backend != "primary" || primary.calls != 2 {
+		t.Fatalf("expected primary after cooldown, got %q (calls %d)", usage.Backend, primary.calls)
+	}
+}
+
+func TestFailoverStopsOnClientError(t *testing.T) {
+	primary := &scriptedClient{errs: []error{&APIError{StatusCode: 400}}}
+	backup := &scriptedClient{}
+	f := NewFailoverClient([]Backend{{Name: "primary", Client: primary}, {Name: "backup", Client: backup}}, 0)
+
+	if _, _, err := f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil); err == nil {
+		t.Fatal("expected 400 to be returned")
+	}
+	if backup.calls != 0 {
+		t.Fatal("a 400 is not a provider outage and must not fail over")
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	a := &scriptedClient{errs: []error{&APIError{StatusCode: 429}}}
+	b := &scriptedClient{errs: []error{&APIError{StatusCode: 500}}}
+	f := NewFailoverClient([]Backend{{Name: "a", Client: a}, {Name: "b", Client: b}}, 0)
+
+	_, _, err := f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Fatalf("expected last backend's error, got %v", err)
+	}
+	// everything cooling down: still tried, instead of failing without a call
+	if _, _, err := f.ChatStreamWithTools(context.Background(), nil, nil, nil, nil); err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ type GeminiClient struct {
 	apiKey     string
 	model      string
 	stream     bool
+	params     GenerationParams
 	httpClient *http.Client
 }
 
@@ -43,6 +44,24 @@ type geminiRequest struct {
 	Contents          []geminiContent          `json:"contents"`
 	Tools             []geminiTool             `json:"tools,omitempty"`
 	SystemInstruction *geminiSystemInstruction `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig  `json:"generationConfig,omitempty"`
+	ToolConfig        *geminiToolConfig        `json:"toolConfig,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO | ANY | NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type geminiSystemInstruction struct {
@@ -56,10 +75,23 @@ type geminiContent struct {
 
 type geminiPart struct {
 	Text             string              `json:"text,omitempty"`
+	Thought          bool                `json:"thought,omitempty"` // Text is a thought summary, not answer text
+	InlineData       *geminiBlob         `json:"inlineData,omitempty"`
+	FileData         *geminiFileData     `json:"fileData,omitempty"`
 	FunctionCall     *geminiFunctionCall `json:"functionCall,omitempty"`
 	FunctionResponse *geminiToolResponse `json:"functionResponse,omitempty"`
 }
 
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
 type geminiFunctionCall struct {
 	Name string                 `json:"name"`
 	Args map[string]interface{} `json:"args,omitempty"`
@@ -98,8 +130,8 @@ type geminiUsage struct {
 
 // ── ChatStreamWithTools ──────────────────────────────────────────────
 
-func (g *GeminiClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta func(string)) (*Message, *Usage, error) {
-	reqBody := g.buildRequest(messages, tools)
+func (g *GeminiClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	reqBody := g.buildRequest(ctx, messages, tools)
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -128,19 +160,18 @@ func (g *GeminiClient) ChatStreamWithTools(ctx context.Context, messages []Messa
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, nil, fmt.Errorf("gemini API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, nil, newAPIError("gemini", resp)
 	}
 
 	if g.stream {
-		return g.parseSSE(resp.Body, onDelta)
+		return g.parseSSE(resp.Body, onDelta, onReasoning)
 	}
-	return g.parseJSON(resp.Body, onDelta)
+	return g.parseJSON(resp.Body, onDelta, onReasoning)
 }
 
 // ── internal helpers ─────────────────────────────────────────────────
 
-func (g *GeminiClient) buildRequest(messages []Message, tools []Tool) geminiRequest {
+func (g *GeminiClient) buildRequest(ctx context.Context, messages []Message, tools []Tool) geminiRequest {
 	var req geminiRequest
 
 	// Convert tools
@@ -181,15 +212,21 @@ func (g *GeminiClient) buildRequest(messages []Message, tools []Tool) geminiRequ
 	for _, m := range messages {
 		switch m.Role {
 		case "system":
-			req.SystemInstruction = &geminiSystemInstruction{
-				Parts: []geminiPart{{Text: m.Content}},
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiSystemInstruction{}
 			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: m.Content})
 
 		case "user":
 			flushToolParts()
+			var parts []geminiPart
+			if m.Content != "" || len(m.Parts) == 0 {
+				parts = append(parts, geminiPart{Text: m.Content})
+			}
+			parts = append(parts, geminiAttachmentParts(m.Parts)...)
 			req.Contents = append(req.Contents, geminiContent{
 				Role:  "user",
-				Parts: []geminiPart{{Text: m.Content}},
+				Parts: parts,
 			})
 
 		case "assistant":
@@ -230,10 +267,62 @@ func (g *GeminiClient) buildRequest(messages []Message, tools []Tool) geminiRequ
 	}
 	flushToolParts()
 
+	g.applyParams(&req, g.params.forContext(ctx))
 	return req
 }
 
-func (g *GeminiClient) parseSSE(r io.Reader, onDelta func(string)) (*Message, *Usage, error) {
+// applyParams translates GenerationParams into generationConfig and
+// toolConfig. Gemini has no parallel_tool_calls switch, so it is ignored.
+func (g *GeminiClient) applyParams(req *geminiRequest, p GenerationParams) {
+	if p.Temperature != nil || p.MaxTokens > 0 || p.TopP != nil || p.Seed != nil {
+		req.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     p.Temperature,
+			MaxOutputTokens: p.MaxTokens,
+			TopP:            p.TopP,
+			Seed:            p.Seed,
+		}
+	}
+	if len(req.Tools) == 0 || p.ToolChoice == "" {
+		return
+	}
+	fc := geminiFunctionCallingConfig{}
+	switch p.ToolChoice {
+	case ToolChoiceAuto:
+		fc.Mode = "AUTO"
+	case ToolChoiceNone:
+		fc.Mode = "NONE"
+	case ToolChoiceRequired:
+		fc.Mode = "ANY"
+	default:
+		fc.Mode = "ANY"
+		fc.AllowedFunctionNames = []string{p.ToolChoice}
+	}
+	req.ToolConfig = &geminiToolConfig{FunctionCallingConfig: fc}
+}
+
+// geminiAttachmentParts maps content parts to Gemini parts: data URLs become
+// inlineData, remote URLs fileData.
+func geminiAttachmentParts(parts []ContentPart) []geminiPart {
+	var out []geminiPart
+	for _, p := range parts {
+		if p.Type == "text" {
+			out = append(out, geminiPart{Text: p.Text})
+			continue
+		}
+		u := p.URL()
+		if u == "" {
+			continue
+		}
+		if mimeType, data, ok := ParseDataURL(u); ok {
+			out = append(out, geminiPart{InlineData: &geminiBlob{MimeType: mimeType, Data: data}})
+		} else {
+			out = append(out, geminiPart{FileData: &geminiFileData{MimeType: p.MIMEType(), FileURI: u}})
+		}
+	}
+	return out
+}
+
+func (g *GeminiClient) parseSSE(r io.Reader, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
 	assembled := &Message{Role: "assistant"}
 	var usage *Usage
 	tcIndex := 0
@@ -264,6 +353,12 @@ func (g *GeminiClient) parseSSE(r io.Reader, onDelta func(string)) (*Message, *U
 		}
 
 		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Thought {
+				if part.Text != "" && onReasoning != nil {
+					onReasoning(part.Text)
+				}
+				continue
+			}
 			if part.Text != "" {
 				assembled.Content += part.Text
 				if onDelta != nil {
@@ -291,7 +386,7 @@ func (g *GeminiClient) parseSSE(r io.Reader, onDelta func(string)) (*Message, *U
 	return assembled, usage, nil
 }
 
-func (g *GeminiClient) parseJSON(r io.Reader, onDelta func(string)) (*Message, *Usage, error) {
+func (g *GeminiClient) parseJSON(r io.Reader, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
 	var chunk geminiStreamChunk
 	if err := json.NewDecoder(r).Decode(&chunk); err != nil {
 		return nil, nil, fmt.Errorf("decode response: %w", err)
@@ -310,6 +405,12 @@ func (g *GeminiClient) parseJSON(r io.Reader, onDelta func(string)) (*Message, *
 
 	if len(chunk.Candidates) > 0 {
 		for i, part := range chunk.Candidates[0].Content.Parts {
+			if part.Thought {
+				if part.Text != "" && onReasoning != nil {
+					onReasoning(part.Text)
+				}
+				continue
+			}
 			if part.Text != "" {
 				assembled.Content += part.Text
 			}
@@ -4,7 +4,12 @@ import "context"
 
 // LLMClient abstracts the streaming chat+tools API so that different backends
 // (OpenAI-compatible, Gemini native, etc.) can be swapped transparently.
+//
+// onDelta receives answer text as it streams; onReasoning receives the
+// model's thinking (DeepSeek reasoning_content, Gemini thoughts, Anthropic
+// thinking blocks), which is never included in the returned Message. Either
+// callback may be nil.
 type LLMClient interface {
 	ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool,
-		onDelta func(string)) (*Message, *Usage, error)
+		onDelta, onReasoning func(string)) (*Message, *Usage, error)
 }
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// azureDeploymentsAPIVersion is the last Azure OpenAI api-version whose data
+// plane lists deployments; chat requests use a newer one.
+const azureDeploymentsAPIVersion = "2022-12-01"
+
+// ListModels returns the model IDs a backend offers, using the same provider
+// detection as NewAutoClient. For Azure OpenAI these are deployment names,
+// which is what a chat request addresses.
+func ListModels(ctx context.Context, client *http.Client, provider, apiBase, apiKey string) ([]string, error) {
+	base := strings.TrimRight(apiBase, "/")
+	var u string
+	header := http.Header{}
+	switch DetectProvider(provider, apiBase) {
+	case ProviderAzure:
+		u = azureEndpoint(apiBase) + "/openai/deployments?api-version=" + azureDeploymentsAPIVersion
+		if apiKey != "" {
+			header.Set("api-key", apiKey)
+		}
+	case ProviderGemini:
+		u = base + "/models?pageSize=1000"
+		if apiKey != "" {
+			u += "&key=" + url.QueryEscape(apiKey)
+		}
+	case ProviderAnthropic:
+		u = base + "/models?limit=1000"
+		header.Set("anthropic-version", anthropicVersion)
+		if apiKey != "" {
+			header.Set("x-api-key", apiKey)
+		}
+	default:
+		u = base + "/models"
+		if apiKey != "" {
+			header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("models", resp)
+	}
+
+	// OpenAI, Azure and Anthropic answer {"data":[{"id":…}]}; Gemini answers
+	// {"models":[{"name":"models/…","supportedGenerationMethods":[…]}]}.
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Models []struct {
+			Name    string   `json:"name"`
+			Methods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode models: %w", err)
+	}
+	models := []string{}
+	for _, m := range body.Data {
+		models = append(models, m.ID)
+	}
+	for _, m := range body.Models {
+		if len(m.Methods) > 0 && !slices.Contains(m.Methods, "generateContent") {
+			continue // embedding-only and similar
+		}
+		models = append(models, strings.TrimPrefix(m.Name, "models/"))
+	}
+	return models, nil
+}
@@ -0,0 +1,72 @@
+package llm
+
+import "context"
+
+// GenerationParams are optional sampling and tool-use settings applied to
+// every request. Nil/empty fields are omitted so the backend default applies.
+type GenerationParams struct {
+	Temperature       *float64
+	MaxTokens         int
+	TopP              *float64
+	Seed              *int
+	ParallelToolCalls *bool
+	ToolChoice        string // "auto" | "none" | "required" | a tool name
+}
+
+// Tool choice modes understood by every backend; any other value names a tool.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+type toolChoiceKey struct{}
+
+// WithToolChoice returns a context whose LLM calls use choice instead of the
+// configured tool choice, e.g. ToolChoiceNone for a call that must answer in
+// text while its history still declares tools.
+func WithToolChoice(ctx context.Context, choice string) context.Context {
+	return context.WithValue(ctx, toolChoiceKey{}, choice)
+}
+
+// ToolChoiceFrom returns the tool choice set by WithToolChoice, if any.
+func ToolChoiceFrom(ctx context.Context) (string, bool) {
+	choice, ok := ctx.Value(toolChoiceKey{}).(string)
+	return choice, ok
+}
+
+// forContext returns p with the tool choice set by WithToolChoice, if any.
+func (p GenerationParams) forContext(ctx context.Context) GenerationParams {
+	if choice, ok := ToolChoiceFrom(ctx); ok {
+		p.ToolChoice = choice
+	}
+	return p
+}
+
+// openAIToolChoice returns the tool_choice value in OpenAI wire format, or
+// nil when unset. A tool name becomes a {"type":"function"} object.
+func (p GenerationParams) openAIToolChoice() interface{} {
+	switch p.ToolChoice {
+	case "":
+		return nil
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		return p.ToolChoice
+	}
+	return map[string]interface{}{
+		"type":     "function",
+		"function": map[string]string{"name": p.ToolChoice},
+	}
+}
+
+// applyTo copies the params onto an OpenAI-compatible request. Tool settings
+// are only sent alongside tools, since the API rejects them otherwise.
+func (p GenerationParams) applyTo(req *ChatRequest) {
+	req.Temperature = p.Temperature
+	req.MaxTokens = p.MaxTokens
+	req.TopP = p.TopP
+	req.Seed = p.Seed
+	if len(req.Tools) > 0 {
+		req.ParallelToolCalls = p.ParallelToolCalls
+		req.ToolChoice = p.openAIToolChoice()
+	}
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var paramsTestTools = []Tool{{Type: "function", Function: ToolFunction{Name: "list_pets"}}}
+
+func testParams() GenerationParams {
+	temp, topP, seed, parallel := 0.2, 0.9, 42, false
+	return GenerationParams{
+		Temperature:       &temp,
+		MaxTokens:         512,
+		TopP:              &topP,
+		Seed:              &seed,
+		ParallelToolCalls: &parallel,
+		ToolChoice:        "list_pets",
+	}
+}
+
+func TestOpenAIParams(t *testing.T) {
+	req := ChatRequest{Model: "m", Tools: paramsTestTools}
+	testParams().applyTo(&req)
+	data, _ := json.Marshal(req)
+	s := string(data)
+	for _, want := range []string{
+		`"temperature":0.2`, `"max_tokens":512`, `"top_p":0.9`, `"seed":42`,
+		`"parallel_tool_calls":false`,
+		`"tool_choice":{"function":{"name":"list_pets"},"type":"function"}`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in %s", want, s)
+		}
+	}
+
+	// Tool settings are dropped when the request carries no tools.
+	req = ChatRequest{Model: "m"}
+	testParams().applyTo(&req)
+	data, _ = json.Marshal(req)
+	if s := string(data); strings.Contains(s, "tool_choice") || strings.Contains(s, "parallel_tool_calls") {
+		t.Errorf("tool settings sent without tools: %s", s)
+	}
+
+	// Unset params leave the request untouched.
+	req = ChatRequest{Model: "m", Tools: paramsTestTools}
+	GenerationParams{}.applyTo(&req)
+	data, _ = json.Marshal(req)
+	if s := string(data); strings.Contains(s, "temperature") || strings.Contains(s, "tool_choice") {
+		t.Errorf("unset params leaked into request: %s", s)
+	}
+}
+
+func TestGeminiParams(t *testing.T) {
+	g := NewGeminiClient("https://generativelanguage.googleapis.com/v1beta", "k", "gemini-test", "")
+	g.params = testParams()
+	req := g.buildRequest(context.Background(), []Message{{Role: "user", Content: "hi"}}, paramsTestTools)
+
+	gc := req.GenerationConfig
+	if gc == nil || *gc.Temperature != 0.2 || gc.MaxOutputTokens != 512 || *gc.TopP != 0.9 || *gc.Seed != 42 {
+		t.Fatalf("generationConfig = %+v", gc)
+	}
+	fc := req.ToolConfig.FunctionCallingConfig
+	if fc.Mode != "ANY" || len(fc.AllowedFunctionNames) != 1 || fc.AllowedFunctionNames[0] != "list_pets" {
+		t.Errorf("functionCallingConfig = %+v", fc)
+	}
+
+	for choice, mode := range map[string]string{"auto": "AUTO", "none": "NONE", "required": "ANY"} {
+		g.params = GenerationParams{ToolChoice: choice}
+		req = g.buildRequest(context.Background(), nil, paramsTestTools)
+		if req.GenerationConfig != nil || req.ToolConfig.FunctionCallingConfig.Mode != mode {
+			t.Errorf("%s: generationConfig = %+v, toolConfig = %+v", choice, req.GenerationConfig, req.ToolConfig)
+		}
+	}
+}
+
+func TestAnthropicParams(t *testing.T) {
+	a := NewAnthropicClient("https://api.anthropic.com/v1", "k", "claude-test", "")
+	a.params = testParams()
+	req := a.buildRequest(context.Background(), []Message{{Role: "user", Content: "hi"}}, paramsTestTools)
+
+	if req.MaxTokens != 512 || *req.Temperature != 0.2 || *req.TopP != 0.9 {
+		t.Fatalf("sampling = max_tokens %d, temperature %v, top_p %v", req.MaxTokens, req.Temperature, req.TopP)
+	}
+	tc := req.ToolChoice
+	if tc == nil || tc.Type != "tool" || tc.Name != "list_pets" || !tc.DisableParallelToolUse {
+		t.Errorf("tool_choice = %+v", tc)
+	}
+
+	a.params = GenerationParams{ToolChoice: "required"}
+	req = a.buildRequest(context.Background(), nil, paramsTestTools)
+	if req.MaxTokens != anthropicMaxTokens || req.ToolChoice.Type != "any" {
+		t.Errorf("required: max_tokens %d, tool_choice %+v", req.MaxTokens, req.ToolChoice)
+	}
+}
+
+func TestToolChoiceFromContext(t *testing.T) {
+	ctx := WithToolChoice(context.Background(), ToolChoiceNone)
+
+	a := NewAnthropicClient("https://api.anthropic.com/v1", "k", "claude-test", "")
+	a.params = GenerationParams{ToolChoice: ToolChoiceRequired}
+	if req := a.buildRequest(ctx, nil, paramsTestTools); len(req.Tools) != 1 || req.ToolChoice == nil || req.ToolChoice.Type != "none" {
+		t.Errorf("anthropic: tools %d, tool_choice %+v", len(req.Tools), req.ToolChoice)
+	}
+
+	g := NewGeminiClient("https://generativelanguage.googleapis.com/v1beta", "k", "gemini-test", "")
+	g.params = GenerationParams{ToolChoice: "list_pets"}
+	if req := g.buildRequest(ctx, nil, paramsTestTools); req.ToolConfig.FunctionCallingConfig.Mode != "NONE" {
+		t.Errorf("gemini: toolConfig = %+v", req.ToolConfig)
+	}
+
+	req := ChatRequest{Tools: paramsTestTools}
+	GenerationParams{ToolChoice: ToolChoiceRequired}.forContext(ctx).applyTo(&req)
+	if req.ToolChoice != ToolChoiceNone {
+		t.Errorf("openai: tool_choice = %v", req.ToolChoice)
+	}
+}
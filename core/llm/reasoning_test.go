@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIReasoningStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Let me "}}]}`,
+			`{"choices":[{"index":0,"delta":{"reasoning_content":"think."}}]}`,
+			`{"choices":[{"index":0,"delta":{"reasoning":"More."}}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"Answer"}}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	var content, reasoning []string
+	client := NewAutoClient("", srv.URL, "", "m", "", true, GenerationParams{}, AzureOptions{})
+	msg, _, err := client.ChatStreamWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil,
+		func(d string) { content = append(content, d) },
+		func(d string) { reasoning = append(reasoning, d) })
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools: %v", err)
+	}
+	if msg.Content != "Answer" || strings.Join(content, "") != "Answer" {
+		t.Errorf("reasoning leaked into content: %q / %v", msg.Content, content)
+	}
+	if strings.Join(reasoning, "") != "Let me think.More." {
+		t.Errorf("reasoning = %v", reasoning)
+	}
+}
+
+func TestOpenAIReasoningNonStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"42","reasoning_content":"6*7"}}]}`)
+	}))
+	defer srv.Close()
+
+	var reasoning string
+	client := NewAutoClient("", srv.URL, "", "m", "", false, GenerationParams{}, AzureOptions{})
+	msg, _, err := client.ChatStreamWithTools(context.Background(), nil, nil, nil, func(d string) { reasoning += d })
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools: %v", err)
+	}
+	if msg.Content != "42" || reasoning != "6*7" {
+		t.Errorf("content = %q, reasoning = %q", msg.Content, reasoning)
+	}
+}
+
+func TestGeminiThoughts(t *testing.T) {
+	stream := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"pondering","thought":true}]}}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"done"}]}}]}
+
+`
+	g := NewGeminiClient("", "", "", "")
+	var reasoning string
+	msg, _, err := g.parseSSE(strings.NewReader(stream), nil, func(d string) { reasoning += d })
+	if err != nil {
+		t.Fatalf("parseSSE: %v", err)
+	}
+	if msg.Content != "done" || reasoning != "pondering" {
+		t.Errorf("content = %q, reasoning = %q", msg.Content, reasoning)
+	}
+}
+
+func TestAnthropicThinking(t *testing.T) {
+	stream := `data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}
+
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"ok"}}
+
+data: {"type":"message_stop"}
+
+`
+	a := NewAnthropicClient("", "", "", "")
+	var reasoning string
+	msg, _, err := a.parseSSE(strings.NewReader(stream), nil, func(d string) { reasoning += d })
+	if err != nil {
+		t.Fatalf("parseSSE: %v", err)
+	}
+	if msg.Content != "ok" || reasoning != "hmm" {
+		t.Errorf("content = %q, reasoning = %q", msg.Content, reasoning)
+	}
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy controls how RetryClient retries failed calls.
+// Zero fields fall back to DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled each time
+	MaxDelay    time.Duration // cap for both backoff and Retry-After
+	Jitter      float64       // 0..1, fraction of the delay randomized
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+// delay returns the wait before retry number n (1-based), honoring Retry-After when given.
+func (p RetryPolicy) delay(n int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return retryAfter
+	}
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		spread := float64(d) * p.Jitter
+		d = time.Duration(float64(d) - spread + rand.Float64()*2*spread)
+	}
+	return d
+}
+
+// RetryInfo describes a retry about to happen.
+type RetryInfo struct {
+	Attempt     int // the attempt that is about to start (2 = first retry)
+	MaxAttempts int
+	Delay       time.Duration // wait before that attempt
+	Err         error         // the failure that triggered the retry
+}
+
+type retryHookKey struct{}
+
+// WithRetryHook returns a context whose LLM calls report retries to fn.
+func WithRetryHook(ctx context.Context, fn func(RetryInfo)) context.Context {
+	return context.WithValue(ctx, retryHookKey{}, fn)
+}
+
+func retryHook(ctx context.Context) func(RetryInfo) {
+	fn, _ := ctx.Value(retryHookKey{}).(func(RetryInfo))
+	return fn
+}
+
+// RetryClient wraps another LLMClient and retries rate limits, transient 5xx
+// responses and transport errors with exponential backoff. A call is only
+// retried while nothing has been streamed to onDelta or onReasoning yet, so
+// the caller never sees duplicated text.
+type RetryClient struct {
+	inner  LLMClient
+	policy RetryPolicy
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryClient(inner LLMClient, policy RetryPolicy) *RetryClient {
+	return &RetryClient{inner: inner, policy: policy.withDefaults(), sleep: sleepCtx}
+}
+
+func (r *RetryClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	for attempt := 1; ; attempt++ {
+		streamed := false
+		msg, usage, err := r.inner.ChatStreamWithTools(ctx, messages, tools, func(d string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(d)
+			}
+		}, func(d string) {
+			streamed = true
+			if onReasoning != nil {
+				onReasoning(d)
+			}
+		})
+		if err == nil || streamed || attempt >= r.policy.MaxAttempts || !IsRetryable(ctx, err) {
+			return msg, usage, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		wait := r.policy.delay(attempt, retryAfter)
+		if hook := retryHook(ctx); hook != nil {
+			hook(RetryInfo{Attempt: attempt + 1, MaxAttempts: r.policy.MaxAttempts, Delay: wait, Err: err})
+		}
+		if serr := r.sleep(ctx, wait); serr != nil {
+			return msg, usage, err
+		}
+	}
+}
+
+// IsRetryable reports whether err is a transient failure: a retryable APIError
+// or a transport error. Cancellation by the caller is never retryable.
+func IsRetryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	// Transport failures while sending or reading the body: connection
+	// refused/reset, timeouts, truncated responses.
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
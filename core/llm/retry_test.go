@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// scriptedClient returns the queued errors in order, then succeeds.
+type scriptedClient struct {
+	errs   []error
+	deltas []string // streamed before failing/succeeding
+	calls  int
+}
+
+func (s *scriptedClient) ChatStreamWithTools(ctx context.Context, messages []Message, tools []Tool, onDelta, onReasoning func(string)) (*Message, *Usage, error) {
+	s.calls++
+	for _, d := range s.deltas {
+		onDelta(d)
+	}
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, nil, err
+	}
+	return &Message{Role: "assistant", Content: "ok"}, nil, nil
+}
+
+func newTestRetryClient(inner LLMClient, policy RetryPolicy) (*RetryClient, *[]time.Duration) {
+	r := NewRetryClient(inner, policy)
+	var slept []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return r, &slept
+}
+
+func TestRetryClientRetriesTransientErrors(t *testing.T) {
+	inner := &scriptedClient{errs: []error{
+		&APIError{Provider: "LLM", StatusCode: 503},
+		&APIError{Provider: "LLM", StatusCode: 429, RetryAfter: 7 * time.Second},
+	}}
+	r, slept := newTestRetryClient(inner, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute})
+
+	var infos []RetryInfo
+	ctx := WithRetryHook(context.Background(), func(info RetryInfo) { infos = append(infos, info) })
+	msg, _, err := r.ChatStreamWithTools(ctx, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if msg.Content != "ok" || inner.calls != 3 {
+		t.Fatalf("calls = %d, msg = %+v", inner.calls, msg)
+	}
+	if len(infos) != 2 || infos[0].Attempt != 2 || infos[1].Attempt != 3 {
+		t.Fatalf("retry hook infos = %+v", infos)
+	}
+	// second wait must honor Retry-After exactly (no jitter)
+	if (*slept)[1] != 7*time.Second {
+		t.Errorf("Retry-After not honored: slept %v", *slept)
+	}
+}
+
+func TestRetryClientGivesUp(t *testing.T) {
+	inner := &scriptedClient{errs: []error{
+		&APIError{StatusCode: 500}, &APIError{StatusCode: 500}, &APIError{StatusCode: 500},
+	}}
+	r, _ := newTestRetryClient(inner, RetryPolicy{MaxAttempts: 2})
+	if _, _, err := r.ChatStreamWithTools(context.Background(), nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if inner.calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", inner.calls)
+	}
+}
+
+func TestRetryClientSkipsNonRetryable(t *testing.T) {
+	inner := &scriptedClient{errs: []error{&APIError{StatusCode: 401}}}
+	r, _ := newTestRetryClient(inner, RetryPolicy{MaxAttempts: 5})
+	if _, _, err := r.ChatStreamWithTools(context.Background(), nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if inner.calls != 1 {
+		t.Fatalf("401 must not be retried, got %d calls", inner.calls)
+	}
+
+	inner = &scriptedClient{errs: []error{errors.New("decode response: bad json")}}
+	r, _ = newTestRetryClient(inner, RetryPolicy{MaxAttempts: 5})
+	r.ChatStreamWithTools(context.Background(), nil, nil, nil, nil)
+	if inner.calls != 1 {
+		t.Fatalf("non-transport error must not be retried, got %d calls", inner.calls)
+	}
+}
+
+func TestRetryClientNoRetryAfterDelta(t *testing.T) {
+	inner := &scriptedClient{
+		errs:   []error{&APIError{StatusCode: 503}},
+		deltas: []string{"partial"},
+	}
+	r, _ := newTestRetryClient(inner, RetryPolicy{MaxAttempts: 3})
+	if _, _, err := r.ChatStreamWithTools(context.Background(), nil, nil, func(string) {}, nil); err == nil {
+		t.Fatal("expected error once content was streamed")
+	}
+	if inner.calls != 1 {
+		t.Fatalf("must not retry after streaming, got %d calls", inner.calls)
+	}
+}
+
+func TestRetryAgainstHTTPServer(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":"slow down"}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`)
+	}))
+	defer srv.Close()
+
+	inner := NewAutoClient("", srv.URL, "", "m", "", false, GenerationParams{}, AzureOptions{})
+	r, slept := newTestRetryClient(inner, RetryPolicy{MaxAttempts: 3})
+	msg, _, err := r.ChatStreamWithTools(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Content != "hi" || hits != 2 {
+		t.Fatalf("hits = %d, msg = %+v", hits, msg)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 2*time.Second {
+		t.Errorf("slept = %v, want [2s]", *slept)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("seconds form: got %v", got)
+	}
+	future := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 0 || got > 10*time.Second {
+		t.Errorf("date form: got %v", got)
+	}
+	if got := parseRetryAfter("garbage"); got != 0 {
+		t.Errorf("garbage: got %v", got)
+	}
+}
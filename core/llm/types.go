@@ -3,10 +3,44 @@ package llm
 import "encoding/json"
 
 type Message struct {
-	Role       string     `json:"role"`
-	Content    string     `json:"content,omitempty"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role       string        `json:"role"`
+	Content    string        `json:"content,omitempty"`
+	Parts      []ContentPart `json:"parts,omitempty"` // attachments (images, files) sent alongside Content
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+	IsError    bool          `json:"is_error,omitempty"` // tool result reports a failure or a call that didn't run
+
+	// FullContent is a tool result before it was shaped into Content. It is
+	// kept for display and never sent to the model.
+	FullContent string `json:"full_content,omitempty"`
+}
+
+// RequestMessage is a Message in OpenAI wire format: Content is a plain
+// string, or an array of content parts when the message has attachments.
+type RequestMessage struct {
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+func toRequestMessages(messages []Message) []RequestMessage {
+	out := make([]RequestMessage, len(messages))
+	for i, m := range messages {
+		rm := RequestMessage{Role: m.Role, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID}
+		switch {
+		case len(m.Parts) > 0:
+			parts := make([]ContentPart, 0, len(m.Parts)+1)
+			if m.Content != "" {
+				parts = append(parts, ContentPart{Type: "text", Text: m.Content})
+			}
+			rm.Content = append(parts, m.Parts...)
+		case m.Content != "":
+			rm.Content = m.Content
+		}
+		out[i] = rm
+	}
+	return out
 }
 
 type ToolCall struct {
@@ -33,11 +67,18 @@ type ToolFunction struct {
 }
 
 type ChatRequest struct {
-	Model         string         `json:"model"`
-	Messages      []Message      `json:"messages"`
-	Tools         []Tool         `json:"tools,omitempty"`
-	Stream        bool           `json:"stream"`
-	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Model         string           `json:"model"`
+	Messages      []RequestMessage `json:"messages"`
+	Tools         []Tool           `json:"tools,omitempty"`
+	Stream        bool             `json:"stream"`
+	StreamOptions *StreamOptions   `json:"stream_options,omitempty"`
+
+	Temperature       *float64    `json:"temperature,omitempty"`
+	MaxTokens         int         `json:"max_tokens,omitempty"`
+	TopP              *float64    `json:"top_p,omitempty"`
+	Seed              *int        `json:"seed,omitempty"`
+	ParallelToolCalls *bool       `json:"parallel_tool_calls,omitempty"`
+	ToolChoice        interface{} `json:"tool_choice,omitempty"` // "auto" | "none" | "required" | {"type":"function",...}
 }
 
 type StreamOptions struct {
@@ -48,6 +89,11 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Backend names the FailoverClient entry that served the call, and Model
+	// the model it is configured with; both are empty otherwise.
+	Backend string `json:"backend,omitempty"`
+	Model   string `json:"model,omitempty"`
 }
 
 type ChatResponse struct {
@@ -57,9 +103,26 @@ type ChatResponse struct {
 }
 
 type Choice struct {
-	Index        int     `json:"index"`
-	Message      Message `json:"message"`
-	FinishReason *string `json:"finish_reason"`
+	Index        int             `json:"index"`
+	Message      ResponseMessage `json:"message"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+// ResponseMessage is an assistant Message as returned by the API, plus the
+// reasoning text some backends attach to it. Reasoning is kept out of Message
+// so it never ends up in stored conversation history.
+type ResponseMessage struct {
+	Message
+	ReasoningContent string `json:"reasoning_content,omitempty"` // DeepSeek, vLLM
+	Reasoning        string `json:"reasoning,omitempty"`         // OpenRouter, Ollama
+}
+
+// ReasoningText returns whichever reasoning field the backend filled in.
+func (m ResponseMessage) ReasoningText() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
 }
 
 type StreamChunk struct {
@@ -77,9 +140,19 @@ type StreamChoice struct {
 // StreamDelta is the delta payload in a streaming chunk.
 // Separate from Message because tool_calls have an extra "index" field.
 type StreamDelta struct {
-	Role      string           `json:"role,omitempty"`
-	Content   string           `json:"content,omitempty"`
-	ToolCalls []StreamToolCall `json:"tool_calls,omitempty"`
+	Role             string           `json:"role,omitempty"`
+	Content          string           `json:"content,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"` // DeepSeek, vLLM
+	Reasoning        string           `json:"reasoning,omitempty"`         // OpenRouter, Ollama
+	ToolCalls        []StreamToolCall `json:"tool_calls,omitempty"`
+}
+
+// ReasoningText returns whichever reasoning field the backend filled in.
+func (d StreamDelta) ReasoningText() string {
+	if d.ReasoningContent != "" {
+		return d.ReasoningContent
+	}
+	return d.Reasoning
 }
 
 type StreamToolCall struct {
@@ -0,0 +1,223 @@
+// Package policy decides whether a tool call runs, waits for the user's
+// approval or is refused, using declarative rules matched against what the
+// tool does (target, HTTP method and path, name, MCP annotations).
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Action is the outcome of a rule.
+type Action string
+
+const (
+	Allow   Action = "allow"
+	Confirm Action = "confirm"
+	Deny    Action = "deny"
+)
+
+func (a Action) valid() bool {
+	return a == Allow || a == Confirm || a == Deny
+}
+
+// ToolInfo describes a tool for rule matching.
+type ToolInfo struct {
+	Name        string
+	Target      string          // OpenAPI target or MCP client name
+	Method      string          // HTTP method; "" for non-HTTP tools
+	Path        string          // HTTP path template, e.g. /users/{id}
+	Annotations map[string]bool // MCP hints that are set: readOnlyHint, destructiveHint, idempotentHint, openWorldHint
+}
+
+// Describer is implemented by executors that know what their tools do.
+// Executors without it are matched on the tool name alone.
+type Describer interface {
+	DescribeTool(name string) ToolInfo
+}
+
+// Rule matches a tool call when every non-empty field matches. Tools,
+// Targets and Paths are globs: * stays within a path segment, ** crosses
+// segments. Matching ignores case.
+type Rule struct {
+	Name        string
+	Action      Action
+	Tools       []string
+	Targets     []string
+	Methods     []string
+	Paths       []string
+	Annotations map[string]bool
+}
+
+// Decision is the action for a tool call and the rule that produced it.
+type Decision struct {
+	Action Action
+	Rule   string // matched rule; "" when the default action applied
+}
+
+// builtinRules run after the configured rules. They allow reads, ask before
+// writes that replace or delete data, and catch destructive tool names on
+// tools whose method says nothing (POST, MCP).
+var builtinRules = []Rule{
+	{Name: "builtin:read-method", Action: Allow, Methods: []string{"GET", "HEAD", "OPTIONS"}},
+	{Name: "builtin:read-only-hint", Action: Allow, Annotations: map[string]bool{"readOnlyHint": true}},
+	{Name: "builtin:write-method", Action: Confirm, Methods: []string{"PUT", "PATCH", "DELETE"}},
+	{Name: "builtin:destructive-hint", Action: Confirm, Annotations: map[string]bool{"destructiveHint": true}},
+	{Name: "builtin:destructive-name", Action: Confirm, Tools: []string{"*delete*", "*remove*", "*destroy*", "*drop*", "*purge*"}},
+}
+
+// Policy evaluates rules in order; the first match wins.
+type Policy struct {
+	rules []rule
+	def   Action
+}
+
+type rule struct {
+	name        string
+	action      Action
+	tools       []*regexp.Regexp
+	targets     []*regexp.Regexp
+	methods     []string
+	paths       []*regexp.Regexp
+	annotations map[string]bool
+}
+
+// New builds a policy from rules followed by the built-in rules. def applies
+// when nothing matches; "" means allow.
+func New(rules []Rule, def Action) (*Policy, error) {
+	if def == "" {
+		def = Allow
+	}
+	if !def.valid() {
+		return nil, fmt.Errorf("policy: invalid default action %q", def)
+	}
+	p := &Policy{def: def}
+	for i, r := range append(append([]Rule(nil), rules...), builtinRules...) {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		c, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("policy: %s: %w", r.Name, err)
+		}
+		p.rules = append(p.rules, c)
+	}
+	return p, nil
+}
+
+var builtin, _ = New(nil, Allow)
+
+// Decide returns the action for a call to the described tool. A nil policy
+// uses the built-in rules.
+func (p *Policy) Decide(t ToolInfo) Decision {
+	if p == nil {
+		p = builtin
+	}
+	for _, r := range p.rules {
+		if r.matches(t) {
+			return Decision{Action: r.action, Rule: r.name}
+		}
+	}
+	return Decision{Action: p.def}
+}
+
+func compile(r Rule) (rule, error) {
+	if !r.Action.valid() {
+		return rule{}, fmt.Errorf("invalid action %q (want allow, confirm or deny)", r.Action)
+	}
+	c := rule{name: r.Name, action: r.Action, annotations: r.Annotations}
+	var err error
+	if c.tools, err = globs(r.Tools); err != nil {
+		return rule{}, err
+	}
+	if c.targets, err = globs(r.Targets); err != nil {
+		return rule{}, err
+	}
+	if c.paths, err = globs(r.Paths); err != nil {
+		return rule{}, err
+	}
+	for _, m := range r.Methods {
+		c.methods = append(c.methods, strings.ToUpper(m))
+	}
+	return c, nil
+}
+
+func (r rule) matches(t ToolInfo) bool {
+	if len(r.tools) > 0 && !anyMatch(r.tools, t.Name) {
+		return false
+	}
+	if len(r.targets) > 0 && !anyMatch(r.targets, t.Target) {
+		return false
+	}
+	if len(r.methods) > 0 && !contains(r.methods, strings.ToUpper(t.Method)) {
+		return false
+	}
+	if len(r.paths) > 0 && (t.Path == "" || !anyMatch(r.paths, t.Path)) {
+		return false
+	}
+	for k, v := range r.annotations {
+		if got, ok := t.Annotations[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func anyMatch(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func globs(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := glob(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// glob compiles a pattern where ** matches anything, * anything but '/'
+// and ? a single character other than '/'.
+func glob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,88 @@
+package policy
+
+import "testing"
+
+func TestBuiltinRules(t *testing.T) {
+	cases := []struct {
+		tool ToolInfo
+		want Action
+		rule string
+	}{
+		{ToolInfo{Name: "pets__listPets", Method: "GET"}, Allow, "builtin:read-method"},
+		{ToolInfo{Name: "pets__getDeletedPets", Method: "GET"}, Allow, "builtin:read-method"},
+		{ToolInfo{Name: "users__resetPassword", Method: "POST"}, Allow, ""},
+		{ToolInfo{Name: "pets__createPet", Method: "POST"}, Allow, ""},
+		{ToolInfo{Name: "pets__updatePet", Method: "put"}, Confirm, "builtin:write-method"},
+		{ToolInfo{Name: "pets__deletePet", Method: "DELETE"}, Confirm, "builtin:write-method"},
+		{ToolInfo{Name: "users__bulkDeleteUsers", Method: "POST"}, Confirm, "builtin:destructive-name"},
+		{ToolInfo{Name: "fs__remove_file"}, Confirm, "builtin:destructive-name"},
+		{ToolInfo{Name: "db__query", Annotations: map[string]bool{"destructiveHint": true}}, Confirm, "builtin:destructive-hint"},
+		{ToolInfo{Name: "db__drop_stats", Annotations: map[string]bool{"readOnlyHint": true}}, Allow, "builtin:read-only-hint"},
+	}
+	var p *Policy
+	for _, c := range cases {
+		got := p.Decide(c.tool)
+		if got.Action != c.want || got.Rule != c.rule {
+			t.Errorf("Decide(%s) = %+v, want %s by %q", c.tool.Name, got, c.want, c.rule)
+		}
+	}
+}
+
+func TestConfiguredRulesComeFirst(t *testing.T) {
+	p, err := New([]Rule{
+		{Name: "admin-deletes", Action: Deny, Targets: []string{"admin"}, Methods: []string{"delete"}, Paths: []string{"/users/**"}},
+		{Action: Allow, Tools: []string{"pets__delete_cache"}},
+		{Name: "orders", Action: Confirm, Paths: []string{"/orders/*"}},
+	}, Confirm)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cases := []struct {
+		tool ToolInfo
+		want Decision
+	}{
+		{ToolInfo{Name: "admin__deleteRole", Target: "Admin", Method: "DELETE", Path: "/users/{id}/roles/{role}"}, Decision{Deny, "admin-deletes"}},
+		{ToolInfo{Name: "admin__deleteTeam", Target: "admin", Method: "DELETE", Path: "/teams/{id}"}, Decision{Confirm, "builtin:write-method"}},
+		{ToolInfo{Name: "pets__delete_cache", Target: "pets", Method: "POST", Path: "/cache"}, Decision{Allow, "rule 2"}},
+		{ToolInfo{Name: "shop__getOrder", Method: "GET", Path: "/orders/{id}"}, Decision{Confirm, "orders"}},
+		{ToolInfo{Name: "shop__getOrderItems", Method: "GET", Path: "/orders/{id}/items"}, Decision{Allow, "builtin:read-method"}},
+		{ToolInfo{Name: "shop__checkout", Method: "POST", Path: "/checkout"}, Decision{Confirm, ""}},
+	}
+	for _, c := range cases {
+		if got := p.Decide(c.tool); got != c.want {
+			t.Errorf("Decide(%s) = %+v, want %+v", c.tool.Name, got, c.want)
+		}
+	}
+}
+
+func TestNewRejectsInvalidActions(t *testing.T) {
+	if _, err := New([]Rule{{Name: "x", Action: "block"}}, Allow); err == nil {
+		t.Error("expected error for unknown rule action")
+	}
+	if _, err := New(nil, "ask"); err == nil {
+		t.Error("expected error for unknown default action")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"/users/*", "/users/{id}", true},
+		{"/users/*", "/users/{id}/roles", false},
+		{"/users/**", "/users/{id}/roles", true},
+		{"*delete*", "pets__DeletePet", true},
+		{"pets__get?et", "pets__getPet", true},
+		{"a.b", "axb", false},
+	}
+	for _, c := range cases {
+		re, err := glob(c.pattern)
+		if err != nil {
+			t.Fatalf("glob(%q): %v", c.pattern, err)
+		}
+		if got := re.MatchString(c.s); got != c.want {
+			t.Errorf("glob(%q).Match(%q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
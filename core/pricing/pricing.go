@@ -0,0 +1,154 @@
+// Package pricing converts token usage into money using a per-model price table.
+package pricing
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultCurrency is the currency of the built-in price table.
+const DefaultCurrency = "USD"
+
+// Price is the cost of one million tokens.
+type Price struct {
+	Input  float64 `json:"input" yaml:"input"`
+	Output float64 `json:"output" yaml:"output"`
+}
+
+// Cost returns the price of a call with the given token counts.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// Defaults are list prices in USD per million tokens for common hosted
+// models. Keys match model names by prefix, so dated snapshots such as
+// gpt-4o-2024-08-06 use the gpt-4o price.
+var Defaults = map[string]Price{
+	// OpenAI
+	"gpt-5":              {1.25, 10},
+	"gpt-5-mini":         {0.25, 2},
+	"gpt-5-nano":         {0.05, 0.40},
+	"gpt-5-pro":          {15, 120},
+	"gpt-4.5":            {75, 150},
+	"gpt-4.1":            {2, 8},
+	"gpt-4.1-mini":       {0.40, 1.60},
+	"gpt-4.1-nano":       {0.10, 0.40},
+	"gpt-4o":             {2.50, 10},
+	"gpt-4o-mini":        {0.15, 0.60},
+	"gpt-4-turbo":        {10, 30},
+	"gpt-4-1106-preview": {10, 30},
+	"gpt-4-0125-preview": {10, 30},
+	"gpt-4":              {30, 60}, // snapshot-only, see snapshotOnly
+	"gpt-3.5-turbo":      {0.50, 1.50},
+	"o1":                 {15, 60},
+	"o1-mini":            {1.10, 4.40},
+	"o1-pro":             {150, 600},
+	"o3":                 {2, 8},
+	"o3-mini":            {1.10, 4.40},
+	"o3-pro":             {20, 80},
+	"o4-mini":            {1.10, 4.40},
+
+	// Anthropic
+	"claude-opus-4":     {15, 75},
+	"claude-sonnet-4":   {3, 15},
+	"claude-haiku-4":    {1, 5},
+	"claude-3-7-sonnet": {3, 15},
+	"claude-3-5-sonnet": {3, 15},
+	"claude-3-5-haiku":  {0.80, 4},
+	"claude-3-haiku":    {0.25, 1.25},
+
+	// Google
+	"gemini-2.5-pro":        {1.25, 10},
+	"gemini-2.5-flash":      {0.30, 2.50},
+	"gemini-2.5-flash-lite": {0.10, 0.40},
+	"gemini-2.0-flash":      {0.10, 0.40},
+	"gemini-2.0-flash-lite": {0.075, 0.30},
+
+	// DeepSeek
+	"deepseek-chat":     {0.27, 1.10},
+	"deepseek-reasoner": {0.55, 2.19},
+}
+
+// snapshotOnly keys are prefixes of newer, cheaper families (gpt-4 of
+// gpt-4.1, gpt-4o and whatever follows), so they only match the model
+// itself and its dated snapshots such as gpt-4-0613.
+var snapshotOnly = map[string]bool{"gpt-4": true}
+
+// Table looks up model prices. The zero value prices nothing.
+type Table struct {
+	Currency string
+	prices   map[string]Price // lower-cased model prefix → price
+}
+
+// NewTable builds a table from overrides layered on top of Defaults. The
+// defaults are in USD, so they are only included when currency is USD or
+// empty; for other currencies every priced model must be configured.
+func NewTable(currency string, overrides map[string]Price) *Table {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	t := &Table{Currency: currency, prices: make(map[string]Price)}
+	if strings.EqualFold(currency, DefaultCurrency) {
+		for model, p := range Defaults {
+			t.prices[model] = p
+		}
+	}
+	for model, p := range overrides {
+		t.prices[strings.ToLower(model)] = p
+	}
+	return t
+}
+
+// Lookup returns the price for model: an exact match, otherwise the longest
+// configured prefix. A "vendor/" prefix (OpenRouter style) is ignored.
+// snapshotOnly keys match as prefixes only of dated snapshots.
+func (t *Table) Lookup(model string) (Price, bool) {
+	if t == nil || model == "" {
+		return Price{}, false
+	}
+	m := strings.ToLower(model)
+	if p, ok := t.prices[m]; ok {
+		return p, true
+	}
+	if i := strings.LastIndexByte(m, '/'); i >= 0 {
+		m = m[i+1:]
+	}
+
+	keys := make([]string, 0, len(t.prices))
+	for k := range t.prices {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, k := range keys {
+		if strings.HasPrefix(m, k) && (!snapshotOnly[k] || isSnapshot(m[len(k):])) {
+			return t.prices[k], true
+		}
+	}
+	return Price{}, false
+}
+
+// isSnapshot reports whether suffix is a dated snapshot such as "-0613"
+// or "-2024-08-06".
+func isSnapshot(suffix string) bool {
+	if suffix == "" {
+		return true
+	}
+	if len(suffix) < 5 || suffix[0] != '-' {
+		return false
+	}
+	for _, c := range suffix[1:5] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cost prices a call to model. ok is false when the model has no price.
+func (t *Table) Cost(model string, promptTokens, completionTokens int) (cost float64, ok bool) {
+	p, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return p.Cost(promptTokens, completionTokens), true
+}
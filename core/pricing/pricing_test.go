@@ -0,0 +1,61 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookupPrefix(t *testing.T) {
+	tab := NewTable("", nil)
+	cases := map[string]Price{
+		"gpt-4o-2024-08-06":          Defaults["gpt-4o"],
+		"gpt-4o-mini":                Defaults["gpt-4o-mini"],
+		"GPT-4.1-mini":               Defaults["gpt-4.1-mini"],
+		"openai/gpt-4o":              Defaults["gpt-4o"],
+		"claude-sonnet-4-5-20250929": Defaults["claude-sonnet-4"],
+		"o3-mini-high":               Defaults["o3-mini"],
+		"gpt-4-0613":                 Defaults["gpt-4"],
+		"gpt-4":                      Defaults["gpt-4"],
+		"gpt-4.1-2025-04-14":         Defaults["gpt-4.1"],
+		"gpt-4.5-preview":            Defaults["gpt-4.5"],
+	}
+	for model, want := range cases {
+		got, ok := tab.Lookup(model)
+		if !ok || got != want {
+			t.Errorf("%s: got %v (%v), want %v", model, got, ok, want)
+		}
+	}
+	if _, ok := tab.Lookup("qwen2.5:7b"); ok {
+		t.Error("local model should have no price")
+	}
+	// An unknown gpt-4 family must not fall back to the legacy gpt-4 rate.
+	if p, ok := tab.Lookup("gpt-4.7-preview"); ok {
+		t.Errorf("gpt-4.7-preview priced as %v", p)
+	}
+}
+
+func TestOverridesAndCurrency(t *testing.T) {
+	tab := NewTable("USD", map[string]Price{"qwen2.5": {Input: 0, Output: 0}, "GPT-4o": {Input: 1, Output: 2}})
+	if p, _ := tab.Lookup("gpt-4o"); p.Input != 1 {
+		t.Errorf("override not applied: %v", p)
+	}
+	if _, ok := tab.Lookup("qwen2.5:7b"); !ok {
+		t.Error("configured free model should be priced")
+	}
+
+	cny := NewTable("CNY", map[string]Price{"deepseek-chat": {Input: 2, Output: 8}})
+	if _, ok := cny.Lookup("gpt-4o"); ok {
+		t.Error("USD defaults must not apply to another currency")
+	}
+	cost, ok := cny.Cost("deepseek-chat", 1_000_000, 500_000)
+	if !ok || math.Abs(cost-6) > 1e-9 {
+		t.Errorf("cost = %v, %v; want 6", cost, ok)
+	}
+}
+
+func TestNilTable(t *testing.T) {
+	var tab *Table
+	if _, ok := tab.Cost("gpt-4o", 10, 10); ok {
+		t.Error("nil table should price nothing")
+	}
+}
@@ -0,0 +1,46 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates token counts without a vocabulary. Weights follow
+// what BPE tokenizers typically produce per character:
+//
+//	ASCII letters, digits, spaces   ~4 chars per token
+//	ASCII punctuation (JSON, code)  ~2 chars per token
+//	CJK ideographs, kana, hangul    ~1 token per char or more
+//	other letters (Cyrillic, …)     ~2 chars per token
+//	emoji and other symbols         ~1 token per char
+//
+// It errs on the high side for CJK, where a byte-length heuristic is worst.
+type Estimator struct{}
+
+func (Estimator) Count(text string) int {
+	var n float64
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+				n += 0.25
+			} else {
+				n += 0.5
+			}
+		case isCJK(r):
+			n += 1.25
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || unicode.IsSpace(r):
+			n += 0.5
+		default:
+			n += 1
+		}
+	}
+	return int(math.Ceil(n))
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // full-width forms
+}
@@ -0,0 +1,98 @@
+// Package tokenizer counts tokens for context budgeting: exact BPE counts for
+// OpenAI-family models, a script-aware estimate for everything else.
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Counter returns the number of tokens text encodes to.
+type Counter interface {
+	Count(text string) int
+}
+
+func init() {
+	// Use the embedded BPE ranks instead of downloading them at runtime.
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// BPE encodings used by OpenAI chat models.
+const (
+	EncodingO200K  = "o200k_base"
+	EncodingCL100K = "cl100k_base"
+)
+
+// ForModel returns a BPE counter when the model is known to use an OpenAI
+// encoding, and Estimator otherwise.
+func ForModel(model string) Counter {
+	if enc := encodingForModel(model); enc != "" {
+		return NewBPE(enc)
+	}
+	return Estimator{}
+}
+
+func encodingForModel(model string) string {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 { // e.g. openai/gpt-4o via a router
+		m = m[i+1:]
+	}
+	for _, prefix := range []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(m, prefix) {
+			return EncodingO200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "text-embedding-"} {
+		if strings.HasPrefix(m, prefix) {
+			return EncodingCL100K
+		}
+	}
+	return ""
+}
+
+// BPE counts tokens with a tiktoken encoding. The ranks are loaded on first
+// use; if that fails it degrades to Estimator.
+type BPE struct {
+	encoding string
+	once     sync.Once
+	enc      *tiktoken.Tiktoken
+}
+
+func NewBPE(encoding string) *BPE {
+	return &BPE{encoding: encoding}
+}
+
+func (b *BPE) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	b.once.Do(func() { b.enc = loadEncoding(b.encoding) })
+	if b.enc == nil {
+		return Estimator{}.Count(text)
+	}
+	return len(b.enc.EncodeOrdinary(text))
+}
+
+var (
+	encMu    sync.Mutex
+	encCache = make(map[string]*tiktoken.Tiktoken)
+)
+
+// loadEncoding builds an encoding once per process; engines are rebuilt on
+// every config reload and the rank tables are large.
+func loadEncoding(name string) *tiktoken.Tiktoken {
+	encMu.Lock()
+	defer encMu.Unlock()
+	if enc, ok := encCache[name]; ok {
+		return enc
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil
+	}
+	encCache[name] = enc
+	return enc
+}
@@ -0,0 +1,57 @@
+package tokenizer
+
+import "testing"
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4o-mini":        EncodingO200K,
+		"GPT-4.1":            EncodingO200K,
+		"o3-mini":            EncodingO200K,
+		"openai/gpt-4o":      EncodingO200K,
+		"gpt-4-turbo":        EncodingCL100K,
+		"gpt-3.5-turbo-0125": EncodingCL100K,
+		"deepseek-chat":      "",
+		"qwen2.5:7b":         "",
+		"claude-sonnet-4":    "",
+	}
+	for model, want := range cases {
+		if got := encodingForModel(model); got != want {
+			t.Errorf("%s: got %q, want %q", model, got, want)
+		}
+	}
+	if _, ok := ForModel("qwen2.5:7b").(Estimator); !ok {
+		t.Error("unknown model should use Estimator")
+	}
+}
+
+func TestBPECount(t *testing.T) {
+	c := NewBPE(EncodingCL100K)
+	if got := c.Count("hello world"); got != 2 {
+		t.Errorf("cl100k(hello world) = %d, want 2", got)
+	}
+	if got := c.Count(""); got != 0 {
+		t.Errorf("empty = %d", got)
+	}
+}
+
+func TestBPEFallsBackOnUnknownEncoding(t *testing.T) {
+	c := NewBPE("no_such_encoding")
+	if got, want := c.Count("hello world"), (Estimator{}).Count("hello world"); got != want {
+		t.Errorf("got %d, want estimator's %d", got, want)
+	}
+}
+
+func TestEstimatorCJK(t *testing.T) {
+	e := Estimator{}
+	zh := "请帮我查询所有用户的订单信息" // 14 Han characters
+	if got := e.Count(zh); got < 14 {
+		t.Errorf("CJK undercounted: %d tokens for 14 chars", got)
+	}
+	// The old len/4 heuristic gave 42/4 = 10 here.
+	if byteEstimate := len(zh) / 4; e.Count(zh) <= byteEstimate {
+		t.Errorf("estimator (%d) should exceed byte heuristic (%d)", e.Count(zh), byteEstimate)
+	}
+	if got := e.Count("1234567890123456"); got != 4 {
+		t.Errorf("ASCII = %d, want 4", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Package toolctx carries per-call state between the tool loop and the
+// executors behind it. It has no dependencies, so executors can use it
+// without importing the loop.
+package toolctx
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// CallReport is what an executor tells the loop about one tool call, beyond
+// its result. Executors fill in the report found in the call's context.
+type CallReport struct {
+	Cached     bool // the result was reused from an earlier identical call
+	HTTPStatus int  // status code of the HTTP response, if any
+	Failed     bool // the tool ran but reported failure, e.g. an MCP isError result
+}
+
+type reportKey struct{}
+
+// ReportFrom returns the report of the tool call ctx belongs to, or nil
+// outside a tool call.
+func ReportFrom(ctx context.Context) *CallReport {
+	r, _ := ctx.Value(reportKey{}).(*CallReport)
+	return r
+}
+
+// WithReport returns a context for a tool call whose executor fills in r.
+func WithReport(ctx context.Context, r *CallReport) context.Context {
+	return context.WithValue(ctx, reportKey{}, r)
+}
+
+type turnKey struct{}
+
+var turns atomic.Uint64
+
+// TurnID identifies the turn a tool call belongs to, so executors can scope
+// state such as caches to it. It is 0 outside a turn.
+func TurnID(ctx context.Context) uint64 {
+	id, _ := ctx.Value(turnKey{}).(uint64)
+	return id
+}
+
+// WithTurn returns a context for a new turn. toolloop.Run calls it; executors'
+// tests and hosts calling tools outside a turn can too.
+func WithTurn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, turnKey{}, turns.Add(1))
+}
@@ -0,0 +1,282 @@
+// Package toolindex ranks tools by relevance to a query, so large toolsets
+// can be narrowed down before they are sent to the model. Ranking is BM25
+// over tool names, groups, descriptions and parameter names, optionally
+// fused with embedding similarity.
+package toolindex
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// nameWeight repeats name and group terms, which say more about a tool than
+// its description.
+const nameWeight = 3
+
+// Match is a tool with its relevance score; higher is better.
+type Match struct {
+	Tool  llm.Tool
+	Score float64
+}
+
+// Index is a BM25 index over a fixed set of tools.
+type Index struct {
+	tools  []llm.Tool
+	texts  []string         // what describes each tool, for embeddings
+	terms  []map[string]int // term frequencies per tool
+	lens   []int
+	df     map[string]int
+	avgLen float64
+}
+
+// New indexes tools. groupOf names the module group of a tool (e.g. the
+// OpenAPI tag) and may be nil.
+func New(tools []llm.Tool, groupOf func(name string) string) *Index {
+	ix := &Index{
+		tools: tools,
+		texts: make([]string, len(tools)),
+		terms: make([]map[string]int, len(tools)),
+		lens:  make([]int, len(tools)),
+		df:    make(map[string]int),
+	}
+	total := 0
+	for i, t := range tools {
+		group := ""
+		if groupOf != nil {
+			group = groupOf(t.Function.Name)
+		}
+		ix.texts[i] = toolText(t, group)
+		tf := make(map[string]int)
+		n := 0
+		for _, term := range toolTerms(t, group) {
+			tf[term]++
+			n++
+		}
+		for term := range tf {
+			ix.df[term]++
+		}
+		ix.terms[i], ix.lens[i] = tf, n
+		total += n
+	}
+	if len(tools) > 0 {
+		ix.avgLen = float64(total) / float64(len(tools))
+	}
+	return ix
+}
+
+// Len returns the number of indexed tools.
+func (ix *Index) Len() int { return len(ix.tools) }
+
+// Tools returns the indexed tools in their original order.
+func (ix *Index) Tools() []llm.Tool { return ix.tools }
+
+// Search returns up to k tools matching query, best first. Tools sharing no
+// term with the query are left out.
+func (ix *Index) Search(query string, k int) []Match {
+	qterms := uniq(Tokenize(query))
+	n := float64(len(ix.tools))
+	var matches []Match
+	for i, tf := range ix.terms {
+		score := 0.0
+		for _, term := range qterms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			df := float64(ix.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - b + b*float64(ix.lens[i])/ix.avgLen
+			score += idf * f * (k1 + 1) / (f + k1*norm)
+		}
+		if score > 0 {
+			matches = append(matches, Match{Tool: ix.tools[i], Score: score})
+		}
+	}
+	sortMatches(matches)
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Fuse merges rankings with reciprocal rank fusion, so lists with
+// incomparable scores (BM25, cosine similarity) can be combined.
+func Fuse(k int, rankings ...[]Match) []Match {
+	const c = 60
+	scores := make(map[string]float64)
+	tools := make(map[string]llm.Tool)
+	for _, ranking := range rankings {
+		for rank, m := range ranking {
+			name := m.Tool.Function.Name
+			scores[name] += 1 / float64(c+rank+1)
+			tools[name] = m.Tool
+		}
+	}
+	matches := make([]Match, 0, len(scores))
+	for name, s := range scores {
+		matches = append(matches, Match{Tool: tools[name], Score: s})
+	}
+	sortMatches(matches)
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Tool.Function.Name < matches[j].Tool.Function.Name
+	})
+}
+
+// toolText is what describes a tool for embeddings: name, group,
+// description and parameter names.
+func toolText(t llm.Tool, group string) string {
+	parts := []string{t.Function.Name}
+	if group != "" {
+		parts = append(parts, "("+group+")")
+	}
+	parts = append(parts, t.Function.Description)
+	if params := paramNames(t); len(params) > 0 {
+		parts = append(parts, "Parameters: "+strings.Join(params, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+func toolTerms(t llm.Tool, group string) []string {
+	var terms []string
+	// The name includes the source prefix, e.g. "petstore__getPet".
+	name := append(Tokenize(t.Function.Name), Tokenize(group)...)
+	for i := 0; i < nameWeight; i++ {
+		terms = append(terms, name...)
+	}
+	terms = append(terms, Tokenize(t.Function.Description)...)
+	for _, p := range paramNames(t) {
+		terms = append(terms, Tokenize(p)...)
+	}
+	return terms
+}
+
+func paramNames(t llm.Tool) []string {
+	params, _ := t.Function.Parameters.(map[string]interface{})
+	props, _ := params["properties"].(map[string]interface{})
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stopwords are too common in tool descriptions to tell tools apart.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "by": true, "for": true,
+	"from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "this": true, "to": true, "with": true,
+}
+
+// Tokenize lowercases text and splits it into terms: words split at case
+// changes, digits and punctuation ("getPetById" → get, pet, id), light
+// plural stemming, and single characters plus bigrams for Han, kana and
+// Hangul runs, which have no spaces.
+func Tokenize(text string) []string {
+	var terms []string
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			w := stem(strings.ToLower(string(word)))
+			if !stopwords[w] {
+				terms = append(terms, w)
+			}
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			terms = append(terms, string(cjk[i]))
+			if i+1 < len(cjk) {
+				terms = append(terms, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			if len(word) > 0 && boundary(runes[i-1], r, runes, i) {
+				flushWord()
+			}
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+// boundary reports whether a word ends between prev and r: lower→Upper,
+// letter↔digit, and the last capital of an acronym before a lowercase
+// letter ("HTTPServer" → http, server).
+func boundary(prev, r rune, runes []rune, i int) bool {
+	switch {
+	case unicode.IsLower(prev) && unicode.IsUpper(r):
+		return true
+	case unicode.IsDigit(prev) != unicode.IsDigit(r):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		return true
+	}
+	return false
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// stem strips plural endings so "pets" matches "pet".
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 4 && (strings.HasSuffix(w, "ses") || strings.HasSuffix(w, "xes")):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func uniq(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package toolindex
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+func tool(name, desc string, params ...string) llm.Tool {
+	props := map[string]interface{}{}
+	for _, p := range params {
+		props[p] = map[string]interface{}{"type": "string"}
+	}
+	return llm.Tool{Type: "function", Function: llm.ToolFunction{
+		Name:        name,
+		Description: desc,
+		Parameters:  map[string]interface{}{"type": "object", "properties": props},
+	}}
+}
+
+var tools = []llm.Tool{
+	tool("shop__listOrders", "List orders of the current user", "status"),
+	tool("shop__cancelOrder", "Cancel an order that has not shipped", "orderId"),
+	tool("shop__getPetById", "Find a pet by ID", "petId"),
+	tool("shop__uploadFile", "Upload an image for a pet", "petId", "file"),
+	tool("crm__createContact", "Create a contact", "email"),
+	tool("crm__sendEmail", "Send an email to a contact", "to", "subject"),
+	tool("docs__search", "全文搜索文档"),
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"getPetById", []string{"get", "pet", "id"}},
+		{"HTTPServer v2", []string{"http", "server", "v", "2"}},
+		{"shop__listOrders", []string{"shop", "list", "order"}},
+		{"List the categories", []string{"list", "category"}},
+		{"搜索文档", []string{"搜", "搜索", "索", "索文", "文", "文档", "档"}},
+		{"查询order", []string{"查", "查询", "询", "order"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func names(matches []Match) []string {
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.Tool.Function.Name
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	ix := New(tools, nil)
+	tests := []struct {
+		query string
+		k     int
+		want  []string
+	}{
+		{"please cancel my order", 1, []string{"shop__cancelOrder"}},
+		{"send an email to Bob", 1, []string{"crm__sendEmail"}},
+		{"upload a photo of my pet", 1, []string{"shop__uploadFile"}},
+		{"搜索部署文档", 1, []string{"docs__search"}},
+		{"what's the weather", 3, []string{}},
+	}
+	for _, tt := range tests {
+		got := names(ix.Search(tt.query, tt.k))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMatchesGroups(t *testing.T) {
+	group := map[string]string{"crm__createContact": "customers", "crm__sendEmail": "customers"}
+	ix := New(tools, func(name string) string { return group[name] })
+	got := names(ix.Search("customers", 5))
+	if want := []string{"crm__createContact", "crm__sendEmail"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search by group = %v, want %v", got, want)
+	}
+}
+
+func TestFuse(t *testing.T) {
+	a := []Match{{Tool: tools[0]}, {Tool: tools[1]}, {Tool: tools[2]}}
+	b := []Match{{Tool: tools[1]}, {Tool: tools[3]}}
+	got := names(Fuse(3, a, b))
+	if want := []string{"shop__cancelOrder", "shop__listOrders", "shop__uploadFile"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fuse = %v, want %v", got, want)
+	}
+}
+
+// keywordEmbedder embeds texts as presence vectors over a fixed vocabulary.
+type keywordEmbedder struct {
+	vocab []string
+	calls int
+}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(e.vocab))
+		for j, w := range e.vocab {
+			if strings.Contains(strings.ToLower(text), w) {
+				v[j] = 1
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestVectorsSearch(t *testing.T) {
+	emb := &keywordEmbedder{vocab: []string{"send", "email", "order", "pet"}}
+	v := NewVectors(emb)
+	ix := New(tools, nil)
+
+	got, err := v.Search(context.Background(), ix, "send the customer an email", 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if n := names(got); len(n) != 1 || n[0] != "crm__sendEmail" {
+		t.Errorf("Search = %v, want crm__sendEmail", n)
+	}
+
+	// Tool vectors are cached; only the query is embedded again.
+	if _, err := v.Search(context.Background(), ix, "an order", 1); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if emb.calls != 3 {
+		t.Errorf("Embed called %d times, want 3 (tools once, two queries)", emb.calls)
+	}
+}
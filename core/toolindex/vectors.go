@@ -0,0 +1,99 @@
+package toolindex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// embedBatch caps the texts sent in one embedding request.
+const embedBatch = 64
+
+// Vectors ranks tools by embedding similarity. Tool vectors are cached by
+// text, so each tool is embedded once per description.
+type Vectors struct {
+	emb   llm.Embedder
+	mu    sync.Mutex
+	cache map[string][]float32
+}
+
+// NewVectors returns a ranker using emb.
+func NewVectors(emb llm.Embedder) *Vectors {
+	return &Vectors{emb: emb, cache: make(map[string][]float32)}
+}
+
+// Search returns up to k of ix's tools, most similar to query first.
+func (v *Vectors) Search(ctx context.Context, ix *Index, query string, k int) ([]Match, error) {
+	if err := v.embedMissing(ctx, ix.texts); err != nil {
+		return nil, err
+	}
+	qv, err := v.emb.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(qv) != 1 {
+		return nil, fmt.Errorf("embedding: got %d vectors for 1 query", len(qv))
+	}
+
+	v.mu.Lock()
+	matches := make([]Match, len(ix.tools))
+	for i, t := range ix.tools {
+		matches[i] = Match{Tool: t, Score: cosine(qv[0], v.cache[ix.texts[i]])}
+	}
+	v.mu.Unlock()
+
+	sortMatches(matches)
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func (v *Vectors) embedMissing(ctx context.Context, texts []string) error {
+	v.mu.Lock()
+	var missing []string
+	seen := make(map[string]bool)
+	for _, t := range texts {
+		if _, ok := v.cache[t]; !ok && !seen[t] {
+			seen[t] = true
+			missing = append(missing, t)
+		}
+	}
+	v.mu.Unlock()
+
+	for start := 0; start < len(missing); start += embedBatch {
+		batch := missing[start:min(start+embedBatch, len(missing))]
+		vecs, err := v.emb.Embed(ctx, batch)
+		if err != nil {
+			return err
+		}
+		if len(vecs) != len(batch) {
+			return fmt.Errorf("embedding: got %d vectors for %d texts", len(vecs), len(batch))
+		}
+		v.mu.Lock()
+		for i, t := range batch {
+			v.cache[t] = vecs[i]
+		}
+		v.mu.Unlock()
+	}
+	return nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
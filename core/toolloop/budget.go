@@ -0,0 +1,105 @@
+package toolloop
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Budget limits one chat turn. Zero fields are unlimited, except
+// MaxIterations which defaults to MaxIterations.
+type Budget struct {
+	MaxIterations int           // LLM calls
+	MaxToolCalls  int           // executed tool calls
+	MaxTokens     int           // total tokens reported by the provider
+	MaxDuration   time.Duration // wall clock
+}
+
+// override returns b with every non-zero field of o replacing it.
+func (b Budget) override(o Budget) Budget {
+	if o.MaxIterations > 0 {
+		b.MaxIterations = o.MaxIterations
+	}
+	if o.MaxToolCalls > 0 {
+		b.MaxToolCalls = o.MaxToolCalls
+	}
+	if o.MaxTokens > 0 {
+		b.MaxTokens = o.MaxTokens
+	}
+	if o.MaxDuration > 0 {
+		b.MaxDuration = o.MaxDuration
+	}
+	return b
+}
+
+// Reasons reported in BudgetEvent.
+const (
+	BudgetIterations = "iterations"
+	BudgetToolCalls  = "tool_calls"
+	BudgetTokens     = "tokens"
+	BudgetDuration   = "duration"
+)
+
+// BudgetEvent is emitted when a turn runs out of budget, just before the
+// model is asked to wrap up. Limit is in seconds for "duration".
+type BudgetEvent struct {
+	Reason string `json:"reason"`
+	Limit  int    `json:"limit"`
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a context whose turns use b's non-zero fields instead of
+// the loop's budget, e.g. for a per-conversation override.
+func WithBudget(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// budget resolves the limits for a turn.
+func (l *Loop) budget(ctx context.Context) Budget {
+	b := l.limits
+	if o, ok := ctx.Value(budgetKey{}).(Budget); ok {
+		b = b.override(o)
+	}
+	if b.MaxIterations <= 0 {
+		b.MaxIterations = MaxIterations
+	}
+	return b
+}
+
+// turnState tracks what a turn has used so far.
+type turnState struct {
+	iterations int
+	toolCalls  int
+	tokens     int
+	start      time.Time
+}
+
+// exceeded reports the first budget the turn has used up.
+func (b Budget) exceeded(s turnState, now time.Time) (BudgetEvent, bool) {
+	switch {
+	case s.iterations >= b.MaxIterations:
+		return BudgetEvent{Reason: BudgetIterations, Limit: b.MaxIterations}, true
+	case b.MaxToolCalls > 0 && s.toolCalls >= b.MaxToolCalls:
+		return BudgetEvent{Reason: BudgetToolCalls, Limit: b.MaxToolCalls}, true
+	case b.MaxTokens > 0 && s.tokens >= b.MaxTokens:
+		return BudgetEvent{Reason: BudgetTokens, Limit: b.MaxTokens}, true
+	case b.MaxDuration > 0 && now.Sub(s.start) >= b.MaxDuration:
+		return BudgetEvent{Reason: BudgetDuration, Limit: int(b.MaxDuration / time.Second)}, true
+	}
+	return BudgetEvent{}, false
+}
+
+// summaryPrompt asks the model to wrap up. It is sent with the final request
+// only and never stored in the conversation.
+func summaryPrompt(ev BudgetEvent) string {
+	limit := fmt.Sprintf("%d %s", ev.Limit, ev.Reason)
+	if ev.Reason == BudgetDuration {
+		limit = fmt.Sprintf("%ds", ev.Limit)
+	}
+	return fmt.Sprintf("[System notice] This turn has reached its budget (%s). Do not call any more tools. "+
+		"Reply to the user now, in their language: summarize what has been done, the results so far, and what is still left to do.", limit)
+}
+
+// skippedResult stands in for tool calls beyond the tool call budget.
+const skippedResult = "Not executed: tool call budget for this turn is exhausted"
@@ -0,0 +1,175 @@
+package toolloop
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// wrapUpRequest reports whether messages end with the budget notice.
+func wrapUpRequest(messages []llm.Message) bool {
+	last := messages[len(messages)-1]
+	return last.Role == "user" && strings.HasPrefix(last.Content, "[System notice] This turn has reached its budget")
+}
+
+// looping calls a tool until it gets the budget notice, then answers (with a
+// stray tool call, which must be dropped).
+func looping(ctx context.Context, messages []llm.Message) (llm.Message, *llm.Usage) {
+	if wrapUpRequest(messages) {
+		msg := toolCalls("getPet")
+		msg.Content = "Summary so far"
+		return msg, &llm.Usage{TotalTokens: 10}
+	}
+	return toolCalls("getPet", "getPet"), &llm.Usage{TotalTokens: 100}
+}
+
+func runBudget(t *testing.T, b Budget) (*scriptedLLM, []llm.Message, []Event) {
+	t.Helper()
+	client := &scriptedLLM{respond: looping}
+	l := New(client, &fakeExecutor{staggered: true})
+	l.SetBudget(Budget{MaxIterations: 3})
+	var events []Event
+	msgs, err := l.Run(WithBudget(context.Background(), b), []llm.Message{{Role: "user", Content: "hi"}}, nil, "", nil, func(ev Event) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return client, msgs, events
+}
+
+func budgetEvent(events []Event) (BudgetEvent, bool) {
+	for _, ev := range events {
+		if b, ok := ev.Data.(BudgetEvent); ok {
+			return b, true
+		}
+	}
+	return BudgetEvent{}, false
+}
+
+func TestBudgetIterationsWrapsUp(t *testing.T) {
+	client, msgs, events := runBudget(t, Budget{})
+
+	if ev, ok := budgetEvent(events); !ok || ev.Reason != BudgetIterations || ev.Limit != 3 {
+		t.Errorf("budget event = %+v, %v", ev, ok)
+	}
+	if len(client.requests) != 4 {
+		t.Errorf("LLM calls = %d, want 3 + wrap-up", len(client.requests))
+	}
+	last := msgs[len(msgs)-1]
+	if last.Content != "Summary so far" || len(last.ToolCalls) != 0 {
+		t.Errorf("final message = %+v", last)
+	}
+	for _, m := range msgs {
+		if strings.HasPrefix(m.Content, "[System notice]") {
+			t.Error("notices must not be stored in history")
+		}
+	}
+	if u, ok := events[len(events)-1].Data.(UsageEvent); !ok || u.TotalTokens != 310 {
+		t.Errorf("last event = %+v, want usage with 310 tokens", events[len(events)-1])
+	}
+}
+
+func TestBudgetToolCallsSkipsExtra(t *testing.T) {
+	_, msgs, events := runBudget(t, Budget{MaxToolCalls: 3})
+
+	if ev, _ := budgetEvent(events); ev.Reason != BudgetToolCalls {
+		t.Errorf("reason = %q, want tool_calls", ev.Reason)
+	}
+	var ran, skipped int
+	for _, m := range msgs {
+		switch {
+		case m.Role == "tool" && m.Content == skippedResult:
+			skipped++
+		case m.Role == "tool":
+			ran++
+		}
+	}
+	if ran != 3 || skipped != 1 {
+		t.Errorf("ran %d, skipped %d; want 3 and 1", ran, skipped)
+	}
+}
+
+func TestBudgetTokens(t *testing.T) {
+	client, _, events := runBudget(t, Budget{MaxTokens: 150})
+	if ev, _ := budgetEvent(events); ev.Reason != BudgetTokens || len(client.requests) != 3 {
+		t.Errorf("reason = %q after %d calls", ev.Reason, len(client.requests))
+	}
+}
+
+func TestBudgetDuration(t *testing.T) {
+	_, msgs, events := runBudget(t, Budget{MaxIterations: 100, MaxDuration: 120 * time.Millisecond})
+	if ev, _ := budgetEvent(events); ev.Reason != BudgetDuration {
+		t.Errorf("reason = %q, want duration", ev.Reason)
+	}
+	if msgs[len(msgs)-1].Content != "Summary so far" {
+		t.Errorf("final message = %+v", msgs[len(msgs)-1])
+	}
+}
+
+func TestBudgetWrapUpOverridesRequiredToolChoice(t *testing.T) {
+	// A model configured with tool_choice "required" answers with tool
+	// calls unless the request overrides the choice.
+	client := &scriptedLLM{respond: func(ctx context.Context, messages []llm.Message) (llm.Message, *llm.Usage) {
+		if choice, ok := llm.ToolChoiceFrom(ctx); ok && choice == llm.ToolChoiceNone {
+			return llm.Message{Role: "assistant", Content: "Summary so far"}, nil
+		}
+		return toolCalls("getPet"), nil
+	}}
+	l := New(client, &fakeExecutor{})
+	l.SetBudget(Budget{MaxIterations: 2})
+
+	var answer []string
+	msgs, err := l.Run(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}, nil, "", nil, func(ev Event) {
+		if c, ok := ev.Data.(ContentEvent); ok {
+			answer = append(answer, c.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if last := msgs[len(msgs)-1]; last.Content != "Summary so far" {
+		t.Errorf("final message = %+v", last)
+	}
+	if strings.Join(answer, "") != "Summary so far" {
+		t.Errorf("content events = %q", answer)
+	}
+}
+
+func TestBudgetDurationDiscardsCutOffText(t *testing.T) {
+	client := &scriptedLLM{wordDelay: 30 * time.Millisecond, respond: func(ctx context.Context, messages []llm.Message) (llm.Message, *llm.Usage) {
+		if wrapUpRequest(messages) {
+			return llm.Message{Role: "assistant", Content: "Summary so far"}, nil
+		}
+		return llm.Message{Role: "assistant", Content: "one two three four five six seven eight nine ten"}, nil
+	}}
+	l := New(client, &fakeExecutor{})
+
+	var before, after strings.Builder
+	var discarded *ContentDiscardedEvent
+	_, err := l.Run(WithBudget(context.Background(), Budget{MaxDuration: 100 * time.Millisecond}),
+		[]llm.Message{{Role: "user", Content: "hi"}}, nil, "", nil, func(ev Event) {
+			switch d := ev.Data.(type) {
+			case ContentDeltaEvent:
+				if discarded == nil {
+					before.WriteString(d.Delta)
+				} else {
+					after.WriteString(d.Delta)
+				}
+			case ContentDiscardedEvent:
+				discarded = &d
+			}
+		})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if discarded == nil || before.Len() == 0 || discarded.Text != before.String() {
+		t.Fatalf("discarded = %+v, streamed before it %q", discarded, before.String())
+	}
+	if after.String() != "Summary so far" {
+		t.Errorf("streamed after the discard = %q, want only the wrap-up", after.String())
+	}
+}
@@ -0,0 +1,167 @@
+package toolloop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// compactTarget is the share of the context budget history is summarized
+// down to, so the next calls don't have to compact again right away.
+const compactTarget = 0.5
+
+// maxTranscriptResult caps each tool result in the text sent for summarizing.
+const maxTranscriptResult = 2000
+
+const summarizerPrompt = "You condense conversation history for an assistant that works with tools. " +
+	"Summarize the conversation you are given so the assistant can continue without it. " +
+	"Keep every fact it may need later: the user's goals and decisions, names, IDs, numbers, URLs, " +
+	"results of tool calls that matter, and open questions. Be concise, use short bullet points, and do not invent anything."
+
+// Summary is a digest of early history. It stands in for the messages before
+// Until, except the system prompt.
+type Summary struct {
+	Text  string
+	Until int
+}
+
+// CompactedEvent is emitted when history that no longer fits the context was
+// folded into the summary.
+type CompactedEvent struct {
+	Summary    string `json:"summary"`
+	Until      int    `json:"until"`      // messages before this index are covered
+	Summarized int    `json:"summarized"` // messages added to the summary by this compaction
+}
+
+type summaryKey struct{}
+
+// WithSummary returns a context whose turns start from a summary made by an
+// earlier compaction.
+func WithSummary(ctx context.Context, s Summary) context.Context {
+	return context.WithValue(ctx, summaryKey{}, s)
+}
+
+// SetCompaction makes the loop summarize history that exceeds the context
+// budget instead of dropping it.
+func (l *Loop) SetCompaction(on bool) {
+	l.compaction = on
+}
+
+// historyStart returns the index of the first message not covered by s.
+func historyStart(messages []llm.Message, s Summary) int {
+	start := 0
+	if len(messages) > 0 && messages[0].Role == "system" {
+		start = 1
+	}
+	if s.Text != "" && s.Until > start && s.Until <= len(messages) {
+		return s.Until
+	}
+	return start
+}
+
+// withSummary replaces the messages covered by s with a summary message
+// placed right behind the system prompt.
+func withSummary(messages []llm.Message, s Summary) []llm.Message {
+	from := historyStart(messages, s)
+	if s.Text == "" || from != s.Until {
+		return messages
+	}
+	out := make([]llm.Message, 0, len(messages)-from+2)
+	if messages[0].Role == "system" {
+		out = append(out, messages[0])
+	}
+	out = append(out, llm.Message{Role: "system", Content: "Summary of the earlier conversation:\n" + s.Text})
+	return append(out, messages[from:]...)
+}
+
+// compact summarizes the oldest messages into sum when the history does not
+// fit the context. It leaves sum alone if summarizing fails, in which case
+// the request is truncated as usual.
+func (l *Loop) compact(ctx context.Context, messages []llm.Message, tools []llm.Tool, sum *Summary, total *UsageEvent, onEvent func(Event)) {
+	if l.maxCtxTokens <= 0 {
+		return
+	}
+	view := withSummary(messages, *sum)
+	kept, _ := l.fitContext(view, tools)
+	if len(kept) == len(view) {
+		return
+	}
+
+	from := historyStart(messages, *sum)
+	pinned := len(view) - (len(messages) - from)
+	target, _ := l.fitWithin(view, tools, int(float64(l.maxCtxTokens)*compactTarget))
+	tail := len(target) - pinned
+	if tail <= 0 {
+		tail = len(kept) - pinned
+	}
+	if tail <= 0 {
+		return // not even the latest message fits; nothing to keep verbatim
+	}
+	cut := len(messages) - tail
+	if cut <= from {
+		return
+	}
+
+	text, err := l.summarize(ctx, sum.Text, messages[from:cut], total)
+	if err != nil {
+		return
+	}
+	*sum = Summary{Text: text, Until: cut}
+	onEvent(Event{Type: "compacted", Data: CompactedEvent{
+		Summary:    text,
+		Until:      cut,
+		Summarized: cut - from,
+	}})
+}
+
+// summarize asks the model to fold msgs into the previous summary.
+func (l *Loop) summarize(ctx context.Context, previous string, msgs []llm.Message, total *UsageEvent) (string, error) {
+	var b strings.Builder
+	if previous != "" {
+		b.WriteString("Summary so far:\n")
+		b.WriteString(previous)
+		b.WriteString("\n\nConversation that follows it:\n")
+	}
+	for _, m := range msgs {
+		writeTranscript(&b, m)
+	}
+
+	request := []llm.Message{
+		{Role: "system", Content: summarizerPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	msg, usage, err := l.client.ChatStreamWithTools(ctx, request, nil, func(string) {}, func(string) {})
+	if usage != nil {
+		l.addUsage(total, usage)
+	}
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return text, nil
+}
+
+func writeTranscript(b *strings.Builder, m llm.Message) {
+	switch m.Role {
+	case "user":
+		fmt.Fprintf(b, "User: %s", m.Content)
+		if len(m.Parts) > 0 {
+			fmt.Fprintf(b, " [%d attachment(s)]", len(m.Parts))
+		}
+		b.WriteString("\n")
+	case "assistant":
+		if m.Content != "" {
+			fmt.Fprintf(b, "Assistant: %s\n", m.Content)
+		}
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(b, "Assistant called %s(%s)\n", tc.Function.Name, tc.Function.Arguments)
+		}
+	case "tool":
+		fmt.Fprintf(b, "Tool result: %s\n", cutText(m.Content, maxTranscriptResult))
+	}
+}
@@ -0,0 +1,129 @@
+package toolloop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// wordCounter counts one token per word.
+type wordCounter struct{}
+
+func (wordCounter) Count(text string) int { return len(strings.Fields(text)) }
+
+// summarizing answers summary requests with "summary N", collecting their
+// transcripts, and every other request with "ok".
+func summarizing(transcripts *[]string) *scriptedLLM {
+	return &scriptedLLM{respond: func(ctx context.Context, messages []llm.Message) (llm.Message, *llm.Usage) {
+		if messages[0].Content == summarizerPrompt {
+			*transcripts = append(*transcripts, messages[1].Content)
+			return llm.Message{Role: "assistant", Content: fmt.Sprintf("summary %d", len(*transcripts))}, &llm.Usage{TotalTokens: 5}
+		}
+		return llm.Message{Role: "assistant", Content: "ok"}, nil
+	}}
+}
+
+// history returns a system prompt and n user/assistant exchanges of 10 words each.
+func history(n int) []llm.Message {
+	msgs := []llm.Message{{Role: "system", Content: "sys"}}
+	for i := 0; i < n; i++ {
+		msgs = append(msgs,
+			llm.Message{Role: "user", Content: fmt.Sprintf("q%d", i) + strings.Repeat(" w", 9)},
+			llm.Message{Role: "assistant", Content: fmt.Sprintf("a%d", i) + strings.Repeat(" w", 9)},
+		)
+	}
+	return msgs
+}
+
+func newCompactingLoop(client llm.LLMClient) *Loop {
+	l := New(client, nil)
+	l.SetTokenCounter(wordCounter{})
+	l.SetMaxContextTokens(100)
+	l.SetCompaction(true)
+	return l
+}
+
+func TestCompactionSummarizesDroppedHistory(t *testing.T) {
+	var transcripts []string
+	client := summarizing(&transcripts)
+	l := newCompactingLoop(client)
+
+	var compacted []CompactedEvent
+	msgs, err := l.Run(context.Background(), history(10), nil, "", nil, func(ev Event) {
+		if c, ok := ev.Data.(CompactedEvent); ok {
+			compacted = append(compacted, c)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(compacted) != 1 {
+		t.Fatalf("compacted events = %d, want 1", len(compacted))
+	}
+	c := compacted[0]
+	if c.Summary != "summary 1" || c.Summarized != c.Until-1 {
+		t.Errorf("event = %+v", c)
+	}
+	if !strings.Contains(transcripts[0], "User: q0 ") || strings.Contains(transcripts[0], fmt.Sprintf("q%d ", (c.Until-1)/2)) {
+		t.Errorf("transcript should cover exactly the dropped messages:\n%s", transcripts[0])
+	}
+
+	req := client.requests[len(client.requests)-1] // after the summary request
+	if req[0].Content != "sys" || req[1].Role != "system" || !strings.HasSuffix(req[1].Content, "summary 1") {
+		t.Fatalf("summary not placed behind the system prompt: %+v", req[:2])
+	}
+	if got, want := len(req)-2, len(msgs)-1-c.Until; got != want {
+		t.Errorf("request keeps %d messages after the summary, want %d", got, want)
+	}
+	if len(msgs) != 22 {
+		t.Errorf("stored history has %d messages, want all 22", len(msgs))
+	}
+}
+
+func TestCompactionExtendsExistingSummary(t *testing.T) {
+	var transcripts []string
+	client := summarizing(&transcripts)
+	l := newCompactingLoop(client)
+
+	ctx := WithSummary(context.Background(), Summary{Text: "earlier facts", Until: 5})
+	var until int
+	_, err := l.Run(ctx, history(12), nil, "", nil, func(ev Event) {
+		if c, ok := ev.Data.(CompactedEvent); ok {
+			until = c.Until
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(transcripts) != 1 {
+		t.Fatalf("summaries = %d, want 1", len(transcripts))
+	}
+	tr := transcripts[0]
+	if !strings.HasPrefix(tr, "Summary so far:\nearlier facts") || strings.Contains(tr, "q1 ") || !strings.Contains(tr, "a2 ") {
+		t.Errorf("transcript should extend the summary from message 5:\n%s", tr)
+	}
+	if until <= 5 {
+		t.Errorf("until = %d, want beyond the previous summary", until)
+	}
+}
+
+func TestSummaryWithinBudgetIsReused(t *testing.T) {
+	var transcripts []string
+	client := summarizing(&transcripts)
+	l := newCompactingLoop(client)
+
+	ctx := WithSummary(context.Background(), Summary{Text: "earlier facts", Until: 17})
+	if _, err := l.Run(ctx, history(10), nil, "", nil, func(Event) {}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(transcripts) != 0 {
+		t.Errorf("unexpected compaction: %v", transcripts)
+	}
+	req := client.requests[len(client.requests)-1]
+	if len(req) != 2+4 || req[2].Content != history(10)[17].Content {
+		t.Errorf("request = %d messages starting %q", len(req), req[2].Content)
+	}
+}
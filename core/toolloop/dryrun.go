@@ -0,0 +1,71 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// Previewer is implemented by executors that can resolve a tool call into the
+// request it would send without sending it. A nil preview means the tool
+// can't be previewed.
+type Previewer interface {
+	Preview(ctx context.Context, toolName, argsJSON, authToken string) (interface{}, error)
+}
+
+// ToolPreviewEvent is emitted instead of running a tool during a dry run.
+type ToolPreviewEvent struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Arguments string      `json:"arguments"`
+	Request   interface{} `json:"request,omitempty"` // nil when the tool can't be previewed
+	Error     string      `json:"error,omitempty"`
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context whose turns preview tool calls instead of
+// running them. The model gets the preview as a placeholder result.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was made by WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	on, _ := ctx.Value(dryRunKey{}).(bool)
+	return on
+}
+
+// dryRunResult is what the model sees in place of a real result.
+type dryRunResult struct {
+	DryRun  bool        `json:"dry_run"`
+	Request interface{} `json:"request,omitempty"`
+	Note    string      `json:"note"`
+}
+
+// preview resolves a call without running it and returns the placeholder
+// result for the model.
+func (l *Loop) preview(ctx context.Context, tc llm.ToolCall, authToken string, onEvent func(Event)) string {
+	ev := ToolPreviewEvent{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments}
+	if p, ok := l.executor.(Previewer); ok {
+		req, err := p.Preview(ctx, tc.Function.Name, tc.Function.Arguments, authToken)
+		if err != nil {
+			ev.Error = err.Error()
+			onEvent(Event{Type: "tool_preview", Data: ev})
+			return "Error: " + err.Error()
+		}
+		ev.Request = req
+	}
+	onEvent(Event{Type: "tool_preview", Data: ev})
+
+	res := dryRunResult{DryRun: true, Request: ev.Request,
+		Note: "Dry run: this request was not sent. Assume it succeeded and continue planning, " +
+			"but don't present any data from it as real."}
+	if ev.Request == nil {
+		res.Note = "Dry run: the tool was not called and can't be previewed. Assume it succeeded and continue planning, " +
+			"but don't present any data from it as real."
+	}
+	data, _ := json.Marshal(res)
+	return string(data)
+}
@@ -0,0 +1,47 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+func TestDryRunPreviewsInsteadOfExecuting(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("deletePet", "mcp_tool"),
+		{Role: "assistant", Content: "done"},
+	}}
+	exec := &fakeExecutor{}
+	l := New(client, exec)
+	confirmed := false
+	l.SetConfirm(func(ConfirmRequest) ConfirmResponse {
+		confirmed = true
+		return ConfirmResponse{Approved: true}
+	})
+
+	var previews []ToolPreviewEvent
+	msgs, err := l.Run(WithDryRun(context.Background()), nil, nil, "", nil, func(ev Event) {
+		if p, ok := ev.Data.(ToolPreviewEvent); ok {
+			previews = append(previews, p)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if exec.calls != 0 || confirmed {
+		t.Errorf("dry run executed %d calls, confirmed = %v", exec.calls, confirmed)
+	}
+	if len(previews) != 2 || previews[0].Request == nil || previews[1].Request != nil {
+		t.Fatalf("previews = %+v", previews)
+	}
+
+	var res dryRunResult
+	if err := json.Unmarshal([]byte(msgs[1].Content), &res); err != nil || !res.DryRun || res.Request == nil {
+		t.Errorf("placeholder result = %q", msgs[1].Content)
+	}
+	if msgs[3].Content != "done" {
+		t.Errorf("model should continue after the placeholders: %+v", msgs[3])
+	}
+}
@@ -4,19 +4,47 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/policy"
+	"github.com/ZacharyZcR/NLUI/core/pricing"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
+	"github.com/ZacharyZcR/NLUI/core/toolindex"
 )
 
+// MaxIterations is the default limit on LLM calls per turn; see Budget.
 const MaxIterations = 25
 
+// DefaultToolConcurrency is how many tool calls from one assistant message
+// run at the same time.
+const DefaultToolConcurrency = 4
+
 type Executor interface {
 	Execute(ctx context.Context, toolName, argsJSON, authToken string) (string, error)
 }
 
-// ConfirmFunc is called before executing a tool that looks dangerous.
-// Return true to proceed, false to skip.
-type ConfirmFunc func(toolName, argsJSON string) bool
+// ConfirmFunc is called before executing a tool call the policy wants
+// confirmed.
+type ConfirmFunc func(req ConfirmRequest) ConfirmResponse
+
+// ConfirmRequest describes a tool call awaiting approval.
+type ConfirmRequest struct {
+	ID        string `json:"id"` // tool call ID, as in ToolCallEvent
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Rule      string `json:"rule,omitempty"` // policy rule that asked for confirmation
+}
+
+// ConfirmResponse is the approver's answer. Non-empty Arguments replace the
+// model's arguments: they are validated against the tool's schema, executed
+// and recorded in the assistant message's tool call.
+type ConfirmResponse struct {
+	Approved  bool   `json:"approved"`
+	Arguments string `json:"arguments,omitempty"`
+}
 
 type Event struct {
 	Type string      `json:"type"`
@@ -24,13 +52,36 @@ type Event struct {
 }
 
 type ToolCallEvent struct {
+	ID        string `json:"id"` // matches the ToolResultEvent of the same call
 	Name      string `json:"name"`
 	Arguments string `json:"arguments"`
+	Source    string `json:"source,omitempty"` // target or MCP server behind the tool
 }
 
+// Statuses reported in ToolResultEvent.
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"   // the tool failed or returned an HTTP error, or the arguments were invalid
+	StatusDenied  = "denied"  // denied by policy or rejected by the approver
+	StatusSkipped = "skipped" // not attempted: the tool-call budget ran out
+	StatusPreview = "preview" // dry run: previewed instead of called
+)
+
+// ToolResultEvent carries the full result; Compacted reports that the model
+// got a shaped version of it. Calls that never ran start and end at once.
 type ToolResultEvent struct {
-	Name   string `json:"name"`
-	Result string `json:"result"`
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Result     string    `json:"result"`
+	Status     string    `json:"status"`
+	Source     string    `json:"source,omitempty"`
+	HTTPStatus int       `json:"http_status,omitempty"` // HTTP tools that got a response
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Compacted  bool      `json:"compacted,omitempty"`
+	Arguments  string    `json:"arguments,omitempty"` // set when the approver edited the arguments
+	Cached     bool      `json:"cached,omitempty"`    // reused from an earlier identical call this turn
 }
 
 type ContentEvent struct {
@@ -41,49 +92,229 @@ type ContentDeltaEvent struct {
 	Delta string `json:"delta"`
 }
 
+// ContentDiscardedEvent withdraws the text streamed by a call that the
+// turn's time budget cut off. The wrap-up answer streams after it.
+type ContentDiscardedEvent struct {
+	Text string `json:"text"`
+}
+
+// ReasoningDeltaEvent carries the model's thinking. It is display-only and
+// never stored in the conversation.
+type ReasoningDeltaEvent struct {
+	Delta string `json:"delta"`
+}
+
+type RetryEvent struct {
+	Attempt     int    `json:"attempt"`
+	MaxAttempts int    `json:"max_attempts"`
+	DelayMs     int64  `json:"delay_ms"`
+	Error       string `json:"error"`
+}
+
 type UsageEvent struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	Backend          string `json:"backend,omitempty"` // last backend used when failover is configured
+	Model            string `json:"model,omitempty"`   // last model used
+
+	// Cost of the turn, summed per call; omitted when the model has no price.
+	Cost     float64 `json:"cost,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+
+	// Models splits the turn by the model that served each call, which
+	// differs from Model after failover.
+	Models []ModelUsage `json:"models,omitempty"`
+}
+
+// ModelUsage is the part of a turn's usage served by one model.
+type ModelUsage struct {
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
 }
 
 type Loop struct {
-	client       llm.LLMClient
-	executor     Executor
-	confirm      ConfirmFunc
-	maxCtxTokens int
+	client        llm.LLMClient
+	executor      Executor
+	middleware    []Middleware
+	chain         Executor // executor wrapped in middleware; nil without any
+	confirm       ConfirmFunc
+	policy        *policy.Policy
+	maxCtxTokens  int
+	compaction    bool
+	counter       tokenizer.Counter
+	prices        *pricing.Table
+	toolWorkers   int
+	resultLimit   int
+	resultLimits  map[string]int
+	loopThreshold int
+	retrieveK     int
+	vectors       *toolindex.Vectors // embedding ranking for retrieval; nil = BM25 only
+	limits        Budget
+	model         string // priced when a call's Usage.Model is empty
+
+	// calibration is actual/estimated prompt tokens from the last call that
+	// reported usage; it corrects the counter for the model's real tokenizer.
+	calibMu     sync.Mutex
+	calibration float64
 }
 
 func New(client llm.LLMClient, executor Executor) *Loop {
-	return &Loop{client: client, executor: executor}
+	return &Loop{client: client, executor: executor, counter: tokenizer.Estimator{}}
 }
 
 func (l *Loop) SetConfirm(fn ConfirmFunc) {
 	l.confirm = fn
 }
 
+// SetPolicy sets the rules deciding which tool calls run, need confirmation
+// or are refused; nil uses the built-in rules.
+func (l *Loop) SetPolicy(p *policy.Policy) {
+	l.policy = p
+}
+
+// decide applies the policy to a tool, described by the executor when it can.
+func (l *Loop) decide(name string) policy.Decision {
+	info := policy.ToolInfo{Name: name}
+	if d, ok := l.executor.(policy.Describer); ok {
+		info = d.DescribeTool(name)
+	}
+	return l.policy.Decide(info)
+}
+
 func (l *Loop) SetMaxContextTokens(n int) {
 	l.maxCtxTokens = n
 }
 
-var dangerousPatterns = []string{
-	"delete", "remove", "destroy", "drop", "purge", "reset",
+// SetTokenCounter sets the counter used for context budgeting; nil restores
+// the default estimator.
+func (l *Loop) SetTokenCounter(c tokenizer.Counter) {
+	if c == nil {
+		c = tokenizer.Estimator{}
+	}
+	l.counter = c
 }
 
-func isDangerous(toolName, argsJSON string) bool {
-	lower := strings.ToLower(toolName)
-	for _, p := range dangerousPatterns {
-		if strings.Contains(lower, p) {
-			return true
+// SetBudget sets the default per-turn limits; WithBudget overrides them per call.
+func (l *Loop) SetBudget(b Budget) {
+	l.limits = b
+}
+
+// SetToolConcurrency limits how many tool calls of one assistant message run
+// at once; 1 runs them one after another, n <= 0 restores the default.
+func (l *Loop) SetToolConcurrency(n int) {
+	l.toolWorkers = n
+}
+
+func (l *Loop) toolConcurrency() int {
+	if l.toolWorkers <= 0 {
+		return DefaultToolConcurrency
+	}
+	return l.toolWorkers
+}
+
+// SetPricing prices usage events with prices. model names the configured
+// model, used for calls that do not report their own (no failover).
+func (l *Loop) SetPricing(prices *pricing.Table, model string) {
+	l.prices = prices
+	l.model = model
+}
+
+// addUsage accumulates one call's usage and cost into the turn total.
+func (l *Loop) addUsage(total *UsageEvent, usage *llm.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	if usage.Backend != "" {
+		total.Backend = usage.Backend
+	}
+	model := usage.Model
+	if model == "" {
+		model = l.model
+	}
+	if model != "" {
+		total.Model = model
+	}
+	var cost float64
+	if model != "" {
+		if c, ok := l.prices.Cost(model, usage.PromptTokens, usage.CompletionTokens); ok {
+			cost = c
+			total.Cost += c
+			total.Currency = l.prices.Currency
 		}
 	}
-	lowerArgs := strings.ToLower(argsJSON)
-	for _, m := range []string{`"delete"`, `"put"`, `"patch"`} {
-		if strings.Contains(lowerArgs, m) {
-			return true
+
+	m := total.modelUsage(model)
+	m.PromptTokens += usage.PromptTokens
+	m.CompletionTokens += usage.CompletionTokens
+	m.TotalTokens += usage.TotalTokens
+	m.Cost += cost
+}
+
+// modelUsage returns the breakdown entry of model, adding it if needed.
+func (u *UsageEvent) modelUsage(model string) *ModelUsage {
+	for i := range u.Models {
+		if u.Models[i].Model == model {
+			return &u.Models[i]
+		}
+	}
+	u.Models = append(u.Models, ModelUsage{Model: model})
+	return &u.Models[len(u.Models)-1]
+}
+
+// fitContext truncates messages to the context budget left after the tool
+// definitions, returning the kept messages and their raw (uncalibrated)
+// token estimate including tools.
+func (l *Loop) fitContext(messages []llm.Message, tools []llm.Tool) ([]llm.Message, int) {
+	return l.fitWithin(messages, tools, l.maxCtxTokens)
+}
+
+// fitWithin is fitContext for a given token limit; maxTokens <= 0 keeps everything.
+func (l *Loop) fitWithin(messages []llm.Message, tools []llm.Tool, maxTokens int) ([]llm.Message, int) {
+	factor := l.calibrationFactor()
+	toolTokens := countTools(l.counter, tools)
+
+	kept := messages
+	if maxTokens > 0 {
+		budget := maxTokens - scaled(toolTokens, factor)
+		if budget < 1 {
+			budget = 1 // still keeps the system prompt
 		}
+		kept = truncateMessages(messages, budget, func(m *llm.Message) int {
+			return scaled(countMessage(l.counter, m), factor)
+		})
+	}
+
+	raw := toolTokens
+	for i := range kept {
+		raw += countMessage(l.counter, &kept[i])
 	}
-	return false
+	return kept, raw
+}
+
+func (l *Loop) calibrationFactor() float64 {
+	l.calibMu.Lock()
+	defer l.calibMu.Unlock()
+	return l.calibration
+}
+
+// calibrate records how the provider's prompt_tokens compare to our estimate.
+func (l *Loop) calibrate(estimated, actual int) {
+	if estimated <= 0 || actual <= 0 {
+		return
+	}
+	f := float64(actual) / float64(estimated)
+	if f < minCalibration {
+		f = minCalibration
+	} else if f > maxCalibration {
+		f = maxCalibration
+	}
+	l.calibMu.Lock()
+	l.calibration = f
+	l.calibMu.Unlock()
 }
 
 func (l *Loop) Run(ctx context.Context, messages []llm.Message, tools []llm.Tool, authToken string, confirm ConfirmFunc, onEvent func(Event)) ([]llm.Message, error) {
@@ -92,19 +323,73 @@ func (l *Loop) Run(ctx context.Context, messages []llm.Message, tools []llm.Tool
 		confirm = l.confirm
 	}
 
+	// Surface LLM retries (rate limits, transient errors) so UIs can show "retrying…".
+	ctx = llm.WithRetryHook(ctx, func(info llm.RetryInfo) {
+		onEvent(Event{Type: "retry", Data: RetryEvent{
+			Attempt:     info.Attempt,
+			MaxAttempts: info.MaxAttempts,
+			DelayMs:     info.Delay.Milliseconds(),
+			Error:       info.Err.Error(),
+		}})
+	})
+
+	ctx = toolctx.WithTurn(ctx)
+
+	budget := l.budget(ctx)
+	state := turnState{start: time.Now()}
+	// Calls and tools run under the duration budget; the wrap-up request uses
+	// ctx, which outlives it.
+	turnCtx := ctx
+	if budget.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		turnCtx, cancel = context.WithTimeout(ctx, budget.MaxDuration)
+		defer cancel()
+	}
+
 	var totalUsage UsageEvent
+	schemas := toolSchemas(tools)
+	sel := l.selectTools(ctx, messages, tools, onEvent)
+	if sel != nil {
+		schemas[SearchToolsName] = asSchema(searchTool(len(tools)).Function.Parameters)
+	}
+	loops := l.newLoopDetector()
+	summary, _ := ctx.Value(summaryKey{}).(Summary)
 
-	for i := 0; i < MaxIterations; i++ {
-		truncated := truncateMessages(messages, l.maxCtxTokens)
-		msg, usage, err := l.client.ChatStreamWithTools(ctx, truncated, tools, func(delta string) {
-			onEvent(Event{Type: "content_delta", Data: ContentDeltaEvent{Delta: delta}})
-		})
-		if usage != nil {
-			totalUsage.PromptTokens += usage.PromptTokens
-			totalUsage.CompletionTokens += usage.CompletionTokens
-			totalUsage.TotalTokens += usage.TotalTokens
+	// notice goes to the model with the next request only, like the
+	// wrap-up prompt, so it never becomes part of the history.
+	var notice string
+	// partial collects the text streamed by the current call, so it can be
+	// withdrawn if the call is cut off.
+	var partial strings.Builder
+	callEvents := func(ev Event) {
+		if d, ok := ev.Data.(ContentDeltaEvent); ok {
+			partial.WriteString(d.Delta)
 		}
+		onEvent(ev)
+	}
+
+	for {
+		if ev, over := budget.exceeded(state, time.Now()); over {
+			return l.wrapUp(ctx, messages, sel.tools(tools), ev, &summary, &totalUsage, onEvent)
+		}
+		state.iterations++
+
+		request := messages
+		if notice != "" {
+			request = append(messages[:len(messages):len(messages)], llm.Message{Role: "user", Content: notice})
+			notice = ""
+		}
+		partial.Reset()
+		msg, err := l.call(turnCtx, request, sel.tools(tools), &summary, &totalUsage, callEvents)
+		state.tokens = totalUsage.TotalTokens
 		if err != nil {
+			if turnCtx.Err() != nil && ctx.Err() == nil {
+				// Out of time mid-call; the budget check wraps up.
+				if partial.Len() > 0 {
+					onEvent(Event{Type: "content_discarded", Data: ContentDiscardedEvent{Text: partial.String()}})
+				}
+				continue
+			}
 			emitUsage(onEvent, totalUsage)
 			return messages, fmt.Errorf("LLM call failed: %w", err)
 		}
@@ -117,56 +402,265 @@ func (l *Loop) Run(ctx context.Context, messages []llm.Message, tools []llm.Tool
 			return messages, nil
 		}
 
-		for _, tc := range msg.ToolCalls {
-			onEvent(Event{Type: "tool_call", Data: ToolCallEvent{
+		allowed := len(msg.ToolCalls)
+		if budget.MaxToolCalls > 0 {
+			allowed = budget.MaxToolCalls - state.toolCalls
+		}
+		results, executed := l.runToolCalls(turnCtx, msg.ToolCalls, allowed, schemas, sel, authToken, confirm, onEvent)
+		state.toolCalls += executed
+		messages = append(messages, results...)
+		if ev, ok := loops.observe(msg.ToolCalls); ok {
+			ev.Notice = loopNotice(ev)
+			onEvent(Event{Type: "loop_detected", Data: ev})
+			notice = ev.Notice
+		}
+	}
+}
+
+// call makes one LLM request, with history before sum.Until replaced by the
+// summary. With compaction on, history that no longer fits is summarized
+// into sum first.
+func (l *Loop) call(ctx context.Context, messages []llm.Message, tools []llm.Tool, sum *Summary, total *UsageEvent, onEvent func(Event)) (*llm.Message, error) {
+	if l.compaction {
+		l.compact(ctx, messages, tools, sum, total, onEvent)
+	}
+	truncated, estimated := l.fitContext(withSummary(messages, *sum), tools)
+	msg, usage, err := l.client.ChatStreamWithTools(ctx, truncated, tools, func(delta string) {
+		onEvent(Event{Type: "content_delta", Data: ContentDeltaEvent{Delta: delta}})
+	}, func(delta string) {
+		onEvent(Event{Type: "reasoning_delta", Data: ReasoningDeltaEvent{Delta: delta}})
+	})
+	if usage != nil {
+		l.calibrate(estimated, usage.PromptTokens)
+		l.addUsage(total, usage)
+	}
+	return msg, err
+}
+
+// wrapUp asks the model for a final answer once a budget is used up. Tools
+// stay declared so the history remains valid for every provider, but the
+// tool choice is "none" so a configured "required" or named tool can't force
+// calls; any calls in the answer are dropped: there is no budget left to
+// run them.
+func (l *Loop) wrapUp(ctx context.Context, messages []llm.Message, tools []llm.Tool, ev BudgetEvent, sum *Summary, total *UsageEvent, onEvent func(Event)) ([]llm.Message, error) {
+	onEvent(Event{Type: "budget_exceeded", Data: ev})
+
+	request := append(messages[:len(messages):len(messages)], llm.Message{Role: "user", Content: summaryPrompt(ev)})
+	msg, err := l.call(llm.WithToolChoice(ctx, llm.ToolChoiceNone), request, tools, sum, total, onEvent)
+	if err != nil {
+		emitUsage(onEvent, *total)
+		return messages, fmt.Errorf("LLM call failed: %w", err)
+	}
+	msg.ToolCalls = nil
+	messages = append(messages, *msg)
+	onEvent(Event{Type: "content", Data: ContentEvent{Text: msg.Content}})
+	emitUsage(onEvent, *total)
+	return messages, nil
+}
+
+// runToolCalls executes the tool calls of one assistant message and returns
+// the tool messages in call order, so history reads the same as sequential
+// execution. Arguments are checked against the tool's schema and the policy
+// is applied first: invalid and denied calls are answered without running,
+// and calls needing approval are confirmed one at a time, in order; calls
+// start in order as workers free up and run concurrently up to the limit.
+// Only the first allowed calls are attempted; the rest are answered with
+// skippedResult. executed counts the calls that ran or had invalid
+// arguments, so a model retrying bad calls still uses up the budget.
+func (l *Loop) runToolCalls(ctx context.Context, calls []llm.ToolCall, allowed int, schemas map[string]map[string]interface{}, sel *toolSelection, authToken string, confirm ConfirmFunc, onEvent func(Event)) (msgs []llm.Message, executed int) {
+	outs := make([]callOutcome, len(calls))
+	done := make([]chan struct{}, len(calls))
+	workers := make(chan struct{}, l.toolConcurrency())
+	dryRun := IsDryRun(ctx)
+
+	for i, tc := range calls {
+		out := &outs[i]
+		out.source = l.source(tc.Function.Name)
+		onEvent(Event{Type: "tool_call", Data: ToolCallEvent{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+			Source:    out.source,
+		}})
+		done[i] = make(chan struct{})
+
+		if executed >= allowed {
+			out.settle(skippedResult, StatusSkipped)
+			close(done[i])
+			continue
+		}
+
+		if schema, ok := schemas[tc.Function.Name]; ok {
+			if problems := validateArgs(schema, tc.Function.Arguments); len(problems) > 0 {
+				out.settle(ValidationError{
+					Error:    "invalid_arguments",
+					Tool:     tc.Function.Name,
+					Problems: problems,
+					Hint:     "The tool was not called. Fix the arguments and call it again.",
+				}.String(), StatusError)
+				executed++
+				close(done[i])
+				continue
+			}
+		}
+
+		if sel != nil && tc.Function.Name == SearchToolsName {
+			out.start()
+			out.settle(l.search(ctx, sel, tc.Function.Arguments), StatusSuccess)
+			executed++
+			close(done[i])
+			continue
+		}
+
+		switch d := l.decide(tc.Function.Name); d.Action {
+		case policy.Deny:
+			out.settle(deniedResult(d), StatusDenied)
+			close(done[i])
+			continue
+		case policy.Confirm:
+			if dryRun || confirm == nil {
+				break
+			}
+			resp := confirm(ConfirmRequest{
+				ID:        tc.ID,
 				Name:      tc.Function.Name,
 				Arguments: tc.Function.Arguments,
-			}})
-
-			// Confirmation gate for dangerous operations
-			if confirm != nil && isDangerous(tc.Function.Name, tc.Function.Arguments) {
-				if !confirm(tc.Function.Name, tc.Function.Arguments) {
-					result := "Operation canceled by user"
-					onEvent(Event{Type: "tool_result", Data: ToolResultEvent{
-						Name:   tc.Function.Name,
-						Result: result,
-					}})
-					messages = append(messages, llm.Message{
-						Role:       "tool",
-						Content:    result,
-						ToolCallID: tc.ID,
-					})
+				Rule:      d.Rule,
+			})
+			if !resp.Approved {
+				out.settle("Operation canceled by user", StatusDenied)
+				close(done[i])
+				continue
+			}
+			if resp.Arguments != "" && resp.Arguments != tc.Function.Arguments {
+				if problems := validateArgs(schemas[tc.Function.Name], resp.Arguments); len(problems) > 0 {
+					out.settle(ValidationError{
+						Error:    "invalid_arguments",
+						Tool:     tc.Function.Name,
+						Problems: problems,
+						Hint:     "The user edited the arguments, but they don't match the schema. The tool was not called.",
+					}.String(), StatusError)
+					executed++
+					close(done[i])
 					continue
 				}
+				// calls shares its array with the assistant message, so
+				// history records what actually ran.
+				calls[i].Function.Arguments = resp.Arguments
+				tc = calls[i]
+				out.edited = true
 			}
+		}
 
-			result, err := l.executor.Execute(ctx, tc.Function.Name, tc.Function.Arguments, authToken)
-			if err != nil {
-				result = fmt.Sprintf("Error: %s", err.Error())
-			}
-			if len(result) > 4000 {
-				result = result[:4000] + "\n...(truncated)"
+		executed++
+		if dryRun {
+			out.start()
+			out.settle(l.preview(ctx, tc, authToken, onEvent), StatusPreview)
+			close(done[i])
+			continue
+		}
+		workers <- struct{}{}
+		go func(i int, tc llm.ToolCall) {
+			defer func() { <-workers }()
+			defer close(done[i])
+			out := &outs[i]
+			out.start()
+			result, err := l.execute(toolctx.WithReport(ctx, &out.report), tc, authToken)
+			status := StatusSuccess
+			if err != nil || out.report.Failed || out.report.HTTPStatus >= 400 {
+				status = StatusError
 			}
+			out.settle(result, status)
+		}(i, tc)
+	}
 
-			onEvent(Event{Type: "tool_result", Data: ToolResultEvent{
-				Name:   tc.Function.Name,
-				Result: result,
-			}})
-
-			messages = append(messages, llm.Message{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: tc.ID,
-			})
+	msgs = make([]llm.Message, len(calls))
+	for i, tc := range calls {
+		<-done[i]
+		out := &outs[i]
+		content, compacted := shapeResult(out.result, l.resultLimitFor(tc.Function.Name))
+		ev := ToolResultEvent{
+			ID:         tc.ID,
+			Name:       tc.Function.Name,
+			Result:     out.result,
+			Status:     out.status,
+			Source:     out.source,
+			HTTPStatus: out.report.HTTPStatus,
+			StartedAt:  out.started,
+			EndedAt:    out.ended,
+			DurationMs: out.ended.Sub(out.started).Milliseconds(),
+			Compacted:  compacted,
+			Cached:     out.report.Cached,
+		}
+		if out.edited {
+			ev.Arguments = tc.Function.Arguments
+		}
+		onEvent(Event{Type: "tool_result", Data: ev})
+		msgs[i] = llm.Message{
+			Role:       "tool",
+			Content:    content,
+			ToolCallID: tc.ID,
+			IsError:    out.status == StatusError || out.status == StatusDenied,
+		}
+		if compacted {
+			msgs[i].FullContent = out.result
 		}
 	}
+	return msgs, executed
+}
+
+// callOutcome is what happened to one tool call of a message.
+type callOutcome struct {
+	result  string
+	status  string
+	source  string
+	report  toolctx.CallReport
+	edited  bool // the approver changed the arguments
+	started time.Time
+	ended   time.Time
+}
+
+func (o *callOutcome) start() { o.started = time.Now() }
 
-	emitUsage(onEvent, totalUsage)
-	return messages, fmt.Errorf("max iterations (%d) reached", MaxIterations)
+// settle records the result; calls that never started take no time.
+func (o *callOutcome) settle(result, status string) {
+	o.result, o.status = result, status
+	o.ended = time.Now()
+	if o.started.IsZero() {
+		o.started = o.ended
+	}
+}
+
+// source names the target or MCP server behind a tool, if the executor can
+// tell.
+func (l *Loop) source(name string) string {
+	if d, ok := l.executor.(policy.Describer); ok {
+		return d.DescribeTool(name).Target
+	}
+	return ""
+}
+
+func deniedResult(d policy.Decision) string {
+	if d.Rule == "" {
+		return "Operation denied by policy"
+	}
+	return fmt.Sprintf("Operation denied by policy (%s)", d.Rule)
+}
+
+func (l *Loop) execute(ctx context.Context, tc llm.ToolCall, authToken string) (string, error) {
+	exec := l.executor
+	if l.chain != nil {
+		exec = l.chain
+	}
+	result, err := exec.Execute(ctx, tc.Function.Name, tc.Function.Arguments, authToken)
+	if err != nil {
+		return fmt.Sprintf("Error: %s", err.Error()), err
+	}
+	return result, nil
 }
 
 func emitUsage(onEvent func(Event), u UsageEvent) {
-	if u.TotalTokens > 0 {
+	if u.TotalTokens > 0 || u.Backend != "" {
 		onEvent(Event{Type: "usage", Data: u})
 	}
 }
@@ -1,47 +1,272 @@
 package toolloop
 
-import "testing"
-
-func TestIsDangerousName(t *testing.T) {
-	cases := []struct {
-		name string
-		args string
-		want bool
-	}{
-		{"deletePet", "{}", true},
-		{"removeUser", "{}", true},
-		{"destroySession", "{}", true},
-		{"dropTable", "{}", true},
-		{"purgeCache", "{}", true},
-		{"resetPassword", "{}", true},
-		{"listPets", "{}", false},
-		{"getPet", "{}", false},
-		{"createPet", "{}", false},
-	}
-	for _, c := range cases {
-		got := isDangerous(c.name, c.args)
-		if got != c.want {
-			t.Errorf("isDangerous(%q, %q) = %v, want %v", c.name, c.args, got, c.want)
-		}
-	}
-}
-
-func TestIsDangerousArgs(t *testing.T) {
-	cases := []struct {
-		name string
-		args string
-		want bool
-	}{
-		{"callAPI", `{"method": "delete"}`, true},
-		{"callAPI", `{"method": "put"}`, true},
-		{"callAPI", `{"method": "patch"}`, true},
-		{"callAPI", `{"method": "get"}`, false},
-		{"callAPI", `{"method": "post"}`, false},
-	}
-	for _, c := range cases {
-		got := isDangerous(c.name, c.args)
-		if got != c.want {
-			t.Errorf("isDangerous(%q, %q) = %v, want %v", c.name, c.args, got, c.want)
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/policy"
+	"github.com/ZacharyZcR/NLUI/core/pricing"
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
+)
+
+func TestAddUsagePricesEachCall(t *testing.T) {
+	l := New(nil, nil)
+	l.SetPricing(pricing.NewTable("", nil), "gpt-4o-mini")
+
+	var total UsageEvent
+	l.addUsage(&total, &llm.Usage{PromptTokens: 1_000_000, TotalTokens: 1_000_000})
+	// A failover call reports the model that actually served it.
+	l.addUsage(&total, &llm.Usage{CompletionTokens: 100_000, TotalTokens: 100_000, Backend: "main", Model: "gpt-4o"})
+
+	if want := 0.15 + 1.0; math.Abs(total.Cost-want) > 1e-9 {
+		t.Errorf("cost = %v, want %v", total.Cost, want)
+	}
+	if total.Currency != "USD" || total.Model != "gpt-4o" || total.Backend != "main" || total.TotalTokens != 1_100_000 {
+		t.Errorf("usage = %+v", total)
+	}
+	if len(total.Models) != 2 || total.Models[0].Model != "gpt-4o-mini" || total.Models[0].TotalTokens != 1_000_000 ||
+		math.Abs(total.Models[0].Cost-0.15) > 1e-9 || total.Models[1].Model != "gpt-4o" || total.Models[1].TotalTokens != 100_000 {
+		t.Errorf("per-model usage = %+v", total.Models)
+	}
+
+	var unpriced UsageEvent
+	l.SetPricing(pricing.NewTable("", nil), "llama3")
+	l.addUsage(&unpriced, &llm.Usage{PromptTokens: 10, TotalTokens: 10})
+	if unpriced.Cost != 0 || unpriced.Currency != "" || unpriced.Model != "llama3" {
+		t.Errorf("unpriced = %+v", unpriced)
+	}
+}
+
+// scriptedLLM is the model of the loop tests. It returns its replies in
+// order, one per call, or asks respond when set, and records each request
+// and the names of the tools sent with it. With wordDelay set it streams
+// the reply word by word and can be cut off between words.
+type scriptedLLM struct {
+	replies   []llm.Message
+	respond   func(ctx context.Context, messages []llm.Message) (llm.Message, *llm.Usage)
+	wordDelay time.Duration
+	calls     int
+	requests  [][]llm.Message
+	tools     [][]string
+}
+
+func (s *scriptedLLM) ChatStreamWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool, onDelta, onReasoning func(string)) (*llm.Message, *llm.Usage, error) {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Function.Name
+	}
+	s.requests = append(s.requests, messages)
+	s.tools = append(s.tools, names)
+	s.calls++
+	var msg llm.Message
+	var usage *llm.Usage
+	if s.respond != nil {
+		msg, usage = s.respond(ctx, messages)
+	} else {
+		msg = s.replies[s.calls-1]
+	}
+	if s.wordDelay > 0 {
+		for _, word := range strings.SplitAfter(msg.Content, " ") {
+			select {
+			case <-ctx.Done():
+				return nil, usage, ctx.Err()
+			case <-time.After(s.wordDelay):
+			}
+			onDelta(word)
+		}
+	}
+	return &msg, usage, nil
+}
+
+// fakeExecutor is the executor of the loop tests. It answers "<tool> ok",
+// or with reply when set, counts calls and records how many run at once.
+// It describes the tools in methods as HTTP operations on the "shop"
+// target and previews every tool except "mcp_tool".
+type fakeExecutor struct {
+	reply     func(toolName, argsJSON string) string
+	staggered bool              // call {"n":N} sleeps 50-10N ms and answers "<tool> N", so later calls finish first
+	methods   map[string]string // tool → HTTP method; nil describes nothing
+	memo      bool              // report calls already made in the same turn as cached
+
+	mu       sync.Mutex
+	calls    int
+	inFlight int
+	peak     int
+	seen     map[string]bool // turn/tool pairs, for memo
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+	e.mu.Lock()
+	e.calls++
+	e.inFlight++
+	if e.inFlight > e.peak {
+		e.peak = e.inFlight
+	}
+	if e.memo {
+		key := fmt.Sprintf("%d/%s", toolctx.TurnID(ctx), toolName)
+		if e.seen == nil {
+			e.seen = map[string]bool{}
+		}
+		if e.seen[key] {
+			toolctx.ReportFrom(ctx).Cached = true
+		}
+		e.seen[key] = true
+	}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.inFlight--
+		e.mu.Unlock()
+	}()
+
+	switch {
+	case e.reply != nil:
+		return e.reply(toolName, argsJSON), nil
+	case e.staggered:
+		var n int
+		fmt.Sscanf(argsJSON, `{"n":%d}`, &n)
+		time.Sleep(time.Duration(50-n*10) * time.Millisecond)
+		return fmt.Sprintf("%s %d", toolName, n), nil
+	}
+	return toolName + " ok", nil
+}
+
+func (e *fakeExecutor) DescribeTool(name string) policy.ToolInfo {
+	if e.methods == nil {
+		return policy.ToolInfo{Name: name}
+	}
+	return policy.ToolInfo{Name: name, Target: "shop", Method: e.methods[name], Path: "/" + name}
+}
+
+func (e *fakeExecutor) Preview(ctx context.Context, toolName, argsJSON, authToken string) (interface{}, error) {
+	if toolName == "mcp_tool" {
+		return nil, nil
+	}
+	return map[string]string{"method": "DELETE", "url": "https://api.example.com/pets/7"}, nil
+}
+
+// echoArgs is a fakeExecutor reply that shows the arguments a tool ran with.
+func echoArgs(toolName, argsJSON string) string {
+	return toolName + " " + argsJSON
+}
+
+func toolCalls(names ...string) llm.Message {
+	msg := llm.Message{Role: "assistant"}
+	for i, name := range names {
+		msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+			ID:       fmt.Sprintf("call_%d", i),
+			Type:     "function",
+			Function: llm.FunctionCall{Name: name, Arguments: fmt.Sprintf(`{"n":%d}`, i)},
+		})
+	}
+	return msg
+}
+
+func TestParallelToolCallsKeepOrder(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("getUser", "getUser", "getUser", "getUser"),
+		{Role: "assistant", Content: "done"},
+	}}
+	exec := &fakeExecutor{staggered: true}
+	l := New(client, exec)
+	l.SetToolConcurrency(2)
+
+	var results []string
+	msgs, err := l.Run(context.Background(), nil, nil, "", nil, func(ev Event) {
+		if r, ok := ev.Data.(ToolResultEvent); ok {
+			results = append(results, r.Result)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if exec.peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", exec.peak)
+	}
+	for i := 0; i < 4; i++ {
+		m := msgs[1+i]
+		if m.Role != "tool" || m.ToolCallID != fmt.Sprintf("call_%d", i) || m.Content != fmt.Sprintf("getUser %d", i) {
+			t.Errorf("message %d = %+v", i, m)
+		}
+		if results[i] != m.Content {
+			t.Errorf("tool_result %d = %q, want %q", i, results[i], m.Content)
+		}
+	}
+}
+
+func TestParallelToolCallsConfirmInOrder(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("deletePet", "getPet", "deleteUser"),
+		{Role: "assistant", Content: "done"},
+	}}
+	l := New(client, &fakeExecutor{staggered: true})
+
+	var asked []string
+	var mu sync.Mutex
+	confirm := func(req ConfirmRequest) ConfirmResponse {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, req.Name)
+		return ConfirmResponse{Approved: req.Name == "deleteUser"}
+	}
+	msgs, err := l.Run(context.Background(), nil, nil, "", confirm, func(Event) {})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.Join(asked, ",") != "deletePet,deleteUser" {
+		t.Errorf("confirm order = %v", asked)
+	}
+	want := []string{"Operation canceled by user", "getPet 1", "deleteUser 2"}
+	for i, w := range want {
+		if msgs[1+i].Content != w {
+			t.Errorf("result %d = %q, want %q", i, msgs[1+i].Content, w)
+		}
+	}
+}
+
+func TestPolicyDecidesToolCalls(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("listOrders", "cancelOrder", "wipeOrders", "updateOrder"),
+		{Role: "assistant", Content: "done"},
+	}}
+	exec := &fakeExecutor{staggered: true, methods: map[string]string{
+		"listOrders":  "GET",
+		"cancelOrder": "POST",
+		"wipeOrders":  "POST",
+		"updateOrder": "PATCH",
+	}}
+	p, err := policy.New([]policy.Rule{
+		{Name: "no-wipe", Action: policy.Deny, Paths: []string{"/wipe*"}},
+		{Name: "cancel", Action: policy.Confirm, Tools: []string{"cancel*"}},
+	}, policy.Allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(client, exec)
+	l.SetPolicy(p)
+
+	var asked []string
+	confirm := func(req ConfirmRequest) ConfirmResponse {
+		asked = append(asked, req.Name+":"+req.Rule)
+		return ConfirmResponse{Approved: true}
+	}
+	msgs, err := l.Run(context.Background(), nil, nil, "", confirm, func(Event) {})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.Join(asked, ","); got != "cancelOrder:cancel,updateOrder:builtin:write-method" {
+		t.Errorf("confirmations = %s", got)
+	}
+	want := []string{"listOrders 0", "cancelOrder 1", "Operation denied by policy (no-wipe)", "updateOrder 3"}
+	for i, w := range want {
+		if msgs[1+i].Content != w {
+			t.Errorf("result %d = %q, want %q", i, msgs[1+i].Content, w)
 		}
 	}
 }
@@ -0,0 +1,109 @@
+package toolloop
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// DefaultLoopThreshold is how many identical calls in a row (or A/B
+// alternations) count as a loop.
+const DefaultLoopThreshold = 3
+
+// Patterns reported in LoopEvent.
+const (
+	LoopRepeat      = "repeat"
+	LoopOscillation = "oscillation"
+)
+
+// LoopEvent is emitted when the model keeps making the same tool calls. The
+// model gets Notice as a user message with the next request only; it is not
+// stored in the history.
+type LoopEvent struct {
+	Pattern string   `json:"pattern"`
+	Tools   []string `json:"tools"`
+	Repeats int      `json:"repeats"`
+	Notice  string   `json:"notice"`
+}
+
+// SetLoopThreshold sets how many repetitions count as a loop (at least 2);
+// 0 restores the default and a negative value disables detection.
+func (l *Loop) SetLoopThreshold(n int) {
+	l.loopThreshold = n
+}
+
+// loopDetector watches the tool calls of one turn.
+type loopDetector struct {
+	threshold int
+	sigs      []string // name + canonical arguments of each call
+	names     []string
+}
+
+func (l *Loop) newLoopDetector() *loopDetector {
+	n := l.loopThreshold
+	if n == 0 {
+		n = DefaultLoopThreshold
+	} else if n == 1 {
+		n = 2 // one call is not a loop
+	}
+	return &loopDetector{threshold: n}
+}
+
+// observe records a round of tool calls and reports a loop once it forms.
+// Detection starts over after each report.
+func (d *loopDetector) observe(calls []llm.ToolCall) (LoopEvent, bool) {
+	if d.threshold <= 0 {
+		return LoopEvent{}, false
+	}
+	for _, tc := range calls {
+		d.sigs = append(d.sigs, tc.Function.Name+" "+canonicalArgs(tc.Function.Arguments))
+		d.names = append(d.names, tc.Function.Name)
+	}
+
+	n, last := d.threshold, len(d.sigs)-1
+	if last+1 >= n && d.same(last, n, 1) {
+		ev := LoopEvent{Pattern: LoopRepeat, Tools: []string{d.names[last]}, Repeats: n}
+		d.sigs, d.names = nil, nil
+		return ev, true
+	}
+	if last+1 >= 2*n && d.sigs[last] != d.sigs[last-1] && d.same(last, n, 2) && d.same(last-1, n, 2) {
+		ev := LoopEvent{Pattern: LoopOscillation, Tools: []string{d.names[last-1], d.names[last]}, Repeats: n}
+		d.sigs, d.names = nil, nil
+		return ev, true
+	}
+	return LoopEvent{}, false
+}
+
+// same reports whether the n signatures at from, from-step, from-2*step, ...
+// are identical.
+func (d *loopDetector) same(from, n, step int) bool {
+	for k := 1; k < n; k++ {
+		if d.sigs[from-k*step] != d.sigs[from] {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalArgs re-encodes JSON arguments so key order and spacing don't
+// hide a repeat.
+func canonicalArgs(argsJSON string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(argsJSON), &v); err != nil {
+		return argsJSON
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func loopNotice(ev LoopEvent) string {
+	if ev.Pattern == LoopOscillation {
+		return fmt.Sprintf("[System notice] You keep alternating between %s and %s with the same arguments (%d times each). "+
+			"Stop repeating these calls: use the results you already have, change your approach, or answer the user.",
+			ev.Tools[0], ev.Tools[1], ev.Repeats)
+	}
+	return fmt.Sprintf("[System notice] You have called %s with the same arguments %d times in a row. "+
+		"Do not call it again with these arguments: use the results you already have, try different arguments or another tool, or answer the user.",
+		ev.Tools[0], ev.Repeats)
+}
@@ -0,0 +1,146 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+func call(name, args string) llm.ToolCall {
+	return llm.ToolCall{Type: "function", Function: llm.FunctionCall{Name: name, Arguments: args}}
+}
+
+func TestLoopDetector(t *testing.T) {
+	cases := []struct {
+		name    string
+		rounds  [][]llm.ToolCall
+		pattern string // "" = no loop
+	}{
+		{"repeat", [][]llm.ToolCall{
+			{call("getPet", `{"id": 1}`)}, {call("getPet", `{"id":1}`)}, {call("getPet", `{ "id" : 1 }`)},
+		}, LoopRepeat},
+		{"repeat in one round", [][]llm.ToolCall{
+			{call("getPet", `{}`), call("getPet", `{}`), call("getPet", `{}`)},
+		}, LoopRepeat},
+		{"different args", [][]llm.ToolCall{
+			{call("getPet", `{"id": 1}`)}, {call("getPet", `{"id": 2}`)}, {call("getPet", `{"id": 3}`)},
+		}, ""},
+		{"too few", [][]llm.ToolCall{
+			{call("getPet", `{}`)}, {call("getPet", `{}`)},
+		}, ""},
+		{"oscillation", [][]llm.ToolCall{
+			{call("a", `{}`)}, {call("b", `{}`)}, {call("a", `{}`)}, {call("b", `{}`)}, {call("a", `{}`)}, {call("b", `{}`)},
+		}, LoopOscillation},
+		{"broken oscillation", [][]llm.ToolCall{
+			{call("a", `{}`)}, {call("b", `{}`)}, {call("a", `{}`)}, {call("c", `{}`)}, {call("a", `{}`)}, {call("b", `{}`)},
+		}, ""},
+	}
+	for _, c := range cases {
+		d := New(nil, nil).newLoopDetector()
+		var got string
+		for _, round := range c.rounds {
+			if ev, ok := d.observe(round); ok {
+				got = ev.Pattern
+			}
+		}
+		if got != c.pattern {
+			t.Errorf("%s: pattern = %q, want %q", c.name, got, c.pattern)
+		}
+	}
+}
+
+func TestLoopThresholdDisables(t *testing.T) {
+	l := New(nil, nil)
+	l.SetLoopThreshold(-1)
+	d := l.newLoopDetector()
+	for i := 0; i < 10; i++ {
+		if _, ok := d.observe([]llm.ToolCall{call("getPet", `{}`)}); ok {
+			t.Fatal("detection should be off")
+		}
+	}
+}
+
+func TestLoopNoticeReachesModel(t *testing.T) {
+	same := llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{call("getPet", `{"id": 7}`)}}
+	client := &scriptedLLM{replies: []llm.Message{same, same, same, {Role: "assistant", Content: "done"}}}
+	l := New(client, &fakeExecutor{})
+
+	var events []LoopEvent
+	msgs, err := l.Run(context.Background(), nil, nil, "", nil, func(ev Event) {
+		if ev.Type == "loop_detected" {
+			events = append(events, ev.Data.(LoopEvent))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(events) != 1 || events[0].Pattern != LoopRepeat || events[0].Tools[0] != "getPet" || events[0].Repeats != 3 {
+		t.Fatalf("loop events = %+v", events)
+	}
+	for i, m := range msgs {
+		if strings.Contains(m.Content, "[System notice]") {
+			t.Errorf("message %d stores the notice: %q", i, m.Content)
+		}
+	}
+	if msgs[5].Role != "tool" || msgs[5].Content != "getPet ok" {
+		t.Errorf("tool result should be left as is: %+v", msgs[5])
+	}
+	sent := client.requests[3]
+	if last := sent[len(sent)-1]; last.Role != "user" || last.Content != events[0].Notice {
+		t.Errorf("request after the loop should end with the notice, got %+v", last)
+	}
+}
+
+// TestLoopNoticeKeepsAnthropicSystem runs the loop against the Anthropic
+// client: the notice must reach the model as a user turn, not be hoisted
+// into the system prompt.
+func TestLoopNoticeKeepsAnthropicSystem(t *testing.T) {
+	var requests []struct {
+		System   json.RawMessage   `json:"system"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, struct {
+			System   json.RawMessage   `json:"system"`
+			Messages []json.RawMessage `json:"messages"`
+		}{})
+		if err := json.NewDecoder(r.Body).Decode(&requests[len(requests)-1]); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		block := `{"type":"tool_use","id":"t` + fmt.Sprint(len(requests)) + `","name":"getPet","input":{"id":7}}`
+		if len(requests) > 3 {
+			block = `{"type":"text","text":"done"}`
+		}
+		fmt.Fprintf(w, `{"id":"m","role":"assistant","content":[%s],"stop_reason":"end_turn"}`, block)
+	}))
+	defer srv.Close()
+
+	client := llm.NewAutoClient("anthropic", srv.URL, "k", "m", "", false, llm.GenerationParams{}, llm.AzureOptions{})
+	var notice string
+	history := []llm.Message{{Role: "system", Content: "You are a pet store."}, {Role: "user", Content: "find pet 7"}}
+	if _, err := New(client, &fakeExecutor{}).Run(context.Background(), history, nil, "", nil, func(ev Event) {
+		if ev.Type == "loop_detected" {
+			notice = ev.Data.(LoopEvent).Notice
+		}
+	}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if notice == "" || len(requests) != 4 {
+		t.Fatalf("notice %q after %d requests", notice, len(requests))
+	}
+	for i, req := range requests {
+		if string(req.System) != string(requests[0].System) {
+			t.Errorf("request %d system = %s, want %s", i, req.System, requests[0].System)
+		}
+	}
+	last := string(requests[3].Messages[len(requests[3].Messages)-1])
+	if !strings.Contains(last, `"role":"user"`) || !strings.Contains(last, "[System notice]") {
+		t.Errorf("last message of the request after the loop = %s", last)
+	}
+}
@@ -0,0 +1,54 @@
+package toolloop
+
+import "context"
+
+// ExecutorFunc adapts a function to the Executor interface.
+type ExecutorFunc func(ctx context.Context, toolName, argsJSON, authToken string) (string, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+	return f(ctx, toolName, argsJSON, authToken)
+}
+
+// Middleware wraps tool execution, e.g. for audit logs, metrics, header
+// injection or redaction. It applies to every tool, HTTP and MCP alike.
+type Middleware func(next Executor) Executor
+
+// RewriteArgs returns a middleware that rewrites arguments before the tool
+// runs. An error stops the call and becomes its result.
+func RewriteArgs(fn func(ctx context.Context, toolName, argsJSON string) (string, error)) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+			args, err := fn(ctx, toolName, argsJSON)
+			if err != nil {
+				return "", err
+			}
+			return next.Execute(ctx, toolName, args, authToken)
+		})
+	}
+}
+
+// RewriteResult returns a middleware that rewrites results (and errors)
+// after the tool ran, before the model or any event sees them.
+func RewriteResult(fn func(ctx context.Context, toolName, argsJSON, result string, err error) (string, error)) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+			result, err := next.Execute(ctx, toolName, argsJSON, authToken)
+			return fn(ctx, toolName, argsJSON, result, err)
+		})
+	}
+}
+
+// Use adds middleware around the executor; earlier middleware runs outside
+// later ones. Policy lookups and dry-run previews still go to the executor
+// itself, so wrapping doesn't hide what it can describe or preview.
+func (l *Loop) Use(mw ...Middleware) {
+	for _, m := range mw {
+		if m != nil {
+			l.middleware = append(l.middleware, m)
+		}
+	}
+	l.chain = l.executor
+	for i := len(l.middleware) - 1; i >= 0; i-- {
+		l.chain = l.middleware[i](l.chain)
+	}
+}
@@ -0,0 +1,81 @@
+package toolloop
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// tag returns a middleware that records its name around each call.
+func tag(name string, trace *[]string) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+			*trace = append(*trace, name+">")
+			defer func() { *trace = append(*trace, "<"+name) }()
+			return next.Execute(ctx, toolName, argsJSON, authToken)
+		})
+	}
+}
+
+func TestMiddlewareWrapsToolCalls(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("getPet", "getSecret", "getBroken"),
+		{Role: "assistant", Content: "done"},
+	}}
+	l := New(client, &fakeExecutor{reply: echoArgs})
+	l.SetToolConcurrency(1)
+
+	var trace []string
+	l.Use(tag("outer", &trace), tag("inner", &trace))
+	l.Use(
+		RewriteArgs(func(ctx context.Context, toolName, argsJSON string) (string, error) {
+			if toolName == "getBroken" {
+				return "", errors.New("blocked by middleware")
+			}
+			return strings.Replace(argsJSON, `"n"`, `"index"`, 1), nil
+		}),
+		RewriteResult(func(ctx context.Context, toolName, argsJSON, result string, err error) (string, error) {
+			return strings.ReplaceAll(result, "Secret", "[redacted]"), err
+		}),
+	)
+
+	msgs, err := l.Run(context.Background(), nil, nil, "", nil, func(Event) {})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{`getPet {"index":0}`, `get[redacted] {"index":1}`, "Error: blocked by middleware"}
+	for i, w := range want {
+		if msgs[1+i].Content != w {
+			t.Errorf("result %d = %q, want %q", i, msgs[1+i].Content, w)
+		}
+	}
+	if got := strings.Join(trace[:4], " "); got != "outer> inner> <inner <outer" {
+		t.Errorf("middleware order = %s", got)
+	}
+}
+
+func TestMiddlewareKeepsPolicyDescriptions(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("cancelOrder"),
+		{Role: "assistant", Content: "done"},
+	}}
+	l := New(client, &fakeExecutor{methods: map[string]string{"cancelOrder": "DELETE"}})
+	l.Use(RewriteResult(func(ctx context.Context, toolName, argsJSON, result string, err error) (string, error) {
+		return result, err
+	}))
+
+	var rules []string
+	confirm := func(req ConfirmRequest) ConfirmResponse {
+		rules = append(rules, req.Rule)
+		return ConfirmResponse{}
+	}
+	if _, err := l.Run(context.Background(), nil, nil, "", confirm, func(Event) {}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(rules) != 1 || rules[0] != "builtin:write-method" {
+		t.Errorf("rules = %v, want the method-based rule through the middleware", rules)
+	}
+}
@@ -0,0 +1,101 @@
+package toolloop
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
+)
+
+func TestToolResultReportsCacheHits(t *testing.T) {
+	exec := &fakeExecutor{memo: true}
+	run := func() []bool {
+		client := &scriptedLLM{replies: []llm.Message{
+			toolCalls("getPet"),
+			toolCalls("getPet"),
+			{Role: "assistant", Content: "done"},
+		}}
+		l := New(client, exec)
+		var cached []bool
+		onEvent := func(ev Event) {
+			if ev.Type == "tool_result" {
+				cached = append(cached, ev.Data.(ToolResultEvent).Cached)
+			}
+		}
+		if _, err := l.Run(context.Background(), nil, nil, "", nil, onEvent); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return cached
+	}
+
+	for turn := 0; turn < 2; turn++ {
+		if got := run(); len(got) != 2 || got[0] || !got[1] {
+			t.Errorf("turn %d: cached = %v, want [false true]", turn, got)
+		}
+	}
+}
+
+func TestToolEventsCarryLifecycle(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{
+		toolCalls("listOrders", "getOrder", "cancelOrder"),
+		{Role: "assistant", Content: "done"},
+	}}
+	l := New(client, &fakeExecutor{staggered: true, methods: map[string]string{
+		"listOrders": "GET", "getOrder": "GET", "cancelOrder": "DELETE",
+	}})
+	l.Use(func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, toolName, argsJSON, authToken string) (string, error) {
+			result, err := next.Execute(ctx, toolName, argsJSON, authToken)
+			if toolName == "getOrder" {
+				toolctx.ReportFrom(ctx).HTTPStatus = 404
+			}
+			return result, err
+		})
+	})
+
+	var started []ToolCallEvent
+	var results []ToolResultEvent
+	onEvent := func(ev Event) {
+		switch d := ev.Data.(type) {
+		case ToolCallEvent:
+			started = append(started, d)
+		case ToolResultEvent:
+			results = append(results, d)
+		}
+	}
+	reject := func(ConfirmRequest) ConfirmResponse { return ConfirmResponse{} }
+	msgs, err := l.Run(context.Background(), nil, nil, "", reject, onEvent)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	wantStatus := []string{StatusSuccess, StatusError, StatusDenied}
+	for i, r := range results {
+		if r.ID != started[i].ID || r.ID != fmt.Sprintf("call_%d", i) {
+			t.Errorf("result %d id = %q, call id = %q", i, r.ID, started[i].ID)
+		}
+		if started[i].Source != "shop" || r.Source != "shop" {
+			t.Errorf("result %d source = %q/%q, want shop", i, started[i].Source, r.Source)
+		}
+		if r.Status != wantStatus[i] {
+			t.Errorf("result %d status = %s, want %s", i, r.Status, wantStatus[i])
+		}
+		if msgs[1+i].IsError != (r.Status != StatusSuccess) {
+			t.Errorf("result %d is_error = %v for status %s", i, msgs[1+i].IsError, r.Status)
+		}
+		if r.EndedAt.Before(r.StartedAt) || r.DurationMs != r.EndedAt.Sub(r.StartedAt).Milliseconds() {
+			t.Errorf("result %d timing = %v..%v (%dms)", i, r.StartedAt, r.EndedAt, r.DurationMs)
+		}
+	}
+	if results[0].DurationMs < 40 {
+		t.Errorf("listOrders took %dms, want the executor's 50ms", results[0].DurationMs)
+	}
+	if results[1].HTTPStatus != 404 {
+		t.Errorf("getOrder http_status = %d, want 404", results[1].HTTPStatus)
+	}
+	if results[2].DurationMs != 0 {
+		t.Errorf("rejected call took %dms, want 0", results[2].DurationMs)
+	}
+}
@@ -0,0 +1,202 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/toolindex"
+)
+
+// SearchToolsName is the meta-tool the model calls to find tools that were
+// left out of the request by retrieval.
+const SearchToolsName = "search_tools"
+
+// defaultSearchLimit is how many tools one search_tools call returns.
+const defaultSearchLimit = 8
+
+// recentToolTurns is how many user turns back tools the model called stay
+// selected, so follow-ups like "now delete it" keep their tools.
+const recentToolTurns = 3
+
+// ToolsSelectedEvent is emitted when retrieval narrows the tools sent to the
+// model at the start of a turn.
+type ToolsSelectedEvent struct {
+	Tools []string `json:"tools"` // selected tools, search_tools excluded
+	Total int      `json:"total"` // tools available before selection
+}
+
+// SetToolRetrieval sends only the topK tools most relevant to the turn, plus
+// search_tools, when more than topK tools are available. Tools are ranked by
+// BM25 over names, groups and descriptions; emb, if not nil, adds embedding
+// similarity. topK <= 0 sends every tool.
+func (l *Loop) SetToolRetrieval(topK int, emb llm.Embedder) {
+	l.retrieveK = topK
+	l.vectors = nil
+	if emb != nil {
+		l.vectors = toolindex.NewVectors(emb)
+	}
+}
+
+// toolSelection is the retrieval state of one turn.
+type toolSelection struct {
+	index  *toolindex.Index
+	active []llm.Tool // sent to the model; search_tools is last
+}
+
+// tools returns the tools to send: the selection, or all of them when
+// retrieval is off.
+func (s *toolSelection) tools(all []llm.Tool) []llm.Tool {
+	if s == nil {
+		return all
+	}
+	return s.active
+}
+
+// selectTools picks the turn's tools, or returns nil when retrieval is off
+// or every tool fits.
+func (l *Loop) selectTools(ctx context.Context, messages []llm.Message, tools []llm.Tool, onEvent func(Event)) *toolSelection {
+	if l.retrieveK <= 0 || len(tools) <= l.retrieveK {
+		return nil
+	}
+	var groupOf func(string) string
+	if g, ok := l.executor.(interface{ ToolGroup(name string) string }); ok {
+		groupOf = g.ToolGroup
+	}
+	sel := &toolSelection{index: toolindex.New(tools, groupOf)}
+
+	for _, name := range recentlyCalled(messages) {
+		sel.add(name)
+	}
+	for _, m := range l.rank(ctx, sel.index, retrievalQuery(messages), l.retrieveK) {
+		sel.add(m.Tool.Function.Name)
+	}
+
+	names := make([]string, len(sel.active))
+	for i, t := range sel.active {
+		names[i] = t.Function.Name
+	}
+	sel.active = append(sel.active, searchTool(len(tools)))
+	onEvent(Event{Type: "tools_selected", Data: ToolsSelectedEvent{Tools: names, Total: len(tools)}})
+	return sel
+}
+
+// rank orders the index's tools by relevance to query, fusing BM25 with
+// embedding similarity when an embedder is set and reachable.
+func (l *Loop) rank(ctx context.Context, ix *toolindex.Index, query string, k int) []toolindex.Match {
+	matches := ix.Search(query, k)
+	if l.vectors == nil {
+		return matches
+	}
+	semantic, err := l.vectors.Search(ctx, ix, query, k)
+	if err != nil {
+		return matches // BM25 alone still works
+	}
+	return toolindex.Fuse(k, matches, semantic)
+}
+
+// add makes an indexed tool callable; unknown and already active names are
+// ignored. It reports whether the tool was added.
+func (s *toolSelection) add(name string) bool {
+	for _, t := range s.active {
+		if t.Function.Name == name {
+			return false
+		}
+	}
+	for _, t := range s.index.Tools() {
+		if t.Function.Name == name {
+			// Keep search_tools last.
+			if n := len(s.active); n > 0 && s.active[n-1].Function.Name == SearchToolsName {
+				s.active = append(s.active[:n-1:n-1], t, s.active[n-1])
+			} else {
+				s.active = append(s.active, t)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+type foundTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// search answers a search_tools call and makes the tools it finds callable
+// for the rest of the turn.
+func (l *Loop) search(ctx context.Context, sel *toolSelection, argsJSON string) string {
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	_ = json.Unmarshal([]byte(argsJSON), &args) // already validated
+	if args.Limit <= 0 {
+		args.Limit = defaultSearchLimit
+	}
+
+	res := struct {
+		Tools []foundTool `json:"tools"`
+		Hint  string      `json:"hint"`
+	}{Tools: []foundTool{}}
+	for _, m := range l.rank(ctx, sel.index, args.Query, args.Limit) {
+		sel.add(m.Tool.Function.Name)
+		res.Tools = append(res.Tools, foundTool{Name: m.Tool.Function.Name, Description: m.Tool.Function.Description})
+	}
+	if len(res.Tools) == 0 {
+		res.Hint = "No tools matched. Try other words, e.g. the kind of object or action involved."
+	} else {
+		res.Hint = "These tools can be called now."
+	}
+	data, _ := json.Marshal(res)
+	return string(data)
+}
+
+func searchTool(total int) llm.Tool {
+	return llm.Tool{Type: "function", Function: llm.ToolFunction{
+		Name: SearchToolsName,
+		Description: fmt.Sprintf("Search all %d available tools by what you want to do. "+
+			"Only the tools most relevant to the conversation are offered to you; call this when none of them fits. "+
+			"The tools found can be called right away.", total),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "What the tool should do, e.g. \"cancel an order\""},
+				"limit": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Maximum tools to return (default %d)", defaultSearchLimit)},
+			},
+			"required": []interface{}{"query"},
+		},
+	}}
+}
+
+// retrievalQuery is the text tools are matched against: the latest two user
+// messages, since the last one alone is often a short follow-up.
+func retrievalQuery(messages []llm.Message) string {
+	var parts []string
+	for i := len(messages) - 1; i >= 0 && len(parts) < 2; i-- {
+		if messages[i].Role == "user" {
+			parts = append(parts, messages[i].Content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// recentlyCalled lists the tools called in the last recentToolTurns user
+// turns, most recent first.
+func recentlyCalled(messages []llm.Message) []string {
+	var names []string
+	turns := 0
+	for i := len(messages) - 1; i >= 0 && turns < recentToolTurns; i-- {
+		m := messages[i]
+		if m.Role == "user" {
+			turns++
+		}
+		for _, tc := range m.ToolCalls {
+			if tc.Function.Name != SearchToolsName {
+				names = append(names, tc.Function.Name)
+			}
+		}
+	}
+	return names
+}
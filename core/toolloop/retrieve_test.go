@@ -0,0 +1,128 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+func manyTools() []llm.Tool {
+	descs := map[string]string{
+		"listOrders":    "List the orders of a customer",
+		"cancelOrder":   "Cancel an order",
+		"getPet":        "Find a pet",
+		"sendEmail":     "Send an email",
+		"createInvoice": "Create an invoice for an order",
+		"listUsers":     "List users",
+	}
+	var tools []llm.Tool
+	for _, name := range []string{"listOrders", "cancelOrder", "getPet", "sendEmail", "createInvoice", "listUsers"} {
+		tools = append(tools, llm.Tool{Type: "function", Function: llm.ToolFunction{
+			Name: name, Description: descs[name],
+			Parameters: map[string]interface{}{"type": "object"},
+		}})
+	}
+	return tools
+}
+
+func TestRetrievalSelectsRelevantTools(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{{Role: "assistant", Content: "ok"}}}
+	l := New(client, &fakeExecutor{})
+	l.SetToolRetrieval(2, nil)
+
+	var selected ToolsSelectedEvent
+	onEvent := func(ev Event) {
+		if ev.Type == "tools_selected" {
+			selected = ev.Data.(ToolsSelectedEvent)
+		}
+	}
+	msgs := []llm.Message{{Role: "user", Content: "cancel my last order"}}
+	if _, err := l.Run(context.Background(), msgs, manyTools(), "", nil, onEvent); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{"cancelOrder", "listOrders", SearchToolsName}
+	if !reflect.DeepEqual(client.tools[0], want) {
+		t.Errorf("tools sent = %v, want %v", client.tools[0], want)
+	}
+	if selected.Total != 6 || len(selected.Tools) != 2 {
+		t.Errorf("tools_selected = %+v, want 2 of 6", selected)
+	}
+}
+
+func TestRetrievalKeepsRecentlyCalledTools(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{{Role: "assistant", Content: "ok"}}}
+	l := New(client, &fakeExecutor{})
+	l.SetToolRetrieval(1, nil)
+
+	history := []llm.Message{
+		{Role: "user", Content: "where is Rex?"},
+		toolCalls("getPet"),
+		{Role: "tool", Content: "getPet ok", ToolCallID: "call_0"},
+		{Role: "assistant", Content: "Found it."},
+		{Role: "user", Content: "now cancel it"},
+	}
+	if _, err := l.Run(context.Background(), history, manyTools(), "", nil, func(Event) {}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"getPet", "cancelOrder", SearchToolsName}
+	if !reflect.DeepEqual(client.tools[0], want) {
+		t.Errorf("tools sent = %v, want %v", client.tools[0], want)
+	}
+}
+
+func TestSearchToolsAddsTools(t *testing.T) {
+	search := llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{
+		ID: "call_0", Type: "function",
+		Function: llm.FunctionCall{Name: SearchToolsName, Arguments: `{"query":"invoice"}`},
+	}}}
+	client := &scriptedLLM{replies: []llm.Message{
+		search,
+		toolCalls("createInvoice"),
+		{Role: "assistant", Content: "done"},
+	}}
+	exec := &fakeExecutor{}
+	l := New(client, exec)
+	l.SetToolRetrieval(1, nil)
+
+	msgs := []llm.Message{{Role: "user", Content: "bill the customer"}}
+	out, err := l.Run(context.Background(), msgs, manyTools(), "", nil, func(Event) {})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var found struct {
+		Tools []struct{ Name string } `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(out[2].Content), &found); err != nil || len(found.Tools) != 1 || found.Tools[0].Name != "createInvoice" {
+		t.Fatalf("search_tools result = %s", out[2].Content)
+	}
+	if got := strings.Join(client.tools[1], ","); !strings.Contains(got, "createInvoice") {
+		t.Errorf("tools after search = %s, want createInvoice included", got)
+	}
+	if last := client.tools[1][len(client.tools[1])-1]; last != SearchToolsName {
+		t.Errorf("last tool = %s, want %s", last, SearchToolsName)
+	}
+	if exec.calls != 1 {
+		t.Errorf("executor calls = %d, want 1 (search_tools is answered by the loop)", exec.calls)
+	}
+}
+
+func TestRetrievalOffForSmallToolsets(t *testing.T) {
+	client := &scriptedLLM{replies: []llm.Message{{Role: "assistant", Content: "ok"}}}
+	l := New(client, &fakeExecutor{})
+	l.SetToolRetrieval(10, nil)
+
+	msgs := []llm.Message{{Role: "user", Content: "hi"}}
+	if _, err := l.Run(context.Background(), msgs, manyTools(), "", nil, func(Event) {}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := fmt.Sprint(client.tools[0]); strings.Contains(got, SearchToolsName) || len(client.tools[0]) != 6 {
+		t.Errorf("tools sent = %s, want all 6 without %s", got, SearchToolsName)
+	}
+}
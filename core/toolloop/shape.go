@@ -0,0 +1,315 @@
+package toolloop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultResultLimit is the size in bytes a tool result is shaped to before
+// it enters the conversation.
+const DefaultResultLimit = 4000
+
+// Shaping steps for JSON results, tried in order until the result fits: drop
+// empty fields, keep fewer array items, shorten strings, then keep fewer
+// object fields.
+var (
+	arrayKeeps   = []int{50, 20, 10, 5, 3, 1}
+	stringLimits = []int{1000, 300, 100, 40}
+	fieldKeeps   = []int{20, 10, 5, 3, 1, 0}
+)
+
+// SetResultLimits sets how many bytes of a tool result the model sees:
+// limit by default (0 = DefaultResultLimit) and perTool by tool name or
+// glob (e.g. "github__*").
+func (l *Loop) SetResultLimits(limit int, perTool map[string]int) {
+	l.resultLimit = limit
+	l.resultLimits = perTool
+}
+
+// resultLimitFor returns the limit of a tool: its exact entry, else the
+// most specific matching glob (most literal characters), else the default.
+func (l *Loop) resultLimitFor(name string) int {
+	if n, ok := l.resultLimits[name]; ok && n > 0 {
+		return n
+	}
+	best, limit := "", 0
+	for pattern, n := range l.resultLimits {
+		if ok, _ := path.Match(pattern, name); !ok || n <= 0 {
+			continue
+		}
+		if limit == 0 || moreSpecific(pattern, best) {
+			best, limit = pattern, n
+		}
+	}
+	if limit > 0 {
+		return limit
+	}
+	if l.resultLimit > 0 {
+		return l.resultLimit
+	}
+	return DefaultResultLimit
+}
+
+// moreSpecific reports whether glob a is more specific than b.
+func moreSpecific(a, b string) bool {
+	la, lb := literalLen(a), literalLen(b)
+	if la != lb {
+		return la > lb
+	}
+	return a < b
+}
+
+func literalLen(pattern string) int {
+	n := 0
+	for _, r := range pattern {
+		if !strings.ContainsRune("*?[]\\", r) {
+			n++
+		}
+	}
+	return n
+}
+
+// shapeResult fits a tool result into limit bytes. JSON keeps its structure
+// and stays valid: null and empty fields are dropped, long arrays end with
+// an "N more items" marker, long strings are shortened and, last, trailing
+// object fields give way to a "N more fields" marker. Anything else is cut
+// on a rune boundary. shaped reports whether the result changed.
+func shapeResult(result string, limit int) (out string, shaped bool) {
+	if len(result) <= limit {
+		return result, false
+	}
+	node, err := parseJSON(result)
+	if err != nil {
+		return cutText(result, limit), true
+	}
+	node = node.dropEmpty()
+	if s := node.encode(); len(s) <= limit {
+		return s, true
+	}
+	for _, keep := range arrayKeeps {
+		if s := node.trim(keep, -1, -1).encode(); len(s) <= limit {
+			return s, true
+		}
+	}
+	minKeep := arrayKeeps[len(arrayKeeps)-1]
+	minStr := stringLimits[len(stringLimits)-1]
+	for _, n := range stringLimits {
+		if s := node.trim(minKeep, n, -1).encode(); len(s) <= limit {
+			return s, true
+		}
+	}
+	var s string
+	for _, fields := range fieldKeeps {
+		items := minKeep
+		if fields == 0 {
+			items = 0
+		}
+		if s = node.trim(items, minStr, fields).encode(); len(s) <= limit {
+			break
+		}
+	}
+	// Only a limit too small for a bare marker ends up here over limit;
+	// valid JSON still beats a cut one.
+	return s, true
+}
+
+// cutText cuts s to at most limit bytes without splitting a rune.
+func cutText(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n...(truncated, %d more bytes)", len(s)-cut)
+}
+
+// jsonNode is a decoded JSON value that keeps object key order.
+type jsonNode struct {
+	kind byte // 'o' object, 'a' array, 's' string, 'l' number/bool/null literal
+	keys []string
+	vals []*jsonNode
+	str  string
+	lit  string
+	more int // array items or object fields dropped by trim
+}
+
+func parseJSON(s string) (*jsonNode, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	n, err := decodeNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+	return n, nil
+}
+
+func decodeNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		n := &jsonNode{kind: 'a'}
+		if v == '{' {
+			n.kind = 'o'
+		}
+		for dec.More() {
+			if n.kind == 'o' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			child, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.vals = append(n.vals, child)
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &jsonNode{kind: 's', str: v}, nil
+	case json.Number:
+		return &jsonNode{kind: 'l', lit: v.String()}, nil
+	case bool:
+		return &jsonNode{kind: 'l', lit: fmt.Sprint(v)}, nil
+	default: // nil
+		return &jsonNode{kind: 'l', lit: "null"}, nil
+	}
+}
+
+func (n *jsonNode) empty() bool {
+	switch n.kind {
+	case 'o', 'a':
+		return len(n.vals) == 0 && n.more == 0
+	case 's':
+		return n.str == ""
+	default:
+		return n.lit == "null"
+	}
+}
+
+// dropEmpty removes object fields that are null, "", [] or {} after their
+// own children were cleaned. Array items are kept so positions stay meaningful.
+func (n *jsonNode) dropEmpty() *jsonNode {
+	if n.kind != 'o' && n.kind != 'a' {
+		return n
+	}
+	out := &jsonNode{kind: n.kind, more: n.more}
+	for i, v := range n.vals {
+		v = v.dropEmpty()
+		if n.kind == 'o' {
+			if v.empty() {
+				continue
+			}
+			out.keys = append(out.keys, n.keys[i])
+		}
+		out.vals = append(out.vals, v)
+	}
+	return out
+}
+
+// trim keeps the first keepItems items of every array and, when
+// keepFields >= 0, the first keepFields fields of every object; when
+// maxStr >= 0 it shortens strings to maxStr runes.
+func (n *jsonNode) trim(keepItems, maxStr, keepFields int) *jsonNode {
+	switch n.kind {
+	case 's':
+		if maxStr >= 0 && utf8.RuneCountInString(n.str) > maxStr {
+			r := []rune(n.str)
+			return &jsonNode{kind: 's', str: fmt.Sprintf("%s...(%d more chars)", string(r[:maxStr]), len(r)-maxStr)}
+		}
+		return n
+	case 'o', 'a':
+		out := &jsonNode{kind: n.kind, keys: n.keys, more: n.more}
+		vals := n.vals
+		keep := keepItems
+		if n.kind == 'o' {
+			keep = keepFields
+		}
+		if keep >= 0 && len(vals) > keep {
+			out.more += len(vals) - keep
+			vals = vals[:keep]
+			if n.kind == 'o' {
+				out.keys = n.keys[:keep]
+			}
+		}
+		for _, v := range vals {
+			out.vals = append(out.vals, v.trim(keepItems, maxStr, keepFields))
+		}
+		return out
+	default:
+		return n
+	}
+}
+
+func (n *jsonNode) encode() string {
+	var b bytes.Buffer
+	n.write(&b)
+	return b.String()
+}
+
+func (n *jsonNode) write(b *bytes.Buffer) {
+	switch n.kind {
+	case 'o':
+		b.WriteByte('{')
+		for i, v := range n.vals {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, n.keys[i])
+			b.WriteByte(':')
+			v.write(b)
+		}
+		if n.more > 0 {
+			if len(n.vals) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`"...":`)
+			writeString(b, fmt.Sprintf("%d more fields", n.more))
+		}
+		b.WriteByte('}')
+	case 'a':
+		b.WriteByte('[')
+		for i, v := range n.vals {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			v.write(b)
+		}
+		if n.more > 0 {
+			if len(n.vals) > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, fmt.Sprintf("... %d more items", n.more))
+		}
+		b.WriteByte(']')
+	case 's':
+		writeString(b, n.str)
+	default:
+		b.WriteString(n.lit)
+	}
+}
+
+// writeString writes s as a JSON string without HTML escaping.
+func writeString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	b.Truncate(b.Len() - 1) // Encode appends a newline
+}
@@ -0,0 +1,123 @@
+package toolloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestShapeResultKeepsSmallResults(t *testing.T) {
+	in := `{"id":1,"note":null}`
+	out, shaped := shapeResult(in, 100)
+	if shaped || out != in {
+		t.Errorf("shapeResult = %q, %v; want unchanged", out, shaped)
+	}
+}
+
+func TestShapeResultDropsEmptyFields(t *testing.T) {
+	in := `{"id":7,"name":"Rex","tag":null,"notes":"","owner":{"email":null,"phones":[]},"tags":["a"],"pad":"` + strings.Repeat(" ", 10) + `"}`
+	out, shaped := shapeResult(in, len(in)-1)
+	if !shaped {
+		t.Fatal("expected result to be shaped")
+	}
+	want := `{"id":7,"name":"Rex","tags":["a"],"pad":"          "}`
+	if out != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+}
+
+func TestShapeResultTrimsArrays(t *testing.T) {
+	var items []string
+	for i := 0; i < 200; i++ {
+		items = append(items, fmt.Sprintf(`{"id":%d,"name":"pet-%d"}`, i, i))
+	}
+	in := `{"total":200,"items":[` + strings.Join(items, ",") + `]}`
+
+	out, shaped := shapeResult(in, 1000)
+	if !shaped || len(out) > 1000 {
+		t.Fatalf("shaped=%v len=%d, want shaped within 1000 bytes", shaped, len(out))
+	}
+	var v struct {
+		Total int               `json:"total"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		t.Fatalf("shaped result is not valid JSON: %v\n%s", err, out)
+	}
+	if v.Total != 200 {
+		t.Errorf("total = %d, want key order and values kept", v.Total)
+	}
+	last := string(v.Items[len(v.Items)-1])
+	wantMarker := fmt.Sprintf(`"... %d more items"`, 200-(len(v.Items)-1))
+	if last != wantMarker {
+		t.Errorf("last item = %s, want %s", last, wantMarker)
+	}
+	if !strings.HasPrefix(out, `{"total":200,"items":[{"id":0,"name":"pet-0"}`) {
+		t.Errorf("structure not kept: %s", out[:60])
+	}
+}
+
+func TestShapeResultShortensStrings(t *testing.T) {
+	in := `{"body":"` + strings.Repeat("日本語", 2000) + `"}`
+	out, _ := shapeResult(in, 500)
+	if len(out) > 500 || !json.Valid([]byte(out)) {
+		t.Fatalf("len=%d valid=%v: %s", len(out), json.Valid([]byte(out)), out)
+	}
+	if !strings.Contains(out, "more chars)") {
+		t.Errorf("missing truncation note: %s", out)
+	}
+}
+
+func TestShapeResultDropsTrailingFields(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < 300; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"field_%03d":"%s"`, i, strings.Repeat("x", 30))
+	}
+	b.WriteString("}")
+
+	out, shaped := shapeResult(b.String(), 1000)
+	if !shaped || len(out) > 1000 || !json.Valid([]byte(out)) {
+		t.Fatalf("len=%d valid=%v: %s", len(out), json.Valid([]byte(out)), out)
+	}
+	var obj map[string]interface{}
+	_ = json.Unmarshal([]byte(out), &obj)
+	if _, ok := obj["field_000"]; !ok || !strings.HasSuffix(fmt.Sprint(obj["..."]), "more fields") {
+		t.Errorf("expected leading fields and a marker: %s", out)
+	}
+}
+
+func TestShapeResultCutsTextOnRuneBoundary(t *testing.T) {
+	in := strings.Repeat("é", 3000) // 2 bytes per rune
+	out, shaped := shapeResult(in, 1001)
+	if !shaped || !utf8.ValidString(out) {
+		t.Fatalf("shaped=%v valid UTF-8=%v", shaped, utf8.ValidString(out))
+	}
+	if !strings.HasPrefix(out, strings.Repeat("é", 500)+"\n...(truncated, 5000 more bytes)") {
+		t.Errorf("unexpected cut: %q", out[990:])
+	}
+}
+
+func TestResultLimitFor(t *testing.T) {
+	l := New(nil, nil)
+	if got := l.resultLimitFor("any"); got != DefaultResultLimit {
+		t.Errorf("default = %d", got)
+	}
+	l.SetResultLimits(2000, map[string]int{"github__*": 8000, "github__get_file": 16000, "*": 1000, "github__list_*": 500})
+	cases := map[string]int{
+		"github__get_file":  16000,
+		"github__list_pull": 500, // the most specific glob wins
+		"github__search":    8000,
+		"jira__search":      1000,
+	}
+	for name, want := range cases {
+		if got := l.resultLimitFor(name); got != want {
+			t.Errorf("resultLimitFor(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
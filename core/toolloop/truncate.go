@@ -1,38 +1,90 @@
 package toolloop
 
-import "github.com/ZacharyZcR/NLUI/core/llm"
+import (
+	"encoding/json"
+	"math"
 
-// estimateTokens gives a rough token count (~4 chars per token).
-func estimateTokens(msg *llm.Message) int {
-	n := len(msg.Content)
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
+)
+
+// attachmentTokens is a flat estimate per image/file part; their base64
+// payload length says little about what the provider actually bills.
+const attachmentTokens = 1000
+
+// Bounds for the calibration factor, so one odd usage report can't make the
+// budget wildly permissive or collapse it.
+const (
+	minCalibration = 0.5
+	maxCalibration = 4.0
+)
+
+// countMessage counts the tokens of a message's text, tool calls and parts.
+func countMessage(c tokenizer.Counter, msg *llm.Message) int {
+	n := c.Count(msg.Content)
 	for _, tc := range msg.ToolCalls {
-		n += len(tc.Function.Name) + len(tc.Function.Arguments)
+		n += c.Count(tc.Function.Name) + c.Count(tc.Function.Arguments)
+	}
+	for _, p := range msg.Parts {
+		if p.Type == "text" {
+			n += c.Count(p.Text)
+		} else {
+			n += attachmentTokens
+		}
+	}
+	return n
+}
+
+// countTools counts the tool definitions as they are serialized into the request.
+func countTools(c tokenizer.Counter, tools []llm.Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
 	}
-	return n / 4
+	return c.Count(string(data))
+}
+
+// estimateTokens estimates a message with the script-aware fallback counter.
+func estimateTokens(msg *llm.Message) int {
+	return countMessage(tokenizer.Estimator{}, msg)
+}
+
+// scaled applies the calibration factor to a raw count.
+func scaled(n int, factor float64) int {
+	if factor <= 0 || n == 0 {
+		return n
+	}
+	return int(math.Ceil(float64(n) * factor))
 }
 
 // truncateMessages keeps messages within a token budget.
 //
 // Rules:
-//  1. system messages (index 0 if role=="system") are always kept
+//  1. leading system messages (the system prompt and any history summary)
+//     are always kept
 //  2. recent messages are preserved (scan from tail)
 //  3. an assistant message with ToolCalls and its subsequent tool-role messages
 //     form an atomic block — they are never split
 //  4. maxTokens <= 0 disables truncation
-func truncateMessages(messages []llm.Message, maxTokens int) []llm.Message {
+//
+// count is the per-message token estimate.
+func truncateMessages(messages []llm.Message, maxTokens int, count func(*llm.Message) int) []llm.Message {
 	if maxTokens <= 0 || len(messages) == 0 {
 		return messages
 	}
 
-	// Reserve budget for system message
+	// Reserve budget for leading system messages
 	budget := maxTokens
 	startIdx := 0
-	if messages[0].Role == "system" {
-		budget -= estimateTokens(&messages[0])
-		startIdx = 1
-		if budget <= 0 {
-			return messages[:1]
-		}
+	for startIdx < len(messages) && messages[startIdx].Role == "system" {
+		budget -= count(&messages[startIdx])
+		startIdx++
+	}
+	if startIdx > 0 && budget <= 0 {
+		return messages[:startIdx]
 	}
 
 	// Build atomic blocks from the non-system portion.
@@ -50,10 +102,10 @@ func truncateMessages(messages []llm.Message, maxTokens int) []llm.Message {
 	for i < len(rest) {
 		msg := &rest[i]
 		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
-			b := block{start: i, tokens: estimateTokens(msg)}
+			b := block{start: i, tokens: count(msg)}
 			j := i + 1
 			for j < len(rest) && rest[j].Role == "tool" {
-				b.tokens += estimateTokens(&rest[j])
+				b.tokens += count(&rest[j])
 				j++
 			}
 			b.end = j
@@ -63,7 +115,7 @@ func truncateMessages(messages []llm.Message, maxTokens int) []llm.Message {
 			blocks = append(blocks, block{
 				start:  i,
 				end:    i + 1,
-				tokens: estimateTokens(msg),
+				tokens: count(msg),
 			})
 			i++
 		}
@@ -83,16 +135,14 @@ func truncateMessages(messages []llm.Message, maxTokens int) []llm.Message {
 	if cutBlock >= len(blocks) {
 		// Nothing fits — keep only system
 		if startIdx > 0 {
-			return messages[:1]
+			return messages[:startIdx]
 		}
 		return nil
 	}
 
 	cutIdx := startIdx + blocks[cutBlock].start
-	out := make([]llm.Message, 0, 1+len(messages)-cutIdx)
-	if startIdx > 0 {
-		out = append(out, messages[0])
-	}
+	out := make([]llm.Message, 0, startIdx+len(messages)-cutIdx)
+	out = append(out, messages[:startIdx]...)
 	out = append(out, messages[cutIdx:]...)
 	return out
 }
@@ -16,7 +16,7 @@ func msgs(roles ...string) []llm.Message {
 
 func TestTruncateDisabledWhenZero(t *testing.T) {
 	m := msgs("system", "user", "assistant")
-	got := truncateMessages(m, 0)
+	got := truncateMessages(m, 0, estimateTokens)
 	if len(got) != 3 {
 		t.Fatalf("expected 3, got %d", len(got))
 	}
@@ -31,7 +31,7 @@ func TestTruncateKeepsSystem(t *testing.T) {
 		{Role: "assistant", Content: "another assistant reply with enough text"},
 	}
 	// Budget so tight only system fits (system ~4 tokens, budget 5 leaves ~1 for rest)
-	got := truncateMessages(m, 5)
+	got := truncateMessages(m, 5, estimateTokens)
 	if len(got) != 1 || got[0].Role != "system" {
 		t.Fatalf("expected only system, got %d messages", len(got))
 	}
@@ -46,7 +46,7 @@ func TestTruncatePreservesRecent(t *testing.T) {
 		{Role: "assistant", Content: "new"},
 	}
 	// Budget enough for system + last 2 messages but not all
-	got := truncateMessages(m, 20)
+	got := truncateMessages(m, 20, estimateTokens)
 	if got[0].Role != "system" {
 		t.Fatal("first must be system")
 	}
@@ -67,7 +67,7 @@ func TestTruncateAtomicBlock(t *testing.T) {
 		{Role: "user", Content: "ok"},
 	}
 	// Enough budget for system + atomic block (assistant+tool) + user
-	got := truncateMessages(m, 100)
+	got := truncateMessages(m, 100, estimateTokens)
 	if len(got) != 5 {
 		t.Fatalf("expected 5, got %d", len(got))
 	}
@@ -82,7 +82,7 @@ func TestTruncateAtomicBlockNotSplit(t *testing.T) {
 		{Role: "user", Content: "x"},
 	}
 	// Budget enough for user but not the atomic block
-	got := truncateMessages(m, 5)
+	got := truncateMessages(m, 5, estimateTokens)
 	// Should keep system + user, skip the atomic block entirely
 	hasSystem := false
 	hasTool := false
@@ -109,3 +109,58 @@ func TestEstimateTokens(t *testing.T) {
 		t.Fatalf("expected 4, got %d", got)
 	}
 }
+
+// fixedCounter counts one token per byte, to make budgets easy to reason about.
+type fixedCounter struct{}
+
+func (fixedCounter) Count(s string) int { return len(s) }
+
+func TestFitContextCountsTools(t *testing.T) {
+	l := New(nil, nil)
+	l.SetTokenCounter(fixedCounter{})
+	l.SetMaxContextTokens(60)
+
+	m := []llm.Message{
+		{Role: "system", Content: "s"},
+		{Role: "user", Content: "0123456789"},
+		{Role: "user", Content: "0123456789"},
+	}
+	if got, _ := l.fitContext(m, nil); len(got) != 3 {
+		t.Fatalf("without tools everything fits, got %d", len(got))
+	}
+
+	tools := []llm.Tool{{Type: "function", Function: llm.ToolFunction{Name: "a_long_tool_name", Description: "d"}}}
+	got, raw := l.fitContext(m, tools)
+	if len(got) == 3 {
+		t.Fatal("tool definitions must consume context budget")
+	}
+	if raw <= countTools(fixedCounter{}, tools) {
+		t.Errorf("raw estimate %d must include kept messages and tools", raw)
+	}
+}
+
+func TestCalibrationScalesBudget(t *testing.T) {
+	l := New(nil, nil)
+	l.SetTokenCounter(fixedCounter{})
+	l.SetMaxContextTokens(25)
+
+	m := []llm.Message{
+		{Role: "system", Content: "s"},
+		{Role: "user", Content: "0123456789"},
+		{Role: "user", Content: "0123456789"},
+	}
+	if got, _ := l.fitContext(m, nil); len(got) != 3 {
+		t.Fatalf("uncalibrated: expected 3, got %d", len(got))
+	}
+
+	// Provider reported twice our estimate: only the newest message fits now.
+	l.calibrate(21, 42)
+	if got, _ := l.fitContext(m, nil); len(got) != 2 {
+		t.Fatalf("calibrated: expected 2, got %d", len(got))
+	}
+
+	l.calibrate(10, 10000)
+	if f := l.calibrationFactor(); f != maxCalibration {
+		t.Errorf("factor = %v, want clamp to %v", f, maxCalibration)
+	}
+}
@@ -0,0 +1,234 @@
+package toolloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// ArgProblem is one way tool arguments break the tool's schema.
+type ArgProblem struct {
+	Path    string `json:"path"` // dotted path to the argument; "" for the whole object
+	Message string `json:"message"`
+}
+
+// ValidationError is returned to the model instead of calling a tool whose
+// arguments don't match its schema.
+type ValidationError struct {
+	Error    string       `json:"error"`
+	Tool     string       `json:"tool"`
+	Problems []ArgProblem `json:"problems"`
+	Hint     string       `json:"hint"`
+}
+
+func (e ValidationError) String() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+// toolSchemas maps tool names to their parameter schemas as plain JSON maps.
+func toolSchemas(tools []llm.Tool) map[string]map[string]interface{} {
+	schemas := make(map[string]map[string]interface{}, len(tools))
+	for _, t := range tools {
+		if s := asSchema(t.Function.Parameters); s != nil {
+			schemas[t.Function.Name] = s
+		}
+	}
+	return schemas
+}
+
+// asSchema normalizes a schema of any Go type to map[string]interface{}.
+func asSchema(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if json.Unmarshal(data, &m) != nil {
+		return nil
+	}
+	return m
+}
+
+// ValidateArguments checks argsJSON against a tool's parameter schema, the
+// same way the loop does before running the tool.
+func ValidateArguments(tool llm.Tool, argsJSON string) []ArgProblem {
+	return validateArgs(asSchema(tool.Function.Parameters), argsJSON)
+}
+
+// validateArgs checks argsJSON against schema: types, required properties
+// and enums, recursing into properties and items. Other keywords are ignored.
+func validateArgs(schema map[string]interface{}, argsJSON string) []ArgProblem {
+	var args interface{} = map[string]interface{}{}
+	if strings.TrimSpace(argsJSON) != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return []ArgProblem{{Message: "arguments are not valid JSON: " + err.Error()}}
+		}
+	}
+	var problems []ArgProblem
+	checkValue(schema, args, "", &problems)
+	return problems
+}
+
+func checkValue(schema map[string]interface{}, v interface{}, path string, problems *[]ArgProblem) {
+	add := func(format string, a ...interface{}) {
+		*problems = append(*problems, ArgProblem{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(types, v) {
+		add("expected %s, got %s", strings.Join(types, " or "), jsonType(v))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 && !inEnum(enum, v) {
+		add("must be one of %s", enumList(enum))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range requiredNames(schema["required"]) {
+			if _, ok := val[name]; !ok {
+				*problems = append(*problems, ArgProblem{Path: joinPath(path, name), Message: "required"})
+			}
+		}
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ps := asSchema(props[name]); ps != nil {
+				checkValue(ps, val[name], joinPath(path, name), problems)
+			}
+		}
+	case []interface{}:
+		if items := asSchema(schema["items"]); items != nil {
+			for i, item := range val {
+				checkValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+}
+
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func matchesAnyType(types []string, v interface{}) bool {
+	for _, t := range types {
+		if matchesType(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, v interface{}) bool {
+	switch t {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true // unknown type keyword: don't second-guess the schema
+}
+
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// inEnum compares JSON encodings, so 1 matches 1.0 whatever Go type the
+// schema's enum was decoded into.
+func inEnum(enum []interface{}, v interface{}) bool {
+	want, _ := json.Marshal(v)
+	for _, e := range enum {
+		if got, _ := json.Marshal(e); string(got) == string(want) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	data, _ := json.Marshal(enum)
+	return string(data)
+}
+
+func requiredNames(r interface{}) []string {
+	switch r := r.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		var out []string
+		for _, s := range r {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
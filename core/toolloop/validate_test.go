@@ -0,0 +1,124 @@
+package toolloop
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+var petSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"id":     map[string]interface{}{"type": "integer"},
+		"status": map[string]interface{}{"type": "string", "enum": []interface{}{"available", "sold"}},
+		"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"body": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"name"},
+		},
+	},
+	"required": []string{"id"},
+}
+
+func TestValidateArgs(t *testing.T) {
+	cases := []struct {
+		args string
+		want []string // "path: message"
+	}{
+		{`{"id": 7}`, nil},
+		{`{"id": 7, "status": "sold", "tags": ["a"], "body": {"name": "Rex"}}`, nil},
+		{``, []string{"id: required"}},
+		{`{"id": "7"}`, []string{"id: expected integer, got string"}},
+		{`{"id": 7.5}`, []string{"id: expected integer, got number"}},
+		{`{"id": 7, "status": "lost"}`, []string{`status: must be one of ["available","sold"]`}},
+		{`{"id": 7, "tags": ["a", 2]}`, []string{"tags[1]: expected string, got integer"}},
+		{`{"id": 7, "body": {}}`, []string{"body.name: required"}},
+		{`[1]`, []string{": expected object, got array"}},
+		{`{"id": 7`, []string{": arguments are not valid JSON: unexpected end of JSON input"}},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, p := range validateArgs(petSchema, c.args) {
+			got = append(got, p.Path+": "+p.Message)
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("validateArgs(%s) = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestInvalidArgumentsSkipExecution(t *testing.T) {
+	bad := llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{
+		ID: "call_0", Type: "function",
+		Function: llm.FunctionCall{Name: "getPet", Arguments: `{"id": "{id}"}`},
+	}}}
+	good := llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{
+		ID: "call_1", Type: "function",
+		Function: llm.FunctionCall{Name: "getPet", Arguments: `{"id": 7}`},
+	}}}
+	client := &scriptedLLM{replies: []llm.Message{bad, good, {Role: "assistant", Content: "done"}}}
+	exec := &fakeExecutor{}
+	l := New(client, exec)
+	l.SetBudget(Budget{MaxToolCalls: 5})
+
+	tools := []llm.Tool{{Type: "function", Function: llm.ToolFunction{Name: "getPet", Parameters: petSchema}}}
+	msgs, err := l.Run(context.Background(), nil, tools, "", nil, func(Event) {})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if exec.calls != 1 {
+		t.Errorf("executor calls = %d, want only the valid retry", exec.calls)
+	}
+	var verr ValidationError
+	if err := json.Unmarshal([]byte(msgs[1].Content), &verr); err != nil {
+		t.Fatalf("tool result is not a validation error: %q", msgs[1].Content)
+	}
+	if verr.Error != "invalid_arguments" || verr.Tool != "getPet" || len(verr.Problems) != 1 || verr.Problems[0].Path != "id" {
+		t.Errorf("validation error = %+v", verr)
+	}
+	if msgs[3].Content != "getPet ok" {
+		t.Errorf("retry result = %q", msgs[3].Content)
+	}
+}
+
+func TestConfirmCanEditArguments(t *testing.T) {
+	call := func(id, args string) llm.ToolCall {
+		return llm.ToolCall{ID: id, Type: "function", Function: llm.FunctionCall{Name: "deletePet", Arguments: args}}
+	}
+	client := &scriptedLLM{replies: []llm.Message{
+		{Role: "assistant", ToolCalls: []llm.ToolCall{call("call_0", `{"id": 1}`), call("call_1", `{"id": 2}`)}},
+		{Role: "assistant", Content: "done"},
+	}}
+	l := New(client, &fakeExecutor{reply: echoArgs})
+	edits := map[string]string{`{"id": 1}`: `{"id": 7}`, `{"id": 2}`: `{"id": "seven"}`}
+	confirm := func(req ConfirmRequest) ConfirmResponse {
+		return ConfirmResponse{Approved: true, Arguments: edits[req.Arguments]}
+	}
+
+	var results []ToolResultEvent
+	tools := []llm.Tool{{Type: "function", Function: llm.ToolFunction{Name: "deletePet", Parameters: petSchema}}}
+	msgs, err := l.Run(context.Background(), nil, tools, "", confirm, func(ev Event) {
+		if r, ok := ev.Data.(ToolResultEvent); ok {
+			results = append(results, r)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if msgs[1].Content != `deletePet {"id": 7}` || results[0].Arguments != `{"id": 7}` {
+		t.Errorf("edited call ran as %q (event arguments %q)", msgs[1].Content, results[0].Arguments)
+	}
+	if got := msgs[0].ToolCalls[0].Function.Arguments; got != `{"id": 7}` {
+		t.Errorf("history records %s, want the edited arguments", got)
+	}
+	if !strings.Contains(msgs[2].Content, "invalid_arguments") || results[1].Arguments != "" {
+		t.Errorf("invalid edit should not run: %q", msgs[2].Content)
+	}
+	if got := msgs[0].ToolCalls[1].Function.Arguments; got != `{"id": 2}` {
+		t.Errorf("rejected edit recorded as %s", got)
+	}
+}
@@ -12,12 +12,14 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ZacharyZcR/NLUI/bootstrap"
 	"github.com/ZacharyZcR/NLUI/config"
 	"github.com/ZacharyZcR/NLUI/core/conversation"
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
 	"github.com/ZacharyZcR/NLUI/engine"
 	"github.com/ZacharyZcR/NLUI/gateway"
 	"github.com/ZacharyZcR/NLUI/mcp"
@@ -33,7 +35,8 @@ type App struct {
 	convMgr          *conversation.Manager // survives reinit
 	language         string
 	ready            bool
-	confirmCh        chan bool
+	confirmCh        chan engine.ConfirmResponse
+	pendingConfirm   atomic.Value // name of the tool awaiting confirmation
 	mcpClients       map[string]*mcp.Client
 	chatCancel       context.CancelFunc // for stopping active chat
 	chatCancelMu     sync.Mutex
@@ -158,6 +161,7 @@ func (a *App) initialize() {
 	a.mcpClients = mcpClients
 
 	caller := gateway.NewCaller(allEndpoints)
+	caller.SetCacheTTL(time.Duration(cfg.Tools.CacheTTLSec) * time.Second)
 	caller.OnAuthChanged = func(configName, token string) {
 		if err := a.svc.SaveTargetAuth(configName, token); err != nil {
 			log.Printf("persist auth token: %v", err)
@@ -169,7 +173,16 @@ func (a *App) initialize() {
 	}
 	a.router = router
 
-	llmClient := llm.NewAutoClient(cfg.LLM.APIBase, cfg.LLM.APIKey, cfg.LLM.Model, cfg.Proxy, cfg.LLM.IsStream())
+	llmClient, err := bootstrap.NewLLMClient(cfg)
+	if err != nil {
+		log.Printf("llm client: %v", err)
+		return
+	}
+	toolPolicy, err := bootstrap.NewPolicy(cfg)
+	if err != nil {
+		log.Printf("tool policy: %v", err)
+		return
+	}
 	a.language = cfg.Language
 
 	convDir := ""
@@ -180,15 +193,27 @@ func (a *App) initialize() {
 		a.convMgr = conversation.NewManager(convDir)
 	}
 
-	a.confirmCh = make(chan bool, 1)
+	a.confirmCh = make(chan engine.ConfirmResponse, 1)
 
 	eng := engine.New(engine.Config{
-		LLM:          llmClient,
-		Executor:     router,
-		Tools:        allTools,
-		SystemPrompt: bootstrap.BuildSystemPrompt(cfg.Language, cfg.Targets, allTools),
-		MaxCtxTokens: cfg.LLM.MaxCtxTokens,
-		ConvMgr:      a.convMgr,
+		LLM:           llmClient,
+		Executor:      router,
+		Tools:         allTools,
+		SystemPrompt:  bootstrap.BuildSystemPrompt(cfg.Language, cfg.Targets, allTools),
+		MaxCtxTokens:  cfg.LLM.MaxCtxTokens,
+		Compact:       cfg.LLM.SummarizesContext(),
+		TokenCounter:  tokenizer.ForModel(cfg.LLM.Model),
+		Model:         cfg.LLM.Model,
+		Prices:        bootstrap.NewPriceTable(cfg),
+		ToolWorkers:   cfg.Tools.Concurrency,
+		ResultLimit:   cfg.Tools.ResultLimit,
+		ResultLimits:  cfg.Tools.ResultLimits,
+		LoopThreshold: cfg.Tools.LoopThreshold,
+		RetrieveTools: cfg.Tools.Retrieval.TopK,
+		Embedder:      bootstrap.NewEmbedder(cfg),
+		Budget:        bootstrap.TurnBudget(cfg),
+		Policy:        toolPolicy,
+		ConvMgr:       a.convMgr,
 	})
 	a.engine = eng
 	a.ready = true
@@ -234,26 +259,17 @@ func (a *App) ProbeProviders() []ProviderInfo {
 	return result
 }
 
-// FetchModels queries /v1/models for any OpenAI-compatible endpoint.
+// FetchModels lists the models of an endpoint (deployments for Azure OpenAI).
 func (a *App) FetchModels(apiBase, apiKey string) []string {
-	client := a.proxyHTTPClient(5 * time.Second)
-	u := strings.TrimRight(apiBase, "/") + "/models"
-
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		return nil
-	}
-	if apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+	provider := ""
+	if cfg, err := a.svc.LoadConfig(); err == nil && cfg.LLM.APIBase == apiBase {
+		provider = cfg.LLM.Provider
 	}
-
-	resp, err := client.Do(req)
+	models, err := llm.ListModels(context.Background(), a.proxyHTTPClient(5*time.Second), provider, apiBase, apiKey)
 	if err != nil {
 		return nil
 	}
-	defer resp.Body.Close()
-
-	return parseModelsResponse(resp)
+	return models
 }
 
 // proxyHTTPClient returns an http.Client that uses the configured proxy (if any).
@@ -297,7 +313,9 @@ func parseModelsResponse(resp *http.Response) []string {
 
 // SaveLLMConfig writes LLM settings to nlui.yaml and reinitializes.
 func (a *App) SaveLLMConfig(apiBase, apiKey, model string) string {
-	if err := a.svc.SaveLLMConfig(apiBase, apiKey, model); err != nil {
+	// The desktop UI has no provider picker; provider and Azure settings
+	// keep whatever nlui.yaml says.
+	if err := a.svc.SaveLLMConfig(service.LLMUpdate{APIBase: apiBase, APIKey: apiKey, Model: model}); err != nil {
 		return err.Error()
 	}
 	a.initialize()
@@ -316,6 +334,7 @@ func (a *App) GetCurrentConfig() map[string]interface{} {
 	}
 	return map[string]interface{}{
 		"exists":   true,
+		"provider": cfg.LLM.Provider,
 		"api_base": cfg.LLM.APIBase,
 		"api_key":  cfg.LLM.APIKey, // Desktop app: return full key for editing
 		"model":    cfg.LLM.Model,
@@ -517,11 +536,9 @@ func (a *App) Chat(message, conversationID string) string {
 		a.chatCancelMu.Unlock()
 	}()
 
-	confirm := func(toolName, argsJSON string) bool {
-		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", map[string]string{
-			"name":      toolName,
-			"arguments": argsJSON,
-		})
+	confirm := func(req engine.ConfirmRequest) engine.ConfirmResponse {
+		a.pendingConfirm.Store(req.Name)
+		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", req)
 		return <-a.confirmCh
 	}
 
@@ -645,10 +662,14 @@ func (a *App) GetConversationMessages(id string) []ChatMessage {
 			}
 		case "tool":
 			seq++
+			content := m.Content
+			if m.FullContent != "" {
+				content = m.FullContent
+			}
 			result = append(result, ChatMessage{
 				ID:       fmt.Sprintf("hist-%d", seq),
 				Role:     "tool_result",
-				Content:  m.Content,
+				Content:  content,
 				ToolName: m.ToolCallID,
 			})
 		}
@@ -656,12 +677,28 @@ func (a *App) GetConversationMessages(id string) []ChatMessage {
 	return result
 }
 
-// ConfirmTool is called by the frontend to approve/reject a dangerous tool call.
-func (a *App) ConfirmTool(approved bool) {
+// ConfirmTool is called by the frontend to approve/reject a tool call. An
+// approval may carry edited arguments; if they don't match the tool's schema
+// nothing is sent and the problems are returned so the user can fix them.
+func (a *App) ConfirmTool(approved bool, arguments string) string {
+	if approved && arguments != "" && a.engine != nil {
+		tool, _ := a.pendingConfirm.Load().(string)
+		if problems := a.engine.ValidateToolArguments(tool, arguments); len(problems) > 0 {
+			msgs := make([]string, len(problems))
+			for i, p := range problems {
+				msgs[i] = p.Message
+				if p.Path != "" {
+					msgs[i] = p.Path + ": " + p.Message
+				}
+			}
+			return strings.Join(msgs, "; ")
+		}
+	}
 	select {
-	case a.confirmCh <- approved:
+	case a.confirmCh <- engine.ConfirmResponse{Approved: approved, Arguments: arguments}:
 	default:
 	}
+	return ""
 }
 
 // ToolInfo describes a single tool for the frontend.
@@ -764,11 +801,9 @@ func (a *App) EditMessage(convID string, msgIndex int, newContent string) string
 		a.chatCancelMu.Unlock()
 	}()
 
-	confirm := func(toolName, argsJSON string) bool {
-		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", map[string]string{
-			"name":      toolName,
-			"arguments": argsJSON,
-		})
+	confirm := func(req engine.ConfirmRequest) engine.ConfirmResponse {
+		a.pendingConfirm.Store(req.Name)
+		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", req)
 		return <-a.confirmCh
 	}
 
@@ -969,6 +1004,17 @@ func (a *App) UpdateToolConfig(convID string, enabledSources, disabledTools []st
 	return ""
 }
 
+// SetDryRun makes a conversation preview tool requests instead of sending them.
+func (a *App) SetDryRun(convID string, on bool) string {
+	if !a.ready {
+		return "not ready"
+	}
+	if err := a.engine.UpdateDryRun(convID, on); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // GetToolConfig returns the tool configuration for a conversation.
 type ToolConfig struct {
 	EnabledSources []string `json:"enabled_sources"`
@@ -1107,11 +1153,9 @@ func (a *App) RegenerateFrom(convID string, fromIndex int) string {
 		a.chatCancelMu.Unlock()
 	}()
 
-	confirm := func(toolName, argsJSON string) bool {
-		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", map[string]string{
-			"name":      toolName,
-			"arguments": argsJSON,
-		})
+	confirm := func(req engine.ConfirmRequest) engine.ConfirmResponse {
+		a.pendingConfirm.Store(req.Name)
+		wailsRuntime.EventsEmit(a.ctx, "tool-confirm", req)
 		return <-a.confirmCh
 	}
 
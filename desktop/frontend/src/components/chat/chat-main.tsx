@@ -3,6 +3,7 @@ import { AlertTriangle, Check, X, StopCircle } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Badge } from "@/components/ui/badge";
 import { Card, CardContent } from "@/components/ui/card";
+import { Textarea } from "@/components/ui/textarea";
 import {
   AlertDialog,
   AlertDialogAction,
@@ -36,6 +37,7 @@ function nextId() {
 interface PendingConfirm {
   name: string;
   arguments: string;
+  rule?: string;
 }
 
 interface UsageInfo {
@@ -50,6 +52,8 @@ export function ChatMain({ conversationId, onConversationCreated }: ChatMainProp
   const [loading, setLoading] = useState(false);
   const [usage, setUsage] = useState<UsageInfo | null>(null);
   const [pendingConfirm, setPendingConfirm] = useState<PendingConfirm | null>(null);
+  const [editedArgs, setEditedArgs] = useState("");
+  const [confirmError, setConfirmError] = useState("");
   const [pendingDeleteIndex, setPendingDeleteIndex] = useState<number | null>(null);
   const [elapsed, setElapsed] = useState(0);
   const scrollRef = useRef<HTMLDivElement>(null);
@@ -123,22 +127,56 @@ export function ChatMain({ conversationId, onConversationCreated }: ChatMainProp
           scrollToBottom();
           break;
         }
+        case "content_discarded": {
+          // The time budget cut the answer off; the wrap-up streams next.
+          const id = streamIdRef.current;
+          streamIdRef.current = "";
+          if (id) setMessages((prev) => prev.filter((m) => m.id !== id));
+          break;
+        }
         case "tool_call": {
           streamIdRef.current = "";
-          const d = event.data as { name: string; arguments: string };
+          const d = event.data as { id: string; name: string; arguments: string };
           setMessages((prev) => [
             ...prev,
-            { id: nextId(), role: "tool_call", content: "", toolName: d.name, toolArgs: d.arguments, timestamp: new Date() },
+            { id: nextId(), role: "tool_call", content: "", toolName: d.name, toolArgs: d.arguments, toolCallId: d.id, timestamp: new Date() },
           ]);
           scrollToBottom();
           break;
         }
         case "tool_result": {
-          const d = event.data as { name: string; result: string };
-          setMessages((prev) => [
-            ...prev,
-            { id: nextId(), role: "tool_result", content: d.result, toolName: d.name, timestamp: new Date() },
-          ]);
+          const d = event.data as {
+            id: string;
+            name: string;
+            result: string;
+            status: Message["toolStatus"];
+            duration_ms: number;
+            arguments?: string;
+          };
+          setMessages((prev) => {
+            let next = prev;
+            if (d.arguments) {
+              // The user edited the arguments on confirmation; show what actually ran.
+              const idx = prev.findIndex((m) => m.role === "tool_call" && m.toolCallId === d.id);
+              if (idx >= 0) {
+                next = [...prev];
+                next[idx] = { ...next[idx], toolArgs: d.arguments };
+              }
+            }
+            return [
+              ...next,
+              {
+                id: nextId(),
+                role: "tool_result",
+                content: d.result,
+                toolName: d.name,
+                toolCallId: d.id,
+                toolStatus: d.status,
+                durationMs: d.duration_ms,
+                timestamp: new Date(),
+              },
+            ];
+          });
           scrollToBottom();
           break;
         }
@@ -200,8 +238,10 @@ export function ChatMain({ conversationId, onConversationCreated }: ChatMainProp
       }
     });
 
-    EventsOn("tool-confirm", (data: { name: string; arguments: string }) => {
+    EventsOn("tool-confirm", (data: PendingConfirm) => {
       setPendingConfirm(data);
+      setEditedArgs(formatJSON(data.arguments));
+      setConfirmError("");
     });
 
     return () => {
@@ -210,10 +250,22 @@ export function ChatMain({ conversationId, onConversationCreated }: ChatMainProp
     };
   }, [onConversationCreated, scrollToBottom]);
 
-  const handleConfirm = useCallback((approved: boolean) => {
-    setPendingConfirm(null);
-    ConfirmTool(approved);
-  }, []);
+  const handleConfirm = useCallback(
+    async (approved: boolean) => {
+      let args = "";
+      if (approved && pendingConfirm) {
+        const edited = compactJSON(editedArgs);
+        if (edited !== compactJSON(pendingConfirm.arguments)) args = edited;
+      }
+      const err = await ConfirmTool(approved, args);
+      if (err) {
+        setConfirmError(err);
+        return;
+      }
+      setPendingConfirm(null);
+    },
+    [pendingConfirm, editedArgs]
+  );
 
   const handleSend = useCallback(
     async (text: string) => {
@@ -343,9 +395,16 @@ export function ChatMain({ conversationId, onConversationCreated }: ChatMainProp
                   <Badge variant="outline" className="text-[11px] font-mono border-destructive/25 text-destructive px-1.5 py-0">
                     {pendingConfirm.name}
                   </Badge>
-                  <pre className="mt-2 text-xs text-muted-foreground font-mono overflow-x-auto whitespace-pre-wrap max-h-32 leading-relaxed">
-                    {formatJSON(pendingConfirm.arguments)}
-                  </pre>
+                  {pendingConfirm.rule && (
+                    <span className="ml-2 text-[11px] text-muted-foreground font-mono">{pendingConfirm.rule}</span>
+                  )}
+                  <Textarea
+                    value={editedArgs}
+                    onChange={(e) => setEditedArgs(e.target.value)}
+                    spellCheck={false}
+                    className="mt-2 min-h-16 max-h-48 text-xs md:text-xs font-mono leading-relaxed"
+                  />
+                  {confirmError && <p className="mt-1.5 text-[11px] text-destructive">{confirmError}</p>}
                 </div>
                 <div className="flex gap-2">
                   <Button size="sm" variant="destructive" className="h-7 text-xs" onClick={() => handleConfirm(true)}>
@@ -426,3 +485,11 @@ function formatJSON(s: string): string {
     return s;
   }
 }
+
+function compactJSON(s: string): string {
+  try {
+    return JSON.stringify(JSON.parse(s));
+  } catch {
+    return s;
+  }
+}
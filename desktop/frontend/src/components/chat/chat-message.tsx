@@ -27,7 +27,14 @@ export function ChatMessage({ message, isLast, onEdit, onDelete, onRetry }: Chat
   }
 
   if (message.role === "tool_result") {
-    return <ToolResultMessage name={message.toolName} content={message.content} />;
+    return (
+      <ToolResultMessage
+        name={message.toolName}
+        content={message.content}
+        status={message.toolStatus}
+        durationMs={message.durationMs}
+      />
+    );
   }
 
   return <AssistantMessage content={message.content} isLast={isLast} onRetry={onRetry} onDelete={onDelete} />;
@@ -66,8 +73,19 @@ function ToolCallMessage({ name, args }: { name?: string; args?: string }) {
 }
 
 /* ── tool_result ── */
-function ToolResultMessage({ name, content }: { name?: string; content: string }) {
+function ToolResultMessage({
+  name,
+  content,
+  status,
+  durationMs,
+}: {
+  name?: string;
+  content: string;
+  status?: Message["toolStatus"];
+  durationMs?: number;
+}) {
   const [open, setOpen] = useState(false);
+  const failed = status === "error" || status === "denied";
   const preview = content.length > 100 ? content.slice(0, 100) + "\u2026" : content;
   const lines = content.split("\n").length;
   const size = content.length > 1024 ? `${(content.length / 1024).toFixed(1)}KB` : `${content.length}B`;
@@ -80,11 +98,16 @@ function ToolResultMessage({ name, content }: { name?: string; content: string }
         <div className="flex items-center gap-1.5 text-xs text-muted-foreground">
           {open ? (
             <ChevronDown className="w-3 h-3 text-emerald-500 dark:text-emerald-400" />
+          ) : failed ? (
+            <X className="w-3 h-3 text-red-500 dark:text-red-400" />
           ) : (
             <Check className="w-3 h-3 text-emerald-500 dark:text-emerald-400" />
           )}
           <span className="font-mono font-medium text-foreground/70">{name}</span>
           <span className="text-muted-foreground/30 text-[10px]">{size}</span>
+          {durationMs !== undefined && durationMs > 0 && (
+            <span className="text-muted-foreground/30 text-[10px]">{formatDuration(durationMs)}</span>
+          )}
           {!open && (
             <span className="text-muted-foreground/40 truncate flex-1 font-mono text-[11px]">
               {preview.replace(/\n/g, " ")}
@@ -338,3 +361,7 @@ function formatJSON(s: string): string {
     return s;
   }
 }
+
+function formatDuration(ms: number): string {
+  return ms < 1000 ? `${ms}ms` : `${(ms / 1000).toFixed(1)}s`;
+}
@@ -4,6 +4,9 @@ export interface Message {
   content: string;
   toolName?: string;
   toolArgs?: string;
+  toolCallId?: string;
+  toolStatus?: "success" | "error" | "denied" | "skipped" | "preview";
+  durationMs?: number;
   timestamp: Date;
   ephemeral?: boolean;
 }
@@ -8,7 +8,7 @@ export function AddTarget(arg1:string,arg2:string,arg3:string,arg4:string,arg5:s
 
 export function Chat(arg1:string,arg2:string):Promise<string>;
 
-export function ConfirmTool(arg1:boolean):Promise<void>;
+export function ConfirmTool(arg1:boolean,arg2:string):Promise<string>;
 
 export function CreateEmptyConversation():Promise<string>;
 
@@ -64,6 +64,8 @@ export function SaveProxy(arg1:string):Promise<string>;
 
 export function SaveStream(arg1:boolean):Promise<string>;
 
+export function SetDryRun(arg1:string,arg2:boolean):Promise<string>;
+
 export function SetWindowTitle(arg1:string):Promise<void>;
 
 export function StopChat():Promise<void>;
@@ -10,8 +10,8 @@ export function Chat(arg1, arg2) {
   return window['go']['main']['App']['Chat'](arg1, arg2);
 }
 
-export function ConfirmTool(arg1) {
-  return window['go']['main']['App']['ConfirmTool'](arg1);
+export function ConfirmTool(arg1, arg2) {
+  return window['go']['main']['App']['ConfirmTool'](arg1, arg2);
 }
 
 export function CreateEmptyConversation() {
@@ -122,6 +122,10 @@ export function SaveStream(arg1) {
   return window['go']['main']['App']['SaveStream'](arg1);
 }
 
+export function SetDryRun(arg1, arg2) {
+  return window['go']['main']['App']['SetDryRun'](arg1, arg2);
+}
+
 export function SetWindowTitle(arg1) {
   return window['go']['main']['App']['SetWindowTitle'](arg1);
 }
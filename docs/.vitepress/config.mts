@@ -44,6 +44,7 @@ export default defineConfig({
               items: [
                 { text: 'API Endpoints', link: '/guide/api' },
                 { text: 'SSE Events', link: '/guide/sse-events' },
+                { text: 'Testing', link: '/guide/testing' },
               ],
             },
             {
@@ -97,6 +98,7 @@ export default defineConfig({
               items: [
                 { text: 'API 端点', link: '/zh/guide/api' },
                 { text: 'SSE 事件', link: '/zh/guide/sse-events' },
+                { text: '测试', link: '/zh/guide/testing' },
               ],
             },
             {
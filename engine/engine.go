@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/core/conversation"
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/policy"
+	"github.com/ZacharyZcR/NLUI/core/pricing"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
 	"github.com/ZacharyZcR/NLUI/core/toolloop"
 )
 
@@ -14,18 +18,56 @@ import (
 type Event = toolloop.Event
 type Executor = toolloop.Executor
 type ConfirmFunc = toolloop.ConfirmFunc
+type ConfirmRequest = toolloop.ConfirmRequest
+type ConfirmResponse = toolloop.ConfirmResponse
+type ArgProblem = toolloop.ArgProblem
+type Middleware = toolloop.Middleware
+type ExecutorFunc = toolloop.ExecutorFunc
 type Tool = llm.Tool
 type Message = llm.Message
+type ContentPart = llm.ContentPart
 type Conversation = conversation.Conversation
+type UsageReport = conversation.UsageReport
+type Budget = toolloop.Budget
+type ConversationBudget = conversation.Budget
+
+// WithDryRun returns a context whose turns preview tool calls instead of
+// running them, whatever the conversation's setting.
+func WithDryRun(ctx context.Context) context.Context {
+	return toolloop.WithDryRun(ctx)
+}
+
+// RewriteArgs returns a middleware that rewrites tool arguments before the call.
+func RewriteArgs(fn func(ctx context.Context, toolName, argsJSON string) (string, error)) Middleware {
+	return toolloop.RewriteArgs(fn)
+}
+
+// RewriteResult returns a middleware that rewrites tool results after the call.
+func RewriteResult(fn func(ctx context.Context, toolName, argsJSON, result string, err error) (string, error)) Middleware {
+	return toolloop.RewriteResult(fn)
+}
 
 type Config struct {
-	LLM          llm.LLMClient
-	Executor     Executor
-	Tools        []Tool
-	SystemPrompt string
-	MaxCtxTokens int
-	ConvDir      string                // "" = in-memory only
-	ConvMgr      *conversation.Manager // optional, reuse across reinit
+	LLM           llm.LLMClient
+	Executor      Executor
+	Tools         []Tool
+	SystemPrompt  string
+	MaxCtxTokens  int
+	Compact       bool                  // summarize history beyond MaxCtxTokens instead of dropping it
+	TokenCounter  tokenizer.Counter     // nil = script-aware estimate
+	Model         string                // configured model, for pricing
+	Prices        *pricing.Table        // nil = usage events carry no cost
+	ToolWorkers   int                   // concurrent tool calls per message; 0 = default, 1 = sequential
+	ResultLimit   int                   // bytes of a tool result the model sees; 0 = default
+	ResultLimits  map[string]int        // per tool name or glob, overriding ResultLimit
+	LoopThreshold int                   // repeated calls that count as a loop; 0 = default, negative = off
+	RetrieveTools int                   // tools sent per turn when there are more, plus search_tools; 0 = all
+	Embedder      llm.Embedder          // nil = rank tools by keywords only
+	Budget        Budget                // per-turn limits; conversations may override them
+	Policy        *policy.Policy        // nil = built-in rules
+	Middleware    []Middleware          // wraps every tool call; the first runs outermost
+	ConvDir       string                // "" = in-memory only
+	ConvMgr       *conversation.Manager // optional, reuse across reinit
 }
 
 type Engine struct {
@@ -38,6 +80,16 @@ type Engine struct {
 func New(cfg Config) *Engine {
 	loop := toolloop.New(cfg.LLM, cfg.Executor)
 	loop.SetMaxContextTokens(cfg.MaxCtxTokens)
+	loop.SetCompaction(cfg.Compact)
+	loop.SetTokenCounter(cfg.TokenCounter)
+	loop.SetPricing(cfg.Prices, cfg.Model)
+	loop.SetToolConcurrency(cfg.ToolWorkers)
+	loop.SetResultLimits(cfg.ResultLimit, cfg.ResultLimits)
+	loop.SetLoopThreshold(cfg.LoopThreshold)
+	loop.SetToolRetrieval(cfg.RetrieveTools, cfg.Embedder)
+	loop.SetBudget(cfg.Budget)
+	loop.SetPolicy(cfg.Policy)
+	loop.Use(cfg.Middleware...)
 
 	convMgr := cfg.ConvMgr
 	if convMgr == nil {
@@ -55,16 +107,24 @@ func New(cfg Config) *Engine {
 // Chat runs a full chat turn: get/create conversation → append user msg → loop → update messages.
 // Returns the conversation ID used.
 func (e *Engine) Chat(ctx context.Context, convID, message, authToken string, confirm ConfirmFunc, onEvent func(Event)) (string, error) {
+	return e.ChatWithAttachments(ctx, convID, message, nil, authToken, confirm, onEvent)
+}
+
+// ChatWithAttachments is Chat with images or files attached to the user message.
+func (e *Engine) ChatWithAttachments(ctx context.Context, convID, message string, parts []ContentPart, authToken string, confirm ConfirmFunc, onEvent func(Event)) (string, error) {
 	conv := e.convMgr.Get(convID)
 	isNew := conv == nil
 	if isNew {
 		conv = e.convMgr.Create("", e.systemPrompt)
 	}
 
-	conv.Messages = append(conv.Messages, llm.Message{Role: "user", Content: message})
+	conv.Messages = append(conv.Messages, llm.Message{Role: "user", Content: message, Parts: parts})
 
 	if isNew {
 		title := message
+		if title == "" && len(parts) > 0 {
+			title = attachmentTitle(parts[0])
+		}
 		if len([]rune(title)) > 30 {
 			title = string([]rune(title)[:30]) + "..."
 		}
@@ -74,7 +134,7 @@ func (e *Engine) Chat(ctx context.Context, convID, message, authToken string, co
 	// Filter tools based on conversation config
 	enabledTools := e.filterTools(conv)
 
-	finalMessages, err := e.loop.Run(ctx, conv.Messages, enabledTools, authToken, confirm, onEvent)
+	finalMessages, err := e.run(ctx, conv.ID, conv.Messages, enabledTools, authToken, confirm, onEvent)
 	if err != nil {
 		e.convMgr.UpdateMessages(conv.ID, finalMessages)
 		return conv.ID, fmt.Errorf("chat: %w", err)
@@ -84,6 +144,60 @@ func (e *Engine) Chat(ctx context.Context, convID, message, authToken string, co
 	return conv.ID, nil
 }
 
+// run executes the tool loop under the conversation's budget and history
+// summary, recording the turn's usage and new summaries on the conversation.
+func (e *Engine) run(ctx context.Context, convID string, messages []Message, tools []Tool, authToken string, confirm ConfirmFunc, onEvent func(Event)) ([]Message, error) {
+	if conv := e.convMgr.Get(convID); conv != nil {
+		if conv.Budget != nil {
+			ctx = toolloop.WithBudget(ctx, Budget{
+				MaxIterations: conv.Budget.MaxIterations,
+				MaxToolCalls:  conv.Budget.MaxToolCalls,
+				MaxTokens:     conv.Budget.MaxTokens,
+				MaxDuration:   time.Duration(conv.Budget.MaxDurationSec) * time.Second,
+			})
+		}
+		if conv.DryRun {
+			ctx = toolloop.WithDryRun(ctx)
+		}
+		if conv.Summary != nil {
+			ctx = toolloop.WithSummary(ctx, toolloop.Summary{Text: conv.Summary.Text, Until: conv.Summary.Until})
+		}
+	}
+	return e.loop.Run(ctx, messages, tools, authToken, confirm, func(ev Event) {
+		if c, ok := ev.Data.(toolloop.CompactedEvent); ok {
+			e.convMgr.SetSummary(convID, &conversation.Summary{
+				Text:      c.Summary,
+				Until:     c.Until,
+				UpdatedAt: time.Now(),
+			})
+		}
+		if u, ok := ev.Data.(toolloop.UsageEvent); ok {
+			e.convMgr.AddUsage(convID, conversation.UsageRecord{
+				Time:             time.Now(),
+				Model:            u.Model,
+				PromptTokens:     u.PromptTokens,
+				CompletionTokens: u.CompletionTokens,
+				TotalTokens:      u.TotalTokens,
+				Cost:             u.Cost,
+				Currency:         u.Currency,
+				Models:           modelUsage(u.Models),
+			})
+		}
+		onEvent(ev)
+	})
+}
+
+func modelUsage(models []toolloop.ModelUsage) []conversation.ModelUsage {
+	if len(models) < 2 {
+		return nil // the record's own model covers it
+	}
+	out := make([]conversation.ModelUsage, len(models))
+	for i, m := range models {
+		out[i] = conversation.ModelUsage(m)
+	}
+	return out
+}
+
 func (e *Engine) SetConfirm(fn ConfirmFunc) {
 	e.loop.SetConfirm(fn)
 }
@@ -96,6 +210,17 @@ func (e *Engine) Tools() []Tool {
 	return e.tools
 }
 
+// ValidateToolArguments checks arguments against the tool's schema, e.g. ones
+// an approver edited. Unknown tools accept any valid JSON.
+func (e *Engine) ValidateToolArguments(name, argsJSON string) []ArgProblem {
+	for _, t := range e.tools {
+		if t.Function.Name == name {
+			return toolloop.ValidateArguments(t, argsJSON)
+		}
+	}
+	return toolloop.ValidateArguments(Tool{}, argsJSON)
+}
+
 func (e *Engine) SystemPrompt() string {
 	return e.systemPrompt
 }
@@ -116,6 +241,11 @@ func (e *Engine) DeleteConversation(id string) {
 	e.convMgr.Delete(id)
 }
 
+// UsageReport sums token usage and cost of turns in [from, to); zero bounds are open.
+func (e *Engine) UsageReport(from, to time.Time) UsageReport {
+	return e.convMgr.UsageReport(from, to)
+}
+
 // EditMessageAndRegenerate edits a message and regenerates from that point.
 func (e *Engine) EditMessageAndRegenerate(ctx context.Context, convID string, msgIndex int, newContent, authToken string, confirm ConfirmFunc, onEvent func(Event)) error {
 	if err := e.convMgr.EditMessage(convID, msgIndex, newContent); err != nil {
@@ -126,7 +256,7 @@ func (e *Engine) EditMessageAndRegenerate(ctx context.Context, convID string, ms
 		return fmt.Errorf("conversation not found")
 	}
 	enabledTools := e.filterTools(conv)
-	finalMessages, err := e.loop.Run(ctx, conv.Messages, enabledTools, authToken, confirm, onEvent)
+	finalMessages, err := e.run(ctx, conv.ID, conv.Messages, enabledTools, authToken, confirm, onEvent)
 	e.convMgr.UpdateMessages(convID, finalMessages)
 	return err
 }
@@ -146,6 +276,16 @@ func (e *Engine) UpdateToolConfig(convID string, enabledSources, disabledTools [
 	return e.convMgr.UpdateToolConfig(convID, enabledSources, disabledTools)
 }
 
+// UpdateBudget overrides the engine's per-turn budget for a conversation; nil restores the default.
+func (e *Engine) UpdateBudget(convID string, budget *ConversationBudget) error {
+	return e.convMgr.UpdateBudget(convID, budget)
+}
+
+// UpdateDryRun makes the conversation's turns preview tool calls instead of running them.
+func (e *Engine) UpdateDryRun(convID string, on bool) error {
+	return e.convMgr.UpdateDryRun(convID, on)
+}
+
 // RegenerateFrom regenerates the conversation from a specific message index.
 // Useful for retrying after the last assistant message.
 func (e *Engine) RegenerateFrom(ctx context.Context, convID string, fromIndex int, authToken string, confirm ConfirmFunc, onEvent func(Event)) error {
@@ -157,8 +297,10 @@ func (e *Engine) RegenerateFrom(ctx context.Context, convID string, fromIndex in
 		return fmt.Errorf("invalid message index")
 	}
 	truncated := conv.Messages[:fromIndex]
+	// A summary of the discarded messages must not stand in for the new ones.
+	e.convMgr.DropSummaryFrom(convID, fromIndex)
 	enabledTools := e.filterTools(conv)
-	finalMessages, err := e.loop.Run(ctx, truncated, enabledTools, authToken, confirm, onEvent)
+	finalMessages, err := e.run(ctx, convID, truncated, enabledTools, authToken, confirm, onEvent)
 	e.convMgr.UpdateMessages(convID, finalMessages)
 	return err
 }
@@ -207,6 +349,17 @@ func (e *Engine) filterTools(conv *Conversation) []Tool {
 	return result
 }
 
+// attachmentTitle names a conversation that starts with an attachment only.
+func attachmentTitle(p ContentPart) string {
+	if p.File != nil && p.File.Filename != "" {
+		return p.File.Filename
+	}
+	if p.Type == "image_url" {
+		return "Image"
+	}
+	return "Attachment"
+}
+
 // extractSource returns the source name from a tool name (e.g., "mcp__tool" -> "mcp")
 func extractSource(toolName string) string {
 	if idx := strings.Index(toolName, "__"); idx > 0 {
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/conversation"
+	"github.com/ZacharyZcR/NLUI/core/llm"
+)
+
+// recordingLLM answers "ok" and keeps the messages of the last request.
+type recordingLLM struct {
+	last []llm.Message
+}
+
+func (r *recordingLLM) ChatStreamWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool, onDelta, onReasoning func(string)) (*llm.Message, *llm.Usage, error) {
+	r.last = append([]llm.Message(nil), messages...)
+	return &llm.Message{Role: "assistant", Content: "ok"}, nil, nil
+}
+
+func TestRegenerateBelowSummaryDropsIt(t *testing.T) {
+	client := &recordingLLM{}
+	e := New(Config{LLM: client, SystemPrompt: "sys"})
+	conv := e.convMgr.Create("", "sys")
+	e.convMgr.UpdateMessages(conv.ID, append(conv.Messages,
+		llm.Message{Role: "user", Content: "first"},
+		llm.Message{Role: "assistant", Content: "one"},
+		llm.Message{Role: "user", Content: "second"},
+		llm.Message{Role: "assistant", Content: "two"},
+		llm.Message{Role: "user", Content: "third"},
+		llm.Message{Role: "assistant", Content: "three"},
+	))
+	e.convMgr.SetSummary(conv.ID, &conversation.Summary{Text: "STALE SUMMARY", Until: 5})
+
+	// Regenerate the answer to "second", which the summary covers.
+	if err := e.RegenerateFrom(context.Background(), conv.ID, 4, "", nil, func(Event) {}); err != nil {
+		t.Fatalf("RegenerateFrom: %v", err)
+	}
+
+	var sent []string
+	for _, m := range client.last {
+		sent = append(sent, m.Content)
+	}
+	joined := strings.Join(sent, "|")
+	if strings.Contains(joined, "STALE SUMMARY") || !strings.Contains(joined, "second") {
+		t.Errorf("model saw %q", joined)
+	}
+	if got := e.convMgr.Get(conv.ID); got.Summary != nil || len(got.Messages) != 5 {
+		t.Errorf("summary = %+v, %d messages", got.Summary, len(got.Messages))
+	}
+}
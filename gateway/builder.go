@@ -3,7 +3,9 @@ package gateway
 import (
 	"fmt"
 	"hash/fnv"
+	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/ZacharyZcR/NLUI/core/llm"
@@ -46,6 +48,7 @@ type Endpoint struct {
 	Auth              AuthConfig
 	Params            []ParamInfo
 	HasBody           bool
+	BodyType          string // Media type of the request body; "" means JSON
 }
 
 type ParamInfo struct {
@@ -63,6 +66,8 @@ func BuildTools(doc *openapi3.T, targetName, baseURL string, auth AuthConfig) ([
 		return tools, endpoints
 	}
 
+	prefix := serverPathPrefix(doc, baseURL)
+
 	for path, pathItem := range doc.Paths.Map() {
 		for method, op := range pathItem.Operations() {
 			if op == nil {
@@ -107,11 +112,12 @@ func BuildTools(doc *openapi3.T, targetName, baseURL string, auth AuthConfig) ([
 				TargetDisplayName: targetName,      // Original name for display
 				BaseURL:           baseURL,
 				Method:            strings.ToUpper(method),
-				Path:              path,
+				Path:              prefix + path,
 				Group:             deriveGroup(op, path),
 				Auth:              auth,
 				Params:            paramInfos,
 				HasBody:           op.RequestBody != nil,
+				BodyType:          bodyType(op),
 			}
 
 			tools = append(tools, tool)
@@ -190,7 +196,7 @@ func buildParams(op *openapi3.Operation) (map[string]interface{}, []ParamInfo) {
 			prop["description"] = fmt.Sprintf("%s (%s)", p.Description, p.In)
 		}
 		properties[p.Name] = prop
-		if p.Required {
+		if p.Required || p.In == "path" { // path parameters are always required
 			required = append(required, p.Name)
 		}
 		paramType := "string"
@@ -203,14 +209,13 @@ func buildParams(op *openapi3.Operation) (map[string]interface{}, []ParamInfo) {
 	}
 
 	if op.RequestBody != nil && op.RequestBody.Value != nil {
-		for _, mediaType := range op.RequestBody.Value.Content {
+		if mediaType := op.RequestBody.Value.Content[bodyMediaType(op.RequestBody.Value.Content)]; mediaType != nil {
 			if mediaType.Schema != nil && mediaType.Schema.Value != nil {
 				properties["body"] = schemaToMap(mediaType.Schema.Value)
 				if op.RequestBody.Value.Required {
 					required = append(required, "body")
 				}
 			}
-			break
 		}
 	}
 
@@ -225,6 +230,59 @@ func buildParams(op *openapi3.Operation) (map[string]interface{}, []ParamInfo) {
 	return schema, paramInfos
 }
 
+// bodyMediaType picks the media type a request body is sent as: JSON when
+// offered, then URL-encoded and multipart forms, then whatever comes first.
+func bodyMediaType(content openapi3.Content) string {
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, want := range []func(string) bool{
+		func(t string) bool { return t == "application/json" || strings.HasSuffix(t, "+json") },
+		func(t string) bool { return t == formURLEncoded },
+		func(t string) bool { return t == multipartForm },
+	} {
+		for _, t := range types {
+			if want(strings.ToLower(t)) {
+				return t
+			}
+		}
+	}
+	if len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// bodyType is the Endpoint.BodyType of op: a form encoding, or "" for JSON.
+func bodyType(op *openapi3.Operation) string {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return ""
+	}
+	switch t := strings.ToLower(bodyMediaType(op.RequestBody.Value.Content)); t {
+	case formURLEncoded, multipartForm:
+		return t
+	}
+	return ""
+}
+
+// serverPathPrefix returns the basePath of a converted Swagger 2.0 spec,
+// which operation paths are relative to. It is empty for OpenAPI 3 specs,
+// whose base_url already includes any server path, and when baseURL
+// already ends with the basePath.
+func serverPathPrefix(doc *openapi3.T, baseURL string) string {
+	basePath, _ := doc.Extensions[basePathExtension].(string)
+	prefix := strings.TrimRight(basePath, "/")
+	if prefix == "" {
+		return ""
+	}
+	if base, err := url.Parse(baseURL); err == nil && strings.HasSuffix(strings.TrimRight(base.Path, "/"), prefix) {
+		return ""
+	}
+	return prefix
+}
+
 func schemaToMap(s *openapi3.Schema) map[string]interface{} {
 	if s == nil {
 		return map[string]interface{}{"type": "string"}
@@ -314,6 +372,7 @@ func BuildToolSet(targetName, baseURL string, auth AuthConfig, tools []llm.Tool,
 			Group:       ep.Group,
 			Params:      params,
 			HasBody:     ep.HasBody,
+			BodyType:    ep.BodyType,
 			Parameters:  parameters,
 		})
 	}
@@ -152,3 +152,15 @@ func TestGenerateOpID(t *testing.T) {
 		t.Errorf("got %q", got)
 	}
 }
+
+func TestBuildParamsRequiresPathParams(t *testing.T) {
+	op := &openapi3.Operation{Parameters: openapi3.Parameters{
+		{Value: &openapi3.Parameter{Name: "id", In: "path"}}, // spec forgot required: true
+		{Value: &openapi3.Parameter{Name: "limit", In: "query"}},
+	}}
+	schema, _ := buildParams(op)
+	required, _ := schema["required"].([]string)
+	if len(required) != 1 || required[0] != "id" {
+		t.Errorf("required = %v, want [id]", required)
+	}
+}
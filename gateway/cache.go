@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
+)
+
+// responseCache keeps GET and HEAD results for the rest of a turn, up to a
+// TTL, so a model re-fetching the same resource doesn't hit the API again.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[cacheKey]cacheEntry
+	gens    map[string]uint64 // per target, bumped by invalidate
+}
+
+type cacheKey struct {
+	turn     uint64
+	method   string
+	url      string
+	identity string // hash of the request headers and cookies, which carry auth
+}
+
+type cacheEntry struct {
+	target  string
+	status  int
+	result  string
+	expires time.Time
+}
+
+// SetCacheTTL caches successful GET and HEAD results within a chat turn for
+// up to ttl. Any other method sent to a target drops its cached results.
+// Calls outside a turn are never cached; ttl <= 0 turns caching off.
+func (c *Caller) SetCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		c.cache = nil
+		return
+	}
+	c.cache = &responseCache{ttl: ttl, entries: make(map[cacheKey]cacheEntry), gens: make(map[string]uint64)}
+}
+
+// key returns the cache key of req, or false when req must not be cached.
+// Cookies the jar will add to req count towards its identity.
+func (rc *responseCache) key(ctx context.Context, req *http.Request, jar http.CookieJar) (cacheKey, bool) {
+	if rc == nil || !readOnly(req.Method) {
+		return cacheKey{}, false
+	}
+	turn := toolctx.TurnID(ctx)
+	if turn == 0 {
+		return cacheKey{}, false
+	}
+	var cookies []*http.Cookie
+	if jar != nil {
+		cookies = jar.Cookies(req.URL)
+	}
+	return cacheKey{turn: turn, method: req.Method, url: req.URL.String(), identity: identityHash(req.Header, cookies)}, true
+}
+
+// generation returns the invalidation count of target. A read takes it
+// before sending and passes it to put, so a result fetched while a write
+// was under way is not stored.
+func (rc *responseCache) generation(target string) uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.gens[target]
+}
+
+func (rc *responseCache) get(key cacheKey) (cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+func (rc *responseCache) put(key cacheKey, gen uint64, target string, status int, result string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.gens[target] != gen {
+		return
+	}
+	now := time.Now()
+	for k, e := range rc.entries {
+		if now.After(e.expires) {
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = cacheEntry{target: target, status: status, result: result, expires: now.Add(rc.ttl)}
+}
+
+// invalidate drops every cached result of target, in all turns, and stops
+// reads already in flight from storing theirs.
+func (rc *responseCache) invalidate(target string) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.gens[target]++
+	for k, e := range rc.entries {
+		if e.target == target {
+			delete(rc.entries, k)
+		}
+	}
+}
+
+func readOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+func identityHash(h http.Header, cookies []*http.Cookie) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sum := sha256.New()
+	for _, name := range names {
+		for _, v := range h[name] {
+			sum.Write([]byte(name + ": " + v + "\n"))
+		}
+	}
+	for _, ck := range cookies {
+		sum.Write([]byte("Cookie: " + ck.Name + "=" + ck.Value + "\n"))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
@@ -6,13 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/policy"
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
 )
 
 type Caller struct {
@@ -21,6 +25,8 @@ type Caller struct {
 	healthCache    map[string]time.Time
 	healthCacheMu  sync.RWMutex
 	healthCacheTTL time.Duration
+	authMu         sync.RWMutex                   // guards Endpoint.Auth; set_auth may run alongside other calls
+	cache          *responseCache                 // nil = no caching; see SetCacheTTL
 	OnAuthChanged  func(configName, token string) // called after set_auth to persist token
 }
 
@@ -59,6 +65,8 @@ type TargetAuthStatus struct {
 }
 
 func (c *Caller) AuthStatus() []TargetAuthStatus {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
 	seen := make(map[string]*TargetAuthStatus)
 	for _, ep := range c.endpoints {
 		if ep.Auth.Type == "" {
@@ -88,6 +96,32 @@ func (c *Caller) AuthStatus() []TargetAuthStatus {
 	return result
 }
 
+// DescribeTool reports the target, method and path behind a tool for policy
+// matching. set_auth tools only carry their target.
+func (c *Caller) DescribeTool(name string) policy.ToolInfo {
+	info := policy.ToolInfo{Name: name}
+	if ep, ok := c.endpoints[name]; ok {
+		info.Target = ep.TargetDisplayName
+		if info.Target == "" {
+			info.Target = ep.TargetName
+		}
+		info.Method = ep.Method
+		info.Path = ep.Path
+	} else if strings.HasSuffix(name, "__set_auth") {
+		info.Target = c.targetDisplayName(strings.TrimSuffix(name, "__set_auth"))
+	}
+	return info
+}
+
+func (c *Caller) targetDisplayName(targetName string) string {
+	for _, ep := range c.endpoints {
+		if ep.TargetName == targetName && ep.TargetDisplayName != "" {
+			return ep.TargetDisplayName
+		}
+	}
+	return targetName
+}
+
 func (c *Caller) HasTool(name string) bool {
 	if _, ok := c.endpoints[name]; ok {
 		return true
@@ -107,15 +141,158 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 		return "", fmt.Errorf("unknown tool: %s", toolName)
 	}
 
+	req, _, err := c.newRequest(ctx, ep, argsJSON, authToken)
+	if err != nil {
+		return "", err
+	}
+
+	key, cacheable := c.cache.key(ctx, req, c.httpClient.Jar)
+	var gen uint64
+	if cacheable {
+		if e, ok := c.cache.get(key); ok {
+			if r := toolctx.ReportFrom(ctx); r != nil {
+				r.Cached, r.HTTPStatus = true, e.status
+			}
+			return e.result, nil
+		}
+		gen = c.cache.generation(ep.TargetName)
+	}
+	if !readOnly(req.Method) {
+		// Writes may change what earlier reads returned, including reads
+		// that overlap with the write; drop them again once it is done.
+		c.cache.invalidate(ep.TargetName)
+		defer c.cache.invalidate(ep.TargetName)
+	}
+
 	// Check target server reachability
 	if err := c.checkHealth(ep.BaseURL); err != nil {
 		return "", fmt.Errorf("target server unreachable (%s): %w", ep.BaseURL, err)
 	}
 
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if r := toolctx.ReportFrom(ctx); r != nil {
+		r.HTTPStatus = resp.StatusCode
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(respBody)), nil
+	}
+	if cacheable {
+		c.cache.put(key, gen, ep.TargetName, resp.StatusCode, string(respBody))
+	}
+	return string(respBody), nil
+}
+
+type headersKey struct{}
+
+// WithHeaders returns a context whose HTTP tool calls send extra headers,
+// e.g. a tracing or tenant header set by tool middleware. They are applied
+// last and win over header parameters and auth. Nested calls merge.
+func WithHeaders(ctx context.Context, h http.Header) context.Context {
+	merged := http.Header{}
+	if prev, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		for name, values := range prev {
+			merged[name] = values
+		}
+	}
+	for name, values := range h {
+		merged[http.CanonicalHeaderKey(name)] = values
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// RequestPreview is the request a tool call would send, with secrets masked.
+// Form bodies are given as a JSON string of the encoded form.
+type RequestPreview struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// maskedSecret replaces tokens and credentials in previews.
+const maskedSecret = "REDACTED"
+
+// Preview resolves a tool call into the request Execute would send, without
+// sending it or checking the target. set_auth calls return a nil preview.
+func (c *Caller) Preview(ctx context.Context, toolName, argsJSON, authToken string) (*RequestPreview, error) {
+	if strings.HasSuffix(toolName, "__set_auth") {
+		return nil, nil
+	}
+	ep, ok := c.endpoints[toolName]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool: %s", toolName)
+	}
+	req, body, err := c.newRequest(ctx, ep, argsJSON, authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	c.authMu.RLock()
+	auth := ep.Auth
+	c.authMu.RUnlock()
+	if auth.Type == "query" && auth.HeaderName != "" {
+		q := req.URL.Query()
+		if q.Has(auth.HeaderName) {
+			q.Set(auth.HeaderName, maskedSecret)
+			req.URL.RawQuery = q.Encode()
+		}
+	}
+
+	p := &RequestPreview{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: make(map[string]string, len(req.Header)),
+		Body:    body,
+	}
+	if ep.BodyType != "" && body != nil {
+		p.Body, _ = json.Marshal(string(body))
+	}
+	for name := range req.Header {
+		value := req.Header.Get(name)
+		switch {
+		case strings.EqualFold(name, "Authorization"):
+			if scheme, _, ok := strings.Cut(value, " "); ok {
+				value = scheme + " " + maskedSecret
+			} else {
+				value = maskedSecret
+			}
+		case strings.EqualFold(name, auth.HeaderName), isSecretHeader(name):
+			value = maskedSecret
+		}
+		p.Headers[name] = value
+	}
+	return p, nil
+}
+
+// isSecretHeader guesses whether a header carries credentials.
+func isSecretHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"auth", "token", "key", "secret", "cookie", "password", "session"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRequest resolves a tool call into the HTTP request for ep: path, query
+// and header parameters, JSON or form body and auth. The encoded body is
+// returned as well so previews don't have to drain the request.
+func (c *Caller) newRequest(ctx context.Context, ep *Endpoint, argsJSON, authToken string) (*http.Request, []byte, error) {
 	var args map[string]interface{}
 	if argsJSON != "" {
 		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
-			return "", fmt.Errorf("parse arguments: %w", err)
+			return nil, nil, fmt.Errorf("parse arguments: %w", err)
 		}
 	}
 
@@ -123,10 +300,12 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 	urlPath := ep.Path
 	for _, p := range ep.Params {
 		if p.In == "path" {
-			if val, ok := args[p.Name]; ok {
-				urlPath = strings.ReplaceAll(urlPath, "{"+p.Name+"}", url.PathEscape(fmt.Sprint(val)))
-				delete(args, p.Name)
+			val, ok := args[p.Name]
+			if !ok {
+				return nil, nil, fmt.Errorf("missing path parameter %q", p.Name)
 			}
+			urlPath = strings.ReplaceAll(urlPath, "{"+p.Name+"}", url.PathEscape(fmt.Sprint(val)))
+			delete(args, p.Name)
 		}
 	}
 	fullURL := strings.TrimRight(ep.BaseURL, "/") + urlPath
@@ -134,7 +313,7 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 	// Query parameters
 	reqURL, err := url.Parse(fullURL)
 	if err != nil {
-		return "", fmt.Errorf("parse url: %w", err)
+		return nil, nil, fmt.Errorf("parse url: %w", err)
 	}
 	q := reqURL.Query()
 	for _, p := range ep.Params {
@@ -148,30 +327,38 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 	reqURL.RawQuery = q.Encode()
 
 	// Request body
+	var body []byte
 	var bodyReader io.Reader
+	contentType := "application/json"
 	if ep.HasBody {
 		if bodyData, ok := args["body"]; ok {
-			bodyJSON, err := json.Marshal(bodyData)
-			if err != nil {
-				return "", fmt.Errorf("marshal body: %w", err)
+			if ep.BodyType != "" {
+				if body, contentType, err = encodeForm(ep.BodyType, bodyData); err != nil {
+					return nil, nil, err
+				}
+			} else if body, err = json.Marshal(bodyData); err != nil {
+				return nil, nil, fmt.Errorf("marshal body: %w", err)
 			}
-			bodyReader = bytes.NewReader(bodyJSON)
+			bodyReader = bytes.NewReader(body)
 		}
 	}
 
 	req, err := http.NewRequestWithContext(ctx, ep.Method, reqURL.String(), bodyReader)
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, nil, fmt.Errorf("create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
 
 	// Auth (client token takes precedence, fallback to configured default)
-	switch ep.Auth.Type {
+	c.authMu.RLock()
+	auth := ep.Auth
+	c.authMu.RUnlock()
+	switch auth.Type {
 	case "bearer":
 		token := authToken
 		if token == "" {
-			token = ep.Auth.Token
+			token = auth.Token
 		}
 		if token != "" {
 			req.Header.Set("Authorization", "Bearer "+token)
@@ -179,24 +366,21 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 	case "header":
 		token := authToken
 		if token == "" {
-			token = ep.Auth.Token
+			token = auth.Token
 		}
-		if token != "" && ep.Auth.HeaderName != "" {
-			req.Header.Set(ep.Auth.HeaderName, token)
+		if token != "" && auth.HeaderName != "" {
+			req.Header.Set(auth.HeaderName, token)
 		}
 	case "query":
 		token := authToken
 		if token == "" {
-			token = ep.Auth.Token
+			token = auth.Token
 		}
-		if token != "" && ep.Auth.HeaderName != "" {
+		if token != "" && auth.HeaderName != "" {
 			q := req.URL.Query()
-			q.Set(ep.Auth.HeaderName, token)
+			q.Set(auth.HeaderName, token)
 			req.URL.RawQuery = q.Encode()
 		}
-		log.Printf("[DEBUG] query auth: param=%s token_len=%d url=%s", ep.Auth.HeaderName, len(token), req.URL.String())
-	default:
-		log.Printf("[DEBUG] auth type=%q (no match)", ep.Auth.Type)
 	}
 
 	// Header parameters
@@ -208,21 +392,75 @@ func (c *Caller) Execute(ctx context.Context, toolName, argsJSON, authToken stri
 		}
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("execute request: %w", err)
+	// Headers injected by tool middleware
+	if extra, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		for name, values := range extra {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read response: %w", err)
+	return req, body, nil
+}
+
+// Form media types an Endpoint.BodyType can name.
+const (
+	formURLEncoded = "application/x-www-form-urlencoded"
+	multipartForm  = "multipart/form-data"
+)
+
+// encodeForm encodes a body object as a form of the given media type and
+// returns it with its Content-Type. Arrays become repeated fields; nested
+// objects are sent as JSON text.
+func encodeForm(mediaType string, body interface{}) ([]byte, string, error) {
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("body must be an object for %s", mediaType)
+	}
+	values := url.Values{}
+	for name, v := range fields {
+		items, isList := v.([]interface{})
+		if !isList {
+			items = []interface{}{v}
+		}
+		for _, item := range items {
+			values.Add(name, formValue(item))
+		}
+	}
+	if mediaType != multipartForm {
+		return []byte(values.Encode()), mediaType, nil
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(respBody)), nil
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
 	}
-	return string(respBody), nil
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range values[name] {
+			if err := w.WriteField(name, v); err != nil {
+				return nil, "", fmt.Errorf("encode form: %w", err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("encode form: %w", err)
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+func formValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
 }
 
 // checkHealth verifies that the target server is reachable.
@@ -267,6 +505,7 @@ func (c *Caller) setAuth(targetName, argsJSON string) (string, error) {
 		return "Error: token is required", nil
 	}
 
+	c.authMu.Lock()
 	count := 0
 	var actualType, actualHeader string
 	for _, ep := range c.endpoints {
@@ -286,6 +525,7 @@ func (c *Caller) setAuth(targetName, argsJSON string) (string, error) {
 		actualHeader = ep.Auth.HeaderName
 		count++
 	}
+	c.authMu.Unlock()
 
 	if count == 0 {
 		return fmt.Sprintf("No endpoints found for target %q", targetName), nil
@@ -0,0 +1,279 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
+)
+
+func TestPreviewMasksSecrets(t *testing.T) {
+	c := NewCaller(map[string]*Endpoint{
+		"petstore__updatePet": {
+			TargetName: "petstore",
+			BaseURL:    "https://pets.example.com/v1/",
+			Method:     "PUT",
+			Path:       "/pets/{id}",
+			Auth:       AuthConfig{Type: "bearer", Token: "s3cret"},
+			Params: []ParamInfo{
+				{Name: "id", In: "path", Required: true},
+				{Name: "notify", In: "query"},
+				{Name: "X-Api-Key", In: "header"},
+				{Name: "X-Request-Source", In: "header"},
+			},
+			HasBody: true,
+		},
+		"weather__forecast": {
+			TargetName: "weather",
+			BaseURL:    "https://weather.example.com",
+			Method:     "GET",
+			Path:       "/forecast",
+			Auth:       AuthConfig{Type: "query", HeaderName: "appid", Token: "s3cret"},
+			Params:     []ParamInfo{{Name: "q", In: "query"}},
+		},
+	})
+
+	p, err := c.Preview(context.Background(), "petstore__updatePet",
+		`{"id": 7, "notify": true, "X-Api-Key": "k", "X-Request-Source": "nlui", "body": {"name": "Rex"}}`, "")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if p.Method != "PUT" || p.URL != "https://pets.example.com/v1/pets/7?notify=true" || string(p.Body) != `{"name":"Rex"}` {
+		t.Errorf("preview = %+v", p)
+	}
+	for name, want := range map[string]string{
+		"Authorization":    "Bearer " + maskedSecret,
+		"X-Api-Key":        maskedSecret,
+		"X-Request-Source": "nlui",
+	} {
+		if got := p.Headers[name]; got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+
+	p, err = c.Preview(context.Background(), "weather__forecast", `{"q": "Paris"}`, "")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if p.URL != "https://weather.example.com/forecast?appid="+maskedSecret+"&q=Paris" {
+		t.Errorf("url = %s", p.URL)
+	}
+
+	if _, err := c.Preview(context.Background(), "petstore__updatePet", `{}`, ""); err == nil {
+		t.Error("missing path parameter should fail the preview")
+	}
+	if p, err := c.Preview(context.Background(), "petstore__set_auth", `{"token": "t"}`, ""); p != nil || err != nil {
+		t.Errorf("set_auth preview = %+v, %v", p, err)
+	}
+}
+
+func TestPreviewShowsEncodedFormBody(t *testing.T) {
+	c := NewCaller(map[string]*Endpoint{
+		"svc__login": {TargetName: "svc", BaseURL: "https://svc.example.com", Method: "POST", Path: "/login", HasBody: true, BodyType: formURLEncoded},
+	})
+	p, err := c.Preview(context.Background(), "svc__login", `{"body": {"user": "ann", "remember": true}}`, "")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if string(p.Body) != `"remember=true\u0026user=ann"` || p.Headers["Content-Type"] != formURLEncoded {
+		t.Errorf("preview body = %s, content type %q", p.Body, p.Headers["Content-Type"])
+	}
+	if _, err := json.Marshal(p); err != nil {
+		t.Errorf("marshal preview: %v", err)
+	}
+}
+
+func TestWithHeadersInjectsHeaders(t *testing.T) {
+	c := NewCaller(map[string]*Endpoint{
+		"svc__list": {TargetName: "svc", BaseURL: "https://svc.example.com", Method: "GET", Path: "/items"},
+	})
+	ctx := WithHeaders(context.Background(), http.Header{"x-tenant": {"acme"}})
+	ctx = WithHeaders(ctx, http.Header{"X-Trace-Id": {"t1"}})
+
+	p, err := c.Preview(ctx, "svc__list", "", "")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if p.Headers["X-Tenant"] != "acme" || p.Headers["X-Trace-Id"] != "t1" {
+		t.Errorf("headers = %v", p.Headers)
+	}
+}
+
+func TestCacheReusesReadsWithinTurn(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, `{"n":%d}`, n)
+	}))
+	defer srv.Close()
+
+	c := NewCaller(map[string]*Endpoint{
+		"svc__getItem":    {TargetName: "svc", BaseURL: srv.URL, Method: "GET", Auth: AuthConfig{Type: "bearer", Token: "t"}, Path: "/items/{id}", Params: []ParamInfo{{Name: "id", In: "path"}}},
+		"svc__updateItem": {TargetName: "svc", BaseURL: srv.URL, Method: "PUT", Path: "/items/{id}", Params: []ParamInfo{{Name: "id", In: "path"}}},
+		"svc__missing":    {TargetName: "svc", BaseURL: srv.URL, Method: "GET", Path: "/missing"},
+	})
+	c.SetCacheTTL(time.Minute)
+
+	turn := toolctx.WithTurn(context.Background())
+	call := func(ctx context.Context, tool, args, token string) (string, bool) {
+		t.Helper()
+		var r toolctx.CallReport
+		got, err := c.Execute(toolctx.WithReport(ctx, &r), tool, args, token)
+		if err != nil {
+			t.Fatalf("Execute %s: %v", tool, err)
+		}
+		return got, r.Cached
+	}
+
+	first, _ := call(turn, "svc__getItem", `{"id":1}`, "")
+	if got, cached := call(turn, "svc__getItem", `{"id":1}`, ""); got != first || !cached {
+		t.Errorf("repeat read = %s (cached %v), want %s from cache", got, cached, first)
+	}
+	if _, cached := call(turn, "svc__getItem", `{"id":2}`, ""); cached {
+		t.Error("other URL served from cache")
+	}
+	if _, cached := call(turn, "svc__getItem", `{"id":1}`, "other-user"); cached {
+		t.Error("other auth identity served from cache")
+	}
+	if _, cached := call(toolctx.WithTurn(context.Background()), "svc__getItem", `{"id":1}`, ""); cached {
+		t.Error("next turn served from cache")
+	}
+	if _, cached := call(context.Background(), "svc__getItem", `{"id":1}`, ""); cached {
+		t.Error("call outside a turn served from cache")
+	}
+
+	call(turn, "svc__updateItem", `{"id":9}`, "")
+	if _, cached := call(turn, "svc__getItem", `{"id":1}`, ""); cached {
+		t.Error("read after a write to the target served from cache")
+	}
+
+	call(turn, "svc__missing", "", "")
+	if _, cached := call(turn, "svc__missing", "", ""); cached {
+		t.Error("error response served from cache")
+	}
+}
+
+func TestExecuteReportsHTTPStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"error":"exists"}`)
+	}))
+	defer srv.Close()
+
+	c := NewCaller(map[string]*Endpoint{
+		"svc__create": {TargetName: "svc", BaseURL: srv.URL, Method: "POST", Path: "/items"},
+	})
+	var r toolctx.CallReport
+	got, err := c.Execute(toolctx.WithReport(context.Background(), &r), "svc__create", "", "")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if r.HTTPStatus != http.StatusConflict || got != `HTTP 409: {"error":"exists"}` {
+		t.Errorf("status = %d, result = %s", r.HTTPStatus, got)
+	}
+}
+
+func TestExecuteEncodesFormBodies(t *testing.T) {
+	var contentType, tags string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			_ = r.ParseForm()
+		}
+		tags = fmt.Sprintf("%v %s %s", r.PostForm["tag"], r.PostForm.Get("user"), r.PostForm.Get("meta"))
+	}))
+	defer srv.Close()
+
+	c := NewCaller(map[string]*Endpoint{
+		"svc__login":  {TargetName: "svc", BaseURL: srv.URL, Method: "POST", Path: "/login", HasBody: true, BodyType: formURLEncoded},
+		"svc__upload": {TargetName: "svc", BaseURL: srv.URL, Method: "POST", Path: "/upload", HasBody: true, BodyType: multipartForm},
+	})
+	args := `{"body":{"user":"rex","tag":["a","b"],"meta":{"k":1}}}`
+
+	if _, err := c.Execute(context.Background(), "svc__login", args, ""); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if contentType != formURLEncoded || tags != `[a b] rex {"k":1}` {
+		t.Errorf("urlencoded: content type %q, fields %s", contentType, tags)
+	}
+
+	if _, err := c.Execute(context.Background(), "svc__upload", args, ""); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.HasPrefix(contentType, multipartForm+"; boundary=") || tags != `[a b] rex {"k":1}` {
+		t.Errorf("multipart: content type %q, fields %s", contentType, tags)
+	}
+}
+
+func TestCacheSkipsReadsOverlappingWrite(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var reads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && reads.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	c := NewCaller(map[string]*Endpoint{
+		"svc__getItem":    {TargetName: "svc", BaseURL: srv.URL, Method: "GET", Path: "/items/1"},
+		"svc__updateItem": {TargetName: "svc", BaseURL: srv.URL, Method: "PUT", Path: "/items/1"},
+	})
+	c.SetCacheTTL(time.Minute)
+	turn := toolctx.WithTurn(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Execute(turn, "svc__getItem", "", "")
+	}()
+	<-started
+	if _, err := c.Execute(turn, "svc__updateItem", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+
+	var r toolctx.CallReport
+	c.Execute(toolctx.WithReport(turn, &r), "svc__getItem", "", "")
+	if r.Cached {
+		t.Error("read that overlapped a write was cached")
+	}
+}
+
+func TestCacheKeysOnJarCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "alice", Path: "/"})
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	c := NewCaller(map[string]*Endpoint{
+		"svc__me":     {TargetName: "svc", BaseURL: srv.URL, Method: "GET", Path: "/me"},
+		"auth__login": {TargetName: "auth", BaseURL: srv.URL, Method: "POST", Path: "/login"},
+	})
+	c.SetCacheTTL(time.Minute)
+	turn := toolctx.WithTurn(context.Background())
+
+	c.Execute(turn, "svc__me", "", "")
+	c.Execute(turn, "auth__login", "", "")
+	var r toolctx.CallReport
+	c.Execute(toolctx.WithReport(turn, &r), "svc__me", "", "")
+	if r.Cached {
+		t.Error("read with a new session cookie served from cache")
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
 )
 
 // Common spec paths to probe when no explicit spec is configured.
@@ -86,19 +89,54 @@ var htmlProbePaths = []string{
 	"/api-docs/swagger-config",
 }
 
-// LoadSpec loads an OpenAPI spec from a file path or URL.
-func LoadSpec(specPath string) (*openapi3.T, error) {
-	loader := openapi3.NewLoader()
-	loader.IsExternalRefsAllowed = false
+// maxSpecSize caps how much of a fetched spec is read.
+const maxSpecSize = 32 << 20
 
+// LoadSpec loads an OpenAPI 3 spec from a file path or URL. Swagger 2.0
+// documents are converted to OpenAPI 3.
+func LoadSpec(specPath string) (*openapi3.T, error) {
 	if strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://") {
 		uri, err := url.Parse(specPath)
 		if err != nil {
 			return nil, err
 		}
-		return loader.LoadFromURI(uri)
+		data, err := fetchSpec(&http.Client{Timeout: 30 * time.Second}, uri)
+		if err != nil {
+			return nil, err
+		}
+		return parseSpec(data, uri)
 	}
-	return loader.LoadFromFile(specPath)
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpec(data, &url.URL{Path: filepath.ToSlash(specPath)})
+}
+
+func fetchSpec(client *http.Client, uri *url.URL) ([]byte, error) {
+	resp, err := client.Get(uri.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: HTTP %d", uri, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSpecSize))
+}
+
+// parseSpec parses an OpenAPI 3 or Swagger 2.0 document (JSON or YAML)
+// served from location.
+func parseSpec(data []byte, location *url.URL) (*openapi3.T, error) {
+	var version struct {
+		Swagger string `json:"swagger"`
+	}
+	if err := yaml.Unmarshal(data, &version); err == nil && strings.HasPrefix(version.Swagger, "2") {
+		return convertSwagger2(data, location)
+	}
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = false
+	return loader.LoadFromDataWithPath(data, location)
 }
 
 // probeResult holds the outcome of a single probe attempt.
@@ -229,14 +267,14 @@ func discoverFromHTML(client *http.Client, baseURL string) (*openapi3.T, string,
 	return nil, "", false
 }
 
-// tryLoadSpec fetches a URL and attempts to parse it as an OpenAPI spec.
-// It checks Content-Type to avoid parsing HTML as JSON.
+// tryLoadSpec fetches a URL and attempts to parse it as an OpenAPI or
+// Swagger 2.0 spec. It checks Content-Type to avoid parsing HTML as JSON.
 func tryLoadSpec(client *http.Client, specURL string) (*openapi3.T, bool) {
 	resp, err := client.Get(specURL)
 	if err != nil {
 		return nil, false
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return nil, false
 	}
@@ -246,10 +284,12 @@ func tryLoadSpec(client *http.Client, specURL string) (*openapi3.T, bool) {
 		return nil, false
 	}
 
-	loader := openapi3.NewLoader()
-	loader.IsExternalRefsAllowed = false
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecSize))
+	if err != nil {
+		return nil, false
+	}
 	uri, _ := url.Parse(specURL)
-	doc, err := loader.LoadFromURI(uri)
+	doc, err := parseSpec(data, uri)
 	if err != nil {
 		return nil, false
 	}
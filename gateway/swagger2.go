@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+)
+
+// basePathExtension marks a converted document with its Swagger 2.0
+// basePath.
+const basePathExtension = "x-nlui-base-path"
+
+// convertSwagger2 parses a Swagger 2.0 document and converts it to OpenAPI 3.
+// body and formData parameters become request bodies typed by consumes,
+// host and basePath become a server, and securityDefinitions become
+// security schemes.
+func convertSwagger2(data []byte, location *url.URL) (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(data, &doc2); err != nil {
+		return nil, fmt.Errorf("parse swagger 2.0: %w", err)
+	}
+	normalizeHost(&doc2)
+	for _, item := range doc2.Paths {
+		if item == nil {
+			continue
+		}
+		for _, op := range item.Operations() {
+			op.Parameters = mergeParams(item.Parameters, op.Parameters)
+			if len(op.Consumes) == 0 && len(doc2.Consumes) == 0 {
+				op.Consumes = defaultConsumes(&doc2, op.Parameters)
+			}
+		}
+		item.Parameters = nil
+	}
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = false
+	doc3, err := openapi2conv.ToV3WithLoader(&doc2, loader, location)
+	if err != nil {
+		return nil, fmt.Errorf("convert swagger 2.0: %w", err)
+	}
+
+	// Swagger 2.0 paths are relative to basePath, which the converter only
+	// keeps inside a server URL (and drops without a host). Record it so
+	// BuildTools can prefix paths with it.
+	if doc2.BasePath != "" && doc2.BasePath != "/" {
+		if doc3.Extensions == nil {
+			doc3.Extensions = map[string]interface{}{}
+		}
+		doc3.Extensions[basePathExtension] = doc2.BasePath
+	}
+
+	// Swagger 2.0 has no bearer scheme; an API key sent in the
+	// Authorization header is how specs declare one.
+	if doc3.Components != nil {
+		for _, ref := range doc3.Components.SecuritySchemes {
+			s := ref.Value
+			if s != nil && s.Type == "apiKey" && s.In == "header" && strings.EqualFold(s.Name, "Authorization") {
+				s.Type, s.Scheme, s.In, s.Name = "http", "bearer", "", ""
+			}
+		}
+	}
+	return doc3, nil
+}
+
+// normalizeHost fixes hosts written with a scheme or path, which the
+// converter rejects: the scheme moves to schemes, the path to basePath.
+func normalizeHost(doc *openapi2.T) {
+	host := doc.Host
+	if host == "" {
+		return
+	}
+	if i := strings.Index(host, "://"); i >= 0 {
+		if len(doc.Schemes) == 0 {
+			doc.Schemes = []string{host[:i]}
+		}
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		if p := strings.Trim(host[i:], "/"); p != "" {
+			doc.BasePath = path.Join("/", p, doc.BasePath)
+		}
+		host = host[:i]
+	}
+	doc.Host = host
+}
+
+// mergeParams adds path-level parameters to an operation's own, which
+// override them by name and location.
+func mergeParams(shared, own openapi2.Parameters) openapi2.Parameters {
+	if len(shared) == 0 {
+		return own
+	}
+	merged := append(openapi2.Parameters{}, own...)
+	for _, p := range shared {
+		overridden := false
+		for _, o := range own {
+			if (o.Ref == "" && o.Name == p.Name && o.In == p.In) || (o.Ref != "" && o.Ref == p.Ref) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// defaultConsumes picks a media type for operations that declare none:
+// JSON for a body parameter, a form encoding for formData parameters.
+func defaultConsumes(doc *openapi2.T, params openapi2.Parameters) []string {
+	consumes := []string(nil)
+	for _, p := range params {
+		if p.Ref != "" {
+			p = doc.Parameters[strings.TrimPrefix(p.Ref, "#/parameters/")]
+			if p == nil {
+				continue
+			}
+		}
+		switch {
+		case p.In == "body":
+			return []string{"application/json"}
+		case p.In == "formData" && p.Type != nil && p.Type.Is("file"):
+			return []string{"multipart/form-data"}
+		case p.In == "formData":
+			consumes = []string{"application/x-www-form-urlencoded"}
+		}
+	}
+	return consumes
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const swagger2Spec = `
+swagger: "2.0"
+info: {title: Legacy, version: "1"}
+host: http://legacy.internal:8080/svc
+basePath: /api
+securityDefinitions:
+  token: {type: apiKey, in: header, name: Authorization}
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, type: integer}
+    get:
+      operationId: getPet
+      responses: {"200": {description: ok}}
+    put:
+      operationId: updatePet
+      parameters:
+        - in: body
+          name: pet
+          required: true
+          schema: {type: object, properties: {name: {type: string}}}
+      responses: {"200": {description: ok}}
+  /pets/{id}/photo:
+    post:
+      operationId: uploadPhoto
+      parameters:
+        - {name: id, in: path, required: true, type: integer}
+        - {name: caption, in: formData, type: string}
+        - {name: file, in: formData, type: file, required: true}
+      responses: {"200": {description: ok}}
+  /login:
+    post:
+      operationId: login
+      consumes: [application/x-www-form-urlencoded]
+      parameters:
+        - {name: user, in: formData, type: string, required: true}
+      responses: {"200": {description: ok}}
+`
+
+func TestLoadSpecConvertsSwagger2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swagger.yaml")
+	if err := os.WriteFile(path, []byte(swagger2Spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadSpec(path)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+	if authType, _ := DetectAuth(doc); authType != "bearer" {
+		t.Errorf("auth = %q, want bearer", authType)
+	}
+
+	tools, endpoints := BuildTools(doc, "legacy", "http://localhost:9000", AuthConfig{})
+	if len(tools) != 5 {
+		t.Fatalf("expected 5 tools, got %d", len(tools))
+	}
+
+	get := endpoints["legacy__getPet"]
+	if get == nil || get.Path != "/svc/api/pets/{id}" {
+		t.Fatalf("getPet endpoint = %+v", get)
+	}
+	if len(get.Params) != 1 || get.Params[0].In != "path" {
+		t.Errorf("path-level parameter not inherited: %+v", get.Params)
+	}
+
+	update := endpoints["legacy__updatePet"]
+	if !update.HasBody || update.BodyType != "" || len(update.Params) != 1 {
+		t.Errorf("updatePet endpoint = %+v", update)
+	}
+	if upload := endpoints["legacy__uploadPhoto"]; upload.BodyType != multipartForm {
+		t.Errorf("uploadPhoto body type = %q", upload.BodyType)
+	}
+	if login := endpoints["legacy__login"]; login.BodyType != formURLEncoded {
+		t.Errorf("login body type = %q", login.BodyType)
+	}
+
+	for _, tool := range tools {
+		if tool.Function.Name != "legacy__login" {
+			continue
+		}
+		props := tool.Function.Parameters.(map[string]interface{})["properties"].(map[string]interface{})
+		body, _ := props["body"].(map[string]interface{})
+		fields, _ := body["properties"].(map[string]interface{})
+		if _, ok := fields["user"]; !ok {
+			t.Errorf("login body schema = %v", body)
+		}
+	}
+}
+
+func TestServerPathPrefixSkipsConfiguredBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swagger.json")
+	spec := `{"swagger":"2.0","info":{"title":"t","version":"1"},"basePath":"/v2",
+		"paths":{"/items":{"get":{"operationId":"list","responses":{"200":{"description":"ok"}}}}}}`
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadSpec(path)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+
+	_, endpoints := BuildTools(doc, "svc", "http://svc", AuthConfig{})
+	if got := endpoints["svc__list"].Path; got != "/v2/items" {
+		t.Errorf("path = %q, want /v2/items", got)
+	}
+	_, endpoints = BuildTools(doc, "svc", "http://svc/v2/", AuthConfig{})
+	if got := endpoints["svc__list"].Path; got != "/items" {
+		t.Errorf("path with base_url ending in basePath = %q, want /items", got)
+	}
+}
+
+func TestOpenAPI3PathsIgnoreServerPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	spec := `{"openapi":"3.0.0","info":{"title":"t","version":"1"},"servers":[{"url":"https://api.example.com/v1"}],
+		"paths":{"/items":{"get":{"operationId":"list","responses":{"200":{"description":"ok"}}}}}}`
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadSpec(path)
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+
+	_, endpoints := BuildTools(doc, "svc", "http://svc", AuthConfig{})
+	if got := endpoints["svc__list"].Path; got != "/items" {
+		t.Errorf("path = %q, want /items", got)
+	}
+}
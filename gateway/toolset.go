@@ -26,6 +26,7 @@ type ToolSetEndpoint struct {
 	Group       string                 `json:"group,omitempty"`
 	Params      []ParamInfo            `json:"params"`
 	HasBody     bool                   `json:"has_body"`
+	BodyType    string                 `json:"body_type,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters"`
 }
 
@@ -76,6 +77,7 @@ func (ts *ToolSet) Build() ([]llm.Tool, map[string]*Endpoint) {
 			Auth:              ts.Auth,
 			Params:            ep.Params,
 			HasBody:           ep.HasBody,
+			BodyType:          ep.BodyType,
 		}
 
 		tools = append(tools, tool)
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/toolctx"
 )
 
 type Client struct {
@@ -194,6 +196,16 @@ func (c *Client) Tools() []MCPTool {
 	return c.tools
 }
 
+// Tool returns the tool with the given (unprefixed) name.
+func (c *Client) Tool(name string) (MCPTool, bool) {
+	for _, t := range c.tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return MCPTool{}, false
+}
+
 func (c *Client) Name() string {
 	return c.name
 }
@@ -216,6 +228,12 @@ func (c *Client) CallTool(ctx context.Context, name, argsJSON string) (string, e
 		return "", err
 	}
 
+	if callResult.IsError {
+		if r := toolctx.ReportFrom(ctx); r != nil {
+			r.Failed = true
+		}
+	}
+
 	var texts []string
 	for _, block := range callResult.Content {
 		if block.Type == "text" {
@@ -53,9 +53,39 @@ type ServerCapabilities struct {
 type ToolsCapability struct{}
 
 type MCPTool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description,omitempty"`
-	InputSchema interface{} `json:"inputSchema"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	InputSchema interface{}      `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are the server's hints about a tool's behavior.
+// Unset hints are nil.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// Hints returns the hints that are set, keyed by their MCP names.
+func (a *ToolAnnotations) Hints() map[string]bool {
+	if a == nil {
+		return nil
+	}
+	hints := make(map[string]bool)
+	for name, v := range map[string]*bool{
+		"readOnlyHint":    a.ReadOnlyHint,
+		"destructiveHint": a.DestructiveHint,
+		"idempotentHint":  a.IdempotentHint,
+		"openWorldHint":   a.OpenWorldHint,
+	} {
+		if v != nil {
+			hints[name] = *v
+		}
+	}
+	return hints
 }
 
 type ToolsListResult struct {
@@ -0,0 +1,178 @@
+// Package mockllm serves a scripted, OpenAI-compatible chat completions API
+// for exercising the tool loop, confirmations and SDKs without a provider.
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script is the YAML file driving the mock. Rules are tried in order and the
+// first match answers; when none match the mock replies with a short notice.
+//
+//	model: mock
+//	rules:
+//	  - when: {user: "(?i)delete pet (\\d+)"}
+//	    reply: "Deleting pet $1."
+//	    tool_calls:
+//	      - name: deletePet
+//	        args: {id: "$1"}
+//	  - when: {after_tool: deletePet}
+//	    reply: "Done."
+type Script struct {
+	Model string `yaml:"model"`
+	Rules []Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Name      string         `yaml:"name"` // optional label for logs
+	When      Match          `yaml:"when"`
+	Reply     string         `yaml:"reply"`     // assistant text; $1 / ${name} expand user regex groups
+	Reasoning string         `yaml:"reasoning"` // sent as reasoning_content
+	ToolCalls []ToolCallSpec `yaml:"tool_calls"`
+	Status    int            `yaml:"status"` // answer with this HTTP error instead, e.g. 429
+	Times     int            `yaml:"times"`  // match at most this many times; 0 = unlimited
+
+	user   *regexp.Regexp
+	result *regexp.Regexp
+}
+
+// Match selects requests by their latest messages. A rule without after_tool
+// only answers a fresh user turn, so a tool result never re-triggers the
+// rule that requested the tool.
+type Match struct {
+	User      string `yaml:"user"`       // regex on the last user message
+	AfterTool string `yaml:"after_tool"` // last message is this tool's result; "*" = any tool
+	Result    string `yaml:"result"`     // regex on that tool result
+}
+
+type ToolCallSpec struct {
+	Name string                 `yaml:"name"`
+	Args map[string]interface{} `yaml:"args"` // string values expand like Reply; a lone "$1" that expands to a number or bool becomes one
+}
+
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScript(data)
+}
+
+func ParseScript(data []byte) (*Script, error) {
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse script: %w", err)
+	}
+	if s.Model == "" {
+		s.Model = "mock"
+	}
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		var err error
+		if r.When.User != "" {
+			if r.user, err = regexp.Compile(r.When.User); err != nil {
+				return nil, fmt.Errorf("rule %d: when.user: %w", i+1, err)
+			}
+		}
+		if r.When.Result != "" {
+			if r.result, err = regexp.Compile(r.When.Result); err != nil {
+				return nil, fmt.Errorf("rule %d: when.result: %w", i+1, err)
+			}
+		}
+		for _, tc := range r.ToolCalls {
+			if tc.Name == "" {
+				return nil, fmt.Errorf("rule %d: tool call without name", i+1)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// label names the rule in logs.
+func (r *Rule) label(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule %d", i+1)
+}
+
+// turn is what rules match against, extracted from the request messages.
+type turn struct {
+	lastRole   string
+	lastUser   string
+	toolName   string // when lastRole == "tool"
+	toolResult string
+}
+
+// matches reports whether the rule applies, returning the user regex
+// submatch indices for template expansion.
+func (r *Rule) matches(t turn) (bool, []int) {
+	if r.When.AfterTool == "" {
+		if t.lastRole != "user" {
+			return false, nil
+		}
+	} else {
+		if t.lastRole != "tool" || (r.When.AfterTool != "*" && r.When.AfterTool != t.toolName) {
+			return false, nil
+		}
+		if r.result != nil && !r.result.MatchString(t.toolResult) {
+			return false, nil
+		}
+	}
+	if r.user == nil {
+		return true, nil
+	}
+	m := r.user.FindStringSubmatchIndex(t.lastUser)
+	return m != nil, m
+}
+
+// expand substitutes user regex groups into a template.
+func (r *Rule) expand(tmpl, src string, match []int) string {
+	if r.user == nil || match == nil {
+		return tmpl
+	}
+	return string(r.user.ExpandString(nil, tmpl, src, match))
+}
+
+// wholeRef matches a template that is a single group reference.
+var wholeRef = regexp.MustCompile(`^\$(\w+|\{\w+\})$`)
+
+// jsonNumber matches JSON number syntax.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+func (r *Rule) expandValue(v interface{}, src string, match []int) interface{} {
+	switch x := v.(type) {
+	case string:
+		out := r.expand(x, src, match)
+		if !wholeRef.MatchString(x) {
+			return out
+		}
+		// A whole-value reference takes the type of what it captured, so
+		// integer and boolean parameters can be filled from the message.
+		switch {
+		case jsonNumber.MatchString(out):
+			return json.Number(out)
+		case out == "true", out == "false":
+			return out == "true"
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = r.expandValue(e, src, match)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = r.expandValue(e, src, match)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,249 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
+	"github.com/gin-gonic/gin"
+)
+
+const noMatchReply = "[mock-llm] no rule matched the request"
+
+// Server answers /chat/completions from a Script.
+type Server struct {
+	script *Script
+
+	mu     sync.Mutex
+	hits   []int // per rule, for Rule.Times
+	nextID int
+}
+
+func NewServer(script *Script) *Server {
+	return &Server{script: script, hits: make([]int, len(script.Rules))}
+}
+
+// Handler serves the API under both / and /v1, so api_base may include /v1 or not.
+func (s *Server) Handler() http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	for _, prefix := range []string{"", "/v1"} {
+		r.POST(prefix+"/chat/completions", s.chatCompletions)
+		r.GET(prefix+"/models", s.listModels)
+	}
+	return r
+}
+
+func (s *Server) Run(port int) error {
+	fmt.Printf("Mock LLM on http://localhost:%d/v1 (model %q, %d rules)\n", port, s.script.Model, len(s.script.Rules))
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), s.Handler())
+}
+
+func (s *Server) listModels(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"object": "list",
+		"data":   []gin.H{{"id": s.script.Model, "object": "model", "owned_by": "nlui"}},
+	})
+}
+
+func (s *Server) chatCompletions(c *gin.Context) {
+	var req llm.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": gin.H{"message": err.Error(), "type": "invalid_request_error"}})
+		return
+	}
+
+	t := extractTurn(req.Messages)
+	rule, match := s.pick(t)
+	if rule != nil && rule.Status != 0 && rule.Status != http.StatusOK {
+		c.JSON(rule.Status, gin.H{"error": gin.H{"message": rule.Reply, "type": "mock_error"}})
+		return
+	}
+
+	msg := llm.ResponseMessage{Message: llm.Message{Role: "assistant", Content: noMatchReply}}
+	if rule != nil {
+		msg.Content = rule.expand(rule.Reply, t.lastUser, match)
+		msg.ReasoningContent = rule.expand(rule.Reasoning, t.lastUser, match)
+		for _, spec := range rule.ToolCalls {
+			args, _ := json.Marshal(rule.expandValue(spec.Args, t.lastUser, match))
+			if spec.Args == nil {
+				args = []byte("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:       s.newID("call"),
+				Type:     "function",
+				Function: llm.FunctionCall{Name: spec.Name, Arguments: string(args)},
+			})
+		}
+	}
+
+	finish := "stop"
+	if len(msg.ToolCalls) > 0 {
+		finish = "tool_calls"
+	}
+	usage := estimateUsage(req.Messages, msg)
+	id := s.newID("chatcmpl-mock")
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		s.stream(c, id, msg, finish, usage, includeUsage)
+		return
+	}
+	c.JSON(200, gin.H{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   s.script.Model,
+		"choices": []gin.H{{"index": 0, "message": msg, "finish_reason": finish}},
+		"usage":   usage,
+	})
+}
+
+// pick returns the first rule matching the turn, counting the hit.
+func (s *Server) pick(t turn) (*Rule, []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.script.Rules {
+		r := &s.script.Rules[i]
+		if r.Times > 0 && s.hits[i] >= r.Times {
+			continue
+		}
+		if ok, m := r.matches(t); ok {
+			s.hits[i]++
+			log.Printf("mock-llm: %s matched", r.label(i))
+			return r, m
+		}
+	}
+	log.Printf("mock-llm: no rule matched (last %s message %q)", t.lastRole, t.lastUser)
+	return nil, nil
+}
+
+func (s *Server) newID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("%s_%d", prefix, s.nextID)
+}
+
+// stream writes the response as OpenAI SSE chunks: reasoning, then content
+// word by word, then one chunk per tool call.
+func (s *Server) stream(c *gin.Context, id string, msg llm.ResponseMessage, finish string, usage *llm.Usage, includeUsage bool) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	send := func(v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+		c.Writer.Flush()
+	}
+	chunk := func(delta gin.H, finishReason interface{}) gin.H {
+		return gin.H{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"model":   s.script.Model,
+			"choices": []gin.H{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	send(chunk(gin.H{"role": "assistant"}, nil))
+	for _, w := range splitWords(msg.ReasoningContent) {
+		send(chunk(gin.H{"reasoning_content": w}, nil))
+	}
+	for _, w := range splitWords(msg.Content) {
+		send(chunk(gin.H{"content": w}, nil))
+	}
+	for i, tc := range msg.ToolCalls {
+		send(chunk(gin.H{"tool_calls": []llm.StreamToolCall{{
+			Index:    i,
+			ID:       tc.ID,
+			Type:     "function",
+			Function: tc.Function,
+		}}}, nil))
+	}
+	send(chunk(gin.H{}, finish))
+	if includeUsage {
+		send(gin.H{"id": id, "object": "chat.completion.chunk", "model": s.script.Model, "choices": []gin.H{}, "usage": usage})
+	}
+	fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
+}
+
+// extractTurn finds the latest user text and, when the request ends with a
+// tool result, which tool produced it.
+func extractTurn(messages []llm.RequestMessage) turn {
+	var t turn
+	if len(messages) == 0 {
+		return t
+	}
+	toolNames := make(map[string]string)
+	for _, m := range messages {
+		for _, tc := range m.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+		}
+		if m.Role == "user" {
+			t.lastUser = contentText(m.Content)
+		}
+	}
+	last := messages[len(messages)-1]
+	t.lastRole = last.Role
+	if last.Role == "tool" {
+		t.toolName = toolNames[last.ToolCallID]
+		t.toolResult = contentText(last.Content)
+	}
+	return t
+}
+
+// contentText flattens a string or content-part array to its text.
+func contentText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var parts []string
+		for _, p := range v {
+			if m, ok := p.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+// splitWords splits text into deltas that keep their trailing spaces.
+func splitWords(text string) []string {
+	var out []string
+	for text != "" {
+		i := strings.IndexByte(text, ' ')
+		if i < 0 {
+			out = append(out, text)
+			break
+		}
+		out = append(out, text[:i+1])
+		text = text[i+1:]
+	}
+	return out
+}
+
+func estimateUsage(messages []llm.RequestMessage, msg llm.ResponseMessage) *llm.Usage {
+	var counter tokenizer.Estimator
+	prompt := 0
+	for _, m := range messages {
+		prompt += counter.Count(contentText(m.Content))
+	}
+	completion := counter.Count(msg.Content) + counter.Count(msg.ReasoningContent)
+	for _, tc := range msg.ToolCalls {
+		completion += counter.Count(tc.Function.Name) + counter.Count(tc.Function.Arguments)
+	}
+	return &llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
@@ -0,0 +1,142 @@
+package mockllm
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/toolloop"
+	"github.com/ZacharyZcR/NLUI/gateway"
+)
+
+const testScript = `
+model: mock-test
+rules:
+  - when: {user: "(?i)delete pet (\\d+)"}
+    reasoning: "remove $1"
+    reply: "Deleting pet $1."
+    tool_calls:
+      - name: deletePet
+        args: {petId: "$1", opts: {force: true}}
+  - when: {after_tool: deletePet, result: "canceled"}
+    reply: "Left it alone."
+  - when: {after_tool: "*"}
+    reply: "Done."
+  - when: {user: flaky}
+    status: 503
+    reply: "try later"
+    times: 1
+`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	script, err := ParseScript([]byte(testScript))
+	if err != nil {
+		t.Fatalf("ParseScript: %v", err)
+	}
+	srv := httptest.NewServer(NewServer(script).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMockToolCallRoundTrip(t *testing.T) {
+	srv := newTestServer(t)
+	for _, stream := range []bool{true, false} {
+		client := llm.NewAutoClient("openai", srv.URL+"/v1", "", "mock-test", "", stream, llm.GenerationParams{}, llm.AzureOptions{})
+		history := []llm.Message{{Role: "user", Content: "please delete pet 42"}}
+
+		var deltas, reasoning strings.Builder
+		msg, usage, err := client.ChatStreamWithTools(context.Background(), history, nil,
+			func(d string) { deltas.WriteString(d) },
+			func(d string) { reasoning.WriteString(d) })
+		if err != nil {
+			t.Fatalf("stream=%v: %v", stream, err)
+		}
+		if msg.Content != "Deleting pet 42." || deltas.String() != msg.Content || reasoning.String() != "remove 42" {
+			t.Errorf("stream=%v: content %q, deltas %q, reasoning %q", stream, msg.Content, deltas.String(), reasoning.String())
+		}
+		if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "deletePet" ||
+			msg.ToolCalls[0].Function.Arguments != `{"opts":{"force":true},"petId":42}` {
+			t.Fatalf("stream=%v: tool calls = %+v", stream, msg.ToolCalls)
+		}
+		if usage == nil || usage.TotalTokens == 0 {
+			t.Errorf("stream=%v: usage = %+v", stream, usage)
+		}
+
+		// The tool result must not re-trigger the delete rule.
+		history = append(history, *msg, llm.Message{Role: "tool", ToolCallID: msg.ToolCalls[0].ID, Content: `{"ok":true}`})
+		msg, _, err = client.ChatStreamWithTools(context.Background(), history, nil, nil, nil)
+		if err != nil || msg.Content != "Done." || len(msg.ToolCalls) != 0 {
+			t.Errorf("stream=%v: after tool = %+v, %v", stream, msg, err)
+		}
+
+		history[len(history)-1].Content = "Operation canceled by user"
+		msg, _, _ = client.ChatStreamWithTools(context.Background(), history, nil, nil, nil)
+		if msg.Content != "Left it alone." {
+			t.Errorf("stream=%v: after cancel = %q", stream, msg.Content)
+		}
+	}
+}
+
+func TestMockStatusRuleAndFallback(t *testing.T) {
+	srv := newTestServer(t)
+	client := llm.NewAutoClient("openai", srv.URL, "", "mock-test", "", false, llm.GenerationParams{}, llm.AzureOptions{})
+	flaky := []llm.Message{{Role: "user", Content: "flaky"}}
+
+	_, _, err := client.ChatStreamWithTools(context.Background(), flaky, nil, nil, nil)
+	if apiErr, ok := err.(*llm.APIError); !ok || apiErr.StatusCode != 503 {
+		t.Fatalf("expected 503 APIError, got %v", err)
+	}
+	// times: 1 — the second attempt falls through to the no-match reply.
+	msg, _, err := client.ChatStreamWithTools(context.Background(), flaky, nil, nil, nil)
+	if err != nil || msg.Content != noMatchReply {
+		t.Fatalf("second call = %+v, %v", msg, err)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	if _, err := ParseScript([]byte(`rules: [{when: {user: "("}}]`)); err == nil {
+		t.Error("expected invalid regex error")
+	}
+	if _, err := ParseScript([]byte(`rules: [{tool_calls: [{args: {}}]}]`)); err == nil {
+		t.Error("expected missing tool name error")
+	}
+}
+
+func TestShippedScriptMatchesPetstore(t *testing.T) {
+	script, err := LoadScript("../testdata/mock-llm.yaml")
+	if err != nil {
+		t.Fatalf("LoadScript: %v", err)
+	}
+	doc, err := gateway.LoadSpec("../testdata/petstore.json")
+	if err != nil {
+		t.Fatalf("LoadSpec: %v", err)
+	}
+	tools, _ := gateway.BuildTools(doc, "petstore", "http://localhost:8080", gateway.AuthConfig{})
+	byName := make(map[string]llm.Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Function.Name] = tool
+	}
+
+	srv := httptest.NewServer(NewServer(script).Handler())
+	defer srv.Close()
+	client := llm.NewAutoClient("openai", srv.URL+"/v1", "", "mock", "", false, llm.GenerationParams{}, llm.AzureOptions{})
+	for _, prompt := range []string{"list all pets", "delete pet 7"} {
+		msg, _, err := client.ChatStreamWithTools(context.Background(), []llm.Message{{Role: "user", Content: prompt}}, nil, nil, nil)
+		if err != nil || len(msg.ToolCalls) == 0 {
+			t.Fatalf("%q: %+v, %v", prompt, msg, err)
+		}
+		for _, tc := range msg.ToolCalls {
+			tool, ok := byName[tc.Function.Name]
+			if !ok {
+				t.Errorf("%q: unknown tool %s", prompt, tc.Function.Name)
+				continue
+			}
+			if problems := toolloop.ValidateArguments(tool, tc.Function.Arguments); len(problems) > 0 {
+				t.Errorf("%q: %s%s rejected: %+v", prompt, tc.Function.Name, tc.Function.Arguments, problems)
+			}
+		}
+	}
+}
@@ -107,6 +107,11 @@ export class NluiChatInterface extends LitElement {
                 this.messages = msgs;
               }
             }
+          } else if (event.type === 'content_discarded') {
+            // The time budget cut the answer off; the wrap-up streams next.
+            const discarded = streamId;
+            streamId = '';
+            this.messages = this.messages.filter((m) => m.id !== discarded);
           } else if (event.type === 'tool_call') {
             streamId = '';
             const { name, arguments: args } = event.data;
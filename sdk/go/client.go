@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -52,13 +54,48 @@ type InfoResponse struct {
 }
 
 type Conversation struct {
-	ID             string    `json:"id"`
-	Title          string    `json:"title"`
-	Messages       []Message `json:"messages"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	EnabledSources []string  `json:"enabled_sources,omitempty"`
-	DisabledTools  []string  `json:"disabled_tools,omitempty"`
+	ID             string       `json:"id"`
+	Title          string       `json:"title"`
+	Messages       []Message    `json:"messages"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	EnabledSources []string     `json:"enabled_sources,omitempty"`
+	DisabledTools  []string     `json:"disabled_tools,omitempty"`
+	Usage          *UsageTotals `json:"usage,omitempty"`
+	Budget         *Budget      `json:"budget,omitempty"`
+	DryRun         bool         `json:"dry_run,omitempty"`
+}
+
+// Budget overrides the per-turn limits of a conversation; zero fields use the server defaults.
+type Budget struct {
+	MaxIterations  int `json:"max_iterations,omitempty"`
+	MaxToolCalls   int `json:"max_tool_calls,omitempty"`
+	MaxTokens      int `json:"max_tokens,omitempty"`
+	MaxDurationSec int `json:"max_duration_sec,omitempty"`
+}
+
+// UsageTotals sums token usage and cost over chat turns.
+type UsageTotals struct {
+	Turns            int     `json:"turns"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	Currency         string  `json:"currency,omitempty"`
+}
+
+// UsageGroup is one row of a UsageReport breakdown.
+type UsageGroup struct {
+	Key   string `json:"key"` // day (YYYY-MM-DD), model or conversation ID
+	Title string `json:"title,omitempty"`
+	UsageTotals
+}
+
+type UsageReport struct {
+	Total          UsageTotals  `json:"total"`
+	ByDay          []UsageGroup `json:"by_day"`
+	ByModel        []UsageGroup `json:"by_model"`
+	ByConversation []UsageGroup `json:"by_conversation"`
 }
 
 type Message struct {
@@ -81,6 +118,37 @@ type ChatEvent struct {
 	Data json.RawMessage `json:"data"`
 }
 
+// Decode unmarshals the event data into v, e.g. a *ToolResultEvent for
+// "tool_result" events.
+func (e ChatEvent) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// ToolCallEvent is the data of a "tool_call" event.
+type ToolCallEvent struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Source    string `json:"source,omitempty"` // target or MCP server
+}
+
+// ToolResultEvent is the data of a "tool_result" event. ID matches the
+// ToolCallEvent of the same call.
+type ToolResultEvent struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Result     string    `json:"result"`
+	Status     string    `json:"status"` // success | error | denied | skipped | preview
+	Source     string    `json:"source,omitempty"`
+	HTTPStatus int       `json:"http_status,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Compacted  bool      `json:"compacted,omitempty"`
+	Arguments  string    `json:"arguments,omitempty"`
+	Cached     bool      `json:"cached,omitempty"`
+}
+
 // ============= Phase 1-5 Types =============
 
 type Target struct {
@@ -112,9 +180,26 @@ type ToolConfig struct {
 }
 
 type LLMConfig struct {
-	APIBase string `json:"api_base"`
-	APIKey  string `json:"api_key"`
-	Model   string `json:"model"`
+	Provider string `json:"provider,omitempty"` // openai | azure | gemini | anthropic; "" = detect from api_base
+	APIBase  string `json:"api_base"`
+	APIKey   string `json:"api_key"`
+	Model    string `json:"model"`
+
+	// Azure OpenAI (provider "azure").
+	Deployment string `json:"deployment,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+
+	// Sampling parameters; nil fields are left unchanged on update.
+	Temperature       *float64 `json:"temperature,omitempty"`
+	MaxTokens         *int     `json:"max_tokens,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	Seed              *int     `json:"seed,omitempty"`
+	ParallelToolCalls *bool    `json:"parallel_tool_calls,omitempty"`
+	ToolChoice        *string  `json:"tool_choice,omitempty"` // auto | none | required | <tool name>
+
+	// ClearSampling names sampling parameters to unset on update, e.g.
+	// "temperature", leaving them to the backend's defaults.
+	ClearSampling []string `json:"-"`
 }
 
 type LLMProvider struct {
@@ -195,16 +280,20 @@ func (c *Client) Info(ctx context.Context) (*InfoResponse, error) {
 // ChatOptions holds options for the Chat method.
 type ChatOptions struct {
 	ConversationID string
+	DryRun         bool // preview tool requests instead of sending them
 	OnEvent        func(event ChatEvent)
 	OnDone         func(conversationID string)
 }
 
 // Chat sends a chat message and streams the response via SSE.
 func (c *Client) Chat(ctx context.Context, message string, opts ChatOptions) error {
-	body := map[string]string{
+	body := map[string]interface{}{
 		"message":         message,
 		"conversation_id": opts.ConversationID,
 	}
+	if opts.DryRun {
+		body["dry_run"] = true
+	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return err
@@ -305,6 +394,63 @@ func splitLines(buffer *string) []string {
 	return lines
 }
 
+// ArgProblem is one way tool arguments break the tool's schema.
+type ArgProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// InvalidArgumentsError is returned by ConfirmTool when edited arguments
+// don't match the tool's schema. The call is still awaiting confirmation.
+type InvalidArgumentsError struct {
+	Problems []ArgProblem `json:"problems"`
+}
+
+func (e *InvalidArgumentsError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Path + ": " + p.Message
+	}
+	return "invalid arguments: " + strings.Join(msgs, "; ")
+}
+
+// ConfirmTool answers a tool_confirm event of a chat session. When approving,
+// arguments may replace the model's arguments ("" keeps them).
+func (c *Client) ConfirmTool(ctx context.Context, sessionID string, approved bool, arguments string) error {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"session_id": sessionID,
+		"approved":   approved,
+		"arguments":  arguments,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/chat/confirm", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		var invalid InvalidArgumentsError
+		if err := json.NewDecoder(resp.Body).Decode(&invalid); err != nil {
+			return err
+		}
+		return &invalid
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("confirm tool failed: %s", resp.Status)
+	}
+	return nil
+}
+
 // ListConversations retrieves all conversations.
 func (c *Client) ListConversations(ctx context.Context) ([]*Conversation, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/conversations", nil)
@@ -406,6 +552,42 @@ func (c *Client) DeleteConversation(ctx context.Context, id string) error {
 	return nil
 }
 
+// GetUsage retrieves the token usage and cost report. from and to are
+// optional inclusive dates (YYYY-MM-DD).
+func (c *Client) GetUsage(ctx context.Context, from, to string) (*UsageReport, error) {
+	q := url.Values{}
+	if from != "" {
+		q.Set("from", from)
+	}
+	if to != "" {
+		q.Set("to", to)
+	}
+	endpoint := c.BaseURL + "/api/usage"
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get usage failed: %s", resp.Status)
+	}
+
+	var result UsageReport
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // ============= Phase 1: Targets Management =============
 
 // AddTarget dynamically adds an API target (OpenAPI spec or ToolSet JSON).
@@ -630,6 +812,92 @@ func (c *Client) UpdateConversationTools(ctx context.Context, conversationID str
 	return &result, nil
 }
 
+// GetConversationBudget retrieves the per-turn budget override of a conversation.
+func (c *Client) GetConversationBudget(ctx context.Context, conversationID string) (*Budget, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/conversations/"+conversationID+"/budget", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get conversation budget failed: %s", resp.Status)
+	}
+
+	var result Budget
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateConversationBudget overrides the per-turn budget of a conversation.
+// An empty Budget restores the server defaults.
+func (c *Client) UpdateConversationBudget(ctx context.Context, conversationID string, budget Budget) (*SimpleResponse, error) {
+	bodyBytes, err := json.Marshal(budget)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.BaseURL+"/api/conversations/"+conversationID+"/budget", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("update conversation budget failed: %s", resp.Status)
+	}
+
+	var result SimpleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetConversationDryRun turns dry-run mode on or off for a conversation. In
+// dry-run mode tool requests are previewed (tool_preview events) instead of sent.
+func (c *Client) SetConversationDryRun(ctx context.Context, conversationID string, enabled bool) (*SimpleResponse, error) {
+	bodyBytes, err := json.Marshal(map[string]bool{"enabled": enabled})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.BaseURL+"/api/conversations/"+conversationID+"/dry_run", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("update conversation dry run failed: %s", resp.Status)
+	}
+
+	var result SimpleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // ============= Phase 3: Message Editing & Regeneration =============
 
 // EditMessageOptions holds options for editing a message.
@@ -782,6 +1050,19 @@ func (c *Client) UpdateLLMConfig(ctx context.Context, config LLMConfig) (*Simple
 	if err != nil {
 		return nil, err
 	}
+	if len(config.ClearSampling) > 0 {
+		// The server clears fields sent as null.
+		var body map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &body); err != nil {
+			return nil, err
+		}
+		for _, name := range config.ClearSampling {
+			body[name] = nil
+		}
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", c.BaseURL+"/api/config/llm", bytes.NewReader(bodyBytes))
 	if err != nil {
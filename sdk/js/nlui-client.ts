@@ -22,13 +22,46 @@ export interface Conversation {
   messages: ChatMessage[];
   created_at: string;
   updated_at: string;
+  usage?: UsageTotals;
+  budget?: ConversationBudget;
+  dry_run?: boolean;
+}
+
+export interface ConversationBudget {
+  max_iterations?: number;
+  max_tool_calls?: number;
+  max_tokens?: number;
+  max_duration_sec?: number;
+}
+
+export interface UsageTotals {
+  turns: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  cost: number;
+  currency?: string;
+}
+
+export interface UsageGroup extends UsageTotals {
+  key: string; // day (YYYY-MM-DD), model or conversation ID
+  title?: string;
+}
+
+export interface UsageReport {
+  total: UsageTotals;
+  by_day: UsageGroup[];
+  by_model: UsageGroup[];
+  by_conversation: UsageGroup[];
 }
 
 export interface ChatOptions {
   conversationId?: string;
+  /** 只预览工具请求（tool_preview 事件），不真正发送 */
+  dryRun?: boolean;
   onEvent?: (event: ChatEvent) => void;
   onSession?: (sessionId: string) => void;
-  onToolConfirm?: (sessionId: string, toolName: string, args: string) => void;
+  onToolConfirm?: (sessionId: string, toolName: string, args: string, rule?: string, toolCallId?: string) => void;
   onDone?: (conversationId: string) => void;
   onError?: (error: Error) => void;
   signal?: AbortSignal;
@@ -36,12 +69,20 @@ export interface ChatOptions {
 
 export type ChatEventType =
   | "content_delta"
+  | "content_discarded"
+  | "reasoning_delta"
   | "content"
   | "tool_call"
   | "tool_result"
+  | "tool_preview"
   | "tool_confirm"
+  | "tools_selected"
   | "session"
+  | "retry"
   | "usage"
+  | "compacted"
+  | "loop_detected"
+  | "budget_exceeded"
   | "error"
   | "done";
 
@@ -58,6 +99,49 @@ export interface ChatEvent {
   data: any;
 }
 
+/** tool_call 事件数据 */
+export interface ToolCallEventData {
+  /** 与同一调用的 tool_result 相同 */
+  id: string;
+  name: string;
+  arguments: string;
+  /** 工具所属的 target 或 MCP server */
+  source?: string;
+}
+
+export type ToolStatus = "success" | "error" | "denied" | "skipped" | "preview";
+
+/** tool_result 事件数据 */
+export interface ToolResultEventData {
+  id: string;
+  name: string;
+  result: string;
+  status: ToolStatus;
+  source?: string;
+  /** HTTP 工具收到的响应状态码 */
+  http_status?: number;
+  started_at: string;
+  ended_at: string;
+  duration_ms: number;
+  compacted?: boolean;
+  /** 确认时被修改后的参数 */
+  arguments?: string;
+  cached?: boolean;
+}
+
+export interface ArgProblem {
+  path: string;
+  message: string;
+}
+
+/** 修改后的工具参数不符合 schema */
+export class InvalidArgumentsError extends Error {
+  constructor(public problems: ArgProblem[]) {
+    super(`Invalid arguments: ${problems.map((p) => `${p.path}: ${p.message}`).join("; ")}`);
+    this.name = "InvalidArgumentsError";
+  }
+}
+
 // ============= Phase 1-5 Types =============
 
 export interface Target {
@@ -82,11 +166,23 @@ export interface ToolSource {
 }
 
 export interface LLMConfig {
+  provider?: "openai" | "azure" | "gemini" | "anthropic" | "";
   api_base: string;
   api_key: string;
   model: string;
+  /** Azure OpenAI deployment name (defaults to model) */
+  deployment?: string;
+  /** Azure OpenAI api-version */
+  api_version?: string;
   stream?: boolean;
   language?: string;
+  temperature?: number | null;
+  max_tokens?: number | null;
+  top_p?: number | null;
+  seed?: number | null;
+  parallel_tool_calls?: boolean | null;
+  /** "auto" | "none" | "required" | a tool name */
+  tool_choice?: string | null;
 }
 
 export interface LLMProvider {
@@ -145,6 +241,7 @@ export class NLUIClient {
       body: JSON.stringify({
         message,
         conversation_id: options.conversationId || "",
+        ...(options.dryRun ? { dry_run: true } : {}),
       }),
       signal: options.signal,
     });
@@ -209,6 +306,19 @@ export class NLUIClient {
     }
   }
 
+  /**
+   * 获取用量与费用报表（from/to 为可选的 YYYY-MM-DD 日期，包含端点）
+   */
+  async getUsage(from?: string, to?: string): Promise<UsageReport> {
+    const params = new URLSearchParams();
+    if (from) params.set("from", from);
+    if (to) params.set("to", to);
+    const query = params.toString();
+    const response = await fetch(`${this.baseURL}/api/usage${query ? `?${query}` : ""}`);
+    if (!response.ok) throw new Error(`Get usage failed: ${response.statusText}`);
+    return response.json();
+  }
+
   // ============= Phase 1: Targets Management =============
 
   /**
@@ -330,6 +440,44 @@ export class NLUIClient {
     return response.json();
   }
 
+  /**
+   * 获取对话的单轮预算（空对象表示使用服务端默认值）
+   */
+  async getConversationBudget(conversationId: string): Promise<ConversationBudget> {
+    const response = await fetch(`${this.baseURL}/api/conversations/${conversationId}/budget`);
+    if (!response.ok) throw new Error(`Get conversation budget failed: ${response.statusText}`);
+    return response.json();
+  }
+
+  /**
+   * 更新对话的单轮预算（传空对象恢复默认值）
+   */
+  async updateConversationBudget(
+    conversationId: string,
+    budget: ConversationBudget
+  ): Promise<{ message: string }> {
+    const response = await fetch(`${this.baseURL}/api/conversations/${conversationId}/budget`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(budget),
+    });
+    if (!response.ok) throw new Error(`Update conversation budget failed: ${response.statusText}`);
+    return response.json();
+  }
+
+  /**
+   * 开关对话的 dry-run 模式（只预览工具请求，不真正发送）
+   */
+  async setConversationDryRun(conversationId: string, enabled: boolean): Promise<{ message: string }> {
+    const response = await fetch(`${this.baseURL}/api/conversations/${conversationId}/dry_run`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ enabled }),
+    });
+    if (!response.ok) throw new Error(`Update conversation dry run failed: ${response.statusText}`);
+    return response.json();
+  }
+
   // ============= Phase 3: Message Editing & Regeneration =============
 
   /**
@@ -428,17 +576,36 @@ export class NLUIClient {
    * 更新 LLM 配置
    */
   async updateLLMConfig(params: {
+    provider?: string;
     api_base: string;
     api_key?: string;
     model?: string;
+    deployment?: string;
+    api_version?: string;
+    /** Sampling parameters: omitted keeps the current value, null clears it */
+    temperature?: number | null;
+    max_tokens?: number | null;
+    top_p?: number | null;
+    seed?: number | null;
+    parallel_tool_calls?: boolean | null;
+    tool_choice?: string | null;
   }): Promise<{ message: string }> {
     const response = await fetch(`${this.baseURL}/api/config/llm`, {
       method: "PUT",
       headers: { "Content-Type": "application/json" },
       body: JSON.stringify({
+        provider: params.provider,
         api_base: params.api_base,
         api_key: params.api_key || "",
         model: params.model || "",
+        deployment: params.deployment,
+        api_version: params.api_version,
+        temperature: params.temperature,
+        max_tokens: params.max_tokens,
+        top_p: params.top_p,
+        seed: params.seed,
+        parallel_tool_calls: params.parallel_tool_calls,
+        tool_choice: params.tool_choice,
       }),
     });
     if (!response.ok) throw new Error(`Update LLM config failed: ${response.statusText}`);
@@ -457,11 +624,12 @@ export class NLUIClient {
   /**
    * 获取指定 LLM 提供商的模型列表
    */
-  async fetchModels(params: { api_base: string; api_key?: string }): Promise<string[]> {
+  async fetchModels(params: { api_base: string; api_key?: string; provider?: string }): Promise<string[]> {
     const response = await fetch(`${this.baseURL}/api/config/llm/models`, {
       method: "POST",
       headers: { "Content-Type": "application/json" },
       body: JSON.stringify({
+        provider: params.provider,
         api_base: params.api_base,
         api_key: params.api_key || "",
       }),
@@ -548,14 +716,19 @@ export class NLUIClient {
   }
 
   /**
-   * 确认或拒绝危险工具调用
+   * 确认或拒绝需要确认的工具调用；批准时可传入修改后的参数 JSON
+   * 参数不符合工具 schema 时抛出 InvalidArgumentsError，调用仍在等待确认
    */
-  async confirmTool(sessionId: string, approved: boolean): Promise<void> {
+  async confirmTool(sessionId: string, approved: boolean, args?: string): Promise<void> {
     const response = await fetch(`${this.baseURL}/api/chat/confirm`, {
       method: "POST",
       headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ session_id: sessionId, approved }),
+      body: JSON.stringify({ session_id: sessionId, approved, arguments: args || "" }),
     });
+    if (response.status === 422) {
+      const body = await response.json().catch(() => ({}));
+      throw new InvalidArgumentsError(body.problems || []);
+    }
     if (!response.ok) throw new Error(`Confirm tool failed: ${response.statusText}`);
   }
 
@@ -617,7 +790,7 @@ export class NLUIClient {
 
               // Handle tool_confirm event
               if (eventType === "tool_confirm") {
-                options.onToolConfirm?.(parsed.session_id, parsed.name, parsed.arguments);
+                options.onToolConfirm?.(parsed.session_id, parsed.name, parsed.arguments, parsed.rule, parsed.id);
                 continue;
               }
 
@@ -653,8 +826,8 @@ export class NLUIClient {
     if (data.error) return "error";
     if (data.delta) return "content_delta";
     if (data.text) return "content";
-    if (data.name && data.arguments) return "tool_call";
-    if (data.name && data.result) return "tool_result";
+    if (data.name && data.result !== undefined) return "tool_result";
+    if (data.name && data.arguments !== undefined) return "tool_call";
     if (data.total_tokens) return "usage";
     return "content";
   }
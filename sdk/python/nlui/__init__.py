@@ -13,6 +13,8 @@ from .types import (
     ChatEvent,
     HealthResponse,
     InfoResponse,
+    ArgProblem,
+    InvalidArgumentsError,
 )
 
 __version__ = "0.2.0"
@@ -25,6 +27,8 @@ __all__ = [
     "ChatEvent",
     "HealthResponse",
     "InfoResponse",
+    "ArgProblem",
+    "InvalidArgumentsError",
 ]
 
 # 默认使用扩展客户端
@@ -11,6 +11,7 @@ from .types import (
     ChatEvent,
     HealthResponse,
     InfoResponse,
+    InvalidArgumentsError,
 )
 
 
@@ -196,6 +197,36 @@ class NLUIClient:
         if resp.status_code not in (200, 204):
             resp.raise_for_status()
 
+    def confirm_tool(
+        self,
+        session_id: str,
+        approved: bool,
+        arguments: Optional[str] = None,
+    ) -> None:
+        """
+        确认或拒绝需要确认的工具调用
+
+        Args:
+            session_id: tool_confirm 事件中的会话 ID
+            approved: 是否批准
+            arguments: 批准时替换模型参数的 JSON（可选）
+
+        Raises:
+            InvalidArgumentsError: 参数不符合工具 schema，调用仍在等待确认
+        """
+        resp = self.session.post(
+            f"{self.base_url}/api/chat/confirm",
+            json={
+                "session_id": session_id,
+                "approved": approved,
+                "arguments": arguments or "",
+            },
+            timeout=self.timeout,
+        )
+        if resp.status_code == 422:
+            raise InvalidArgumentsError.from_body(resp.text)
+        resp.raise_for_status()
+
     def _infer_event_type(self, data: dict) -> str:
         """推断事件类型"""
         if "error" in data:
@@ -204,10 +235,10 @@ class NLUIClient:
             return "content_delta"
         if "text" in data:
             return "content"
-        if "name" in data and "arguments" in data:
-            return "tool_call"
         if "name" in data and "result" in data:
             return "tool_result"
+        if "name" in data and "arguments" in data:
+            return "tool_call"
         if "total_tokens" in data:
             return "usage"
         return "unknown"
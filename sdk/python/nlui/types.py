@@ -2,6 +2,7 @@
 NLUI Type Definitions
 """
 
+import json
 from dataclasses import dataclass
 from typing import List, Optional, Any, Dict
 from datetime import datetime
@@ -46,3 +47,29 @@ class InfoResponse:
     """服务信息响应"""
     language: str
     tools: int
+
+
+@dataclass
+class ArgProblem:
+    """工具参数校验问题"""
+    path: str
+    message: str
+
+
+class InvalidArgumentsError(Exception):
+    """修改后的工具参数不符合 schema，调用仍在等待确认"""
+
+    def __init__(self, problems: List[ArgProblem]):
+        self.problems = problems
+        super().__init__(
+            "invalid arguments: " + "; ".join(f"{p.path}: {p.message}" for p in problems)
+        )
+
+    @classmethod
+    def from_body(cls, body: str) -> "InvalidArgumentsError":
+        """从 422 响应体解析问题列表"""
+        try:
+            problems = json.loads(body).get("problems") or []
+        except ValueError:
+            problems = []
+        return cls([ArgProblem(path=p["path"], message=p["message"]) for p in problems])
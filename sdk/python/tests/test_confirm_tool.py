@@ -0,0 +1,74 @@
+import json
+
+import httpx
+import pytest
+import requests
+
+from nlui import AsyncNLUIClient, InvalidArgumentsError, NLUIClient
+
+PROBLEMS = {
+    "error": "invalid arguments",
+    "problems": [{"path": "id", "message": "expected integer"}],
+}
+
+
+def _response(status: int, body: dict) -> requests.Response:
+    resp = requests.Response()
+    resp.status_code = status
+    resp._content = json.dumps(body).encode()
+    return resp
+
+
+def test_confirm_tool_sends_arguments(monkeypatch):
+    client = NLUIClient()
+    sent = {}
+
+    def post(url, json=None, timeout=None):
+        sent.update(url=url, body=json)
+        return _response(200, {"message": "confirmation sent"})
+
+    monkeypatch.setattr(client.session, "post", post)
+    client.confirm_tool("s1", True, '{"id": 7}')
+
+    assert sent["url"] == "http://localhost:9000/api/chat/confirm"
+    assert sent["body"] == {"session_id": "s1", "approved": True, "arguments": '{"id": 7}'}
+
+
+def test_confirm_tool_raises_invalid_arguments(monkeypatch):
+    client = NLUIClient()
+    monkeypatch.setattr(client.session, "post", lambda *a, **kw: _response(422, PROBLEMS))
+
+    with pytest.raises(InvalidArgumentsError) as exc:
+        client.confirm_tool("s1", True, '{"id": "x"}')
+
+    assert [(p.path, p.message) for p in exc.value.problems] == [("id", "expected integer")]
+    assert str(exc.value) == "invalid arguments: id: expected integer"
+
+
+def test_confirm_tool_other_errors(monkeypatch):
+    client = NLUIClient()
+    monkeypatch.setattr(
+        client.session, "post", lambda *a, **kw: _response(409, {"error": "no pending confirmation"})
+    )
+
+    with pytest.raises(requests.HTTPError):
+        client.confirm_tool("s1", False)
+
+
+@pytest.mark.asyncio
+async def test_async_confirm_tool_raises_invalid_arguments():
+    sent = []
+
+    def handler(request: httpx.Request) -> httpx.Response:
+        sent.append(json.loads(request.content))
+        return httpx.Response(422, json=PROBLEMS)
+
+    client = AsyncNLUIClient()
+    client.client = httpx.AsyncClient(transport=httpx.MockTransport(handler))
+
+    with pytest.raises(InvalidArgumentsError) as exc:
+        await client.confirm_tool("s1", True, '{"id": "x"}')
+    await client.close()
+
+    assert sent == [{"session_id": "s1", "approved": True, "arguments": '{"id": "x"}'}]
+    assert exc.value.problems[0].path == "id"
@@ -100,6 +100,11 @@ export function ChatInterface({
                 return prev;
               });
             }
+          } else if (event.type === "content_discarded") {
+            // The time budget cut the answer off; the wrap-up streams next.
+            const discarded = streamId;
+            streamId = "";
+            setMessages((prev) => prev.filter((m) => m.id !== discarded));
           } else if (event.type === "tool_call") {
             streamId = "";
             const { name, arguments: args } = event.data;
@@ -92,6 +92,13 @@ export function useChat(
                 }
                 return newMessages;
               });
+            } else if (event.type === "content_discarded") {
+              // The time budget cut the answer off; the wrap-up streams next.
+              assistantContent = "";
+              setMessages((prev) => {
+                const lastMsg = prev[prev.length - 1];
+                return lastMsg && lastMsg.role === "assistant" ? prev.slice(0, -1) : prev;
+              });
             } else if (event.type === "content") {
               assistantContent = event.data.text;
               setMessages((prev) => {
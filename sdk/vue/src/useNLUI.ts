@@ -111,6 +111,8 @@ export function useChat(client: Ref<NLUIClient>, options: UseChatOptions = {}) {
 
           if (event.type === 'content_delta') {
             assistantMessage.content += event.data.delta;
+          } else if (event.type === 'content_discarded') {
+            assistantMessage.content = '';
           } else if (event.type === 'content') {
             assistantMessage.content = event.data.text;
           }
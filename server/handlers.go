@@ -7,18 +7,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/bootstrap"
 	"github.com/ZacharyZcR/NLUI/config"
 	"github.com/ZacharyZcR/NLUI/core/conversation"
 	"github.com/ZacharyZcR/NLUI/core/llm"
+	"github.com/ZacharyZcR/NLUI/core/tokenizer"
 	"github.com/ZacharyZcR/NLUI/engine"
 	"github.com/ZacharyZcR/NLUI/presets"
 	"github.com/ZacharyZcR/NLUI/service"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // ============= Phase 1: Targets Management =============
@@ -229,6 +234,63 @@ func (s *Server) updateConversationTools(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "tool configuration updated"})
 }
 
+// getConversationBudget returns the conversation's budget override ({} = engine defaults)
+func (s *Server) getConversationBudget(c *gin.Context) {
+	conv := s.engine.GetConversation(c.Param("id"))
+	if conv == nil {
+		c.JSON(404, gin.H{"error": "conversation not found"})
+		return
+	}
+	budget := conv.Budget
+	if budget == nil {
+		budget = &engine.ConversationBudget{}
+	}
+	c.JSON(200, budget)
+}
+
+// updateConversationBudget overrides per-turn limits for a conversation; zero fields use the engine defaults
+func (s *Server) updateConversationBudget(c *gin.Context) {
+	var req engine.ConversationBudget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MaxIterations < 0 || req.MaxToolCalls < 0 || req.MaxTokens < 0 || req.MaxDurationSec < 0 {
+		c.JSON(400, gin.H{"error": "budget limits must not be negative"})
+		return
+	}
+
+	budget := &req
+	if req == (engine.ConversationBudget{}) {
+		budget = nil
+	}
+	if err := s.engine.UpdateBudget(c.Param("id"), budget); err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "budget updated"})
+}
+
+// updateConversationDryRun turns dry-run mode on or off for a conversation
+func (s *Server) updateConversationDryRun(c *gin.Context) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.engine.UpdateDryRun(c.Param("id"), req.Enabled); err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "dry run updated"})
+}
+
 // ============= Phase 3: Message Editing & Regeneration =============
 
 type EditMessageRequest struct {
@@ -355,14 +417,23 @@ func (s *Server) deleteMessagesFrom(c *gin.Context) {
 // ============= Phase 4: LLM Configuration =============
 
 type LLMConfigRequest struct {
-	APIBase string `json:"api_base"`
-	APIKey  string `json:"api_key"`
-	Model   string `json:"model"`
+	Provider *string `json:"provider"` // omitted = keep the current provider
+	APIBase  string  `json:"api_base"`
+	APIKey   string  `json:"api_key"`
+	Model    string  `json:"model"`
+
+	// Azure OpenAI (provider "azure"); omitted fields keep their current value.
+	Deployment *string `json:"deployment"`
+	APIVersion *string `json:"api_version"`
+
+	// Sampling parameters are decoded from the same body into a
+	// config.SamplingUpdate: omitted fields keep their value, null clears it.
 }
 
 type FetchModelsRequest struct {
-	APIBase string `json:"api_base" binding:"required"`
-	APIKey  string `json:"api_key"`
+	Provider string `json:"provider"` // "" = detect from api_base
+	APIBase  string `json:"api_base" binding:"required"`
+	APIKey   string `json:"api_key"`
 }
 
 // getLLMConfig returns current LLM configuration
@@ -372,24 +443,47 @@ func (s *Server) getLLMConfig(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	sampling := cfg.LLM.SamplingConfig
 	c.JSON(200, gin.H{
-		"api_base": cfg.LLM.APIBase,
-		"api_key":  service.MaskKey(cfg.LLM.APIKey),
-		"model":    cfg.LLM.Model,
-		"stream":   cfg.LLM.IsStream(),
-		"language": cfg.Language,
+		"provider":            cfg.LLM.Provider,
+		"api_base":            cfg.LLM.APIBase,
+		"api_key":             service.MaskKey(cfg.LLM.APIKey),
+		"model":               cfg.LLM.Model,
+		"deployment":          cfg.LLM.Deployment,
+		"api_version":         cfg.LLM.APIVersion,
+		"stream":              cfg.LLM.IsStream(),
+		"language":            cfg.Language,
+		"temperature":         sampling.Temperature,
+		"max_tokens":          sampling.MaxTokens,
+		"top_p":               sampling.TopP,
+		"seed":                sampling.Seed,
+		"parallel_tool_calls": sampling.ParallelToolCalls,
+		"tool_choice":         sampling.ToolChoice,
 	})
 }
 
 // updateLLMConfig updates LLM configuration
 func (s *Server) updateLLMConfig(c *gin.Context) {
 	var req LLMConfigRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var sampling config.SamplingUpdate
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindBodyWith(&sampling, binding.JSON); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := s.svc.SaveLLMConfig(req.APIBase, req.APIKey, req.Model); err != nil {
+	if err := s.svc.SaveLLMConfig(service.LLMUpdate{
+		Provider:   req.Provider,
+		APIBase:    req.APIBase,
+		APIKey:     req.APIKey,
+		Model:      req.Model,
+		Deployment: req.Deployment,
+		APIVersion: req.APIVersion,
+		Sampling:   sampling,
+	}); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
@@ -407,7 +501,7 @@ func (s *Server) probeLLMProviders(c *gin.Context) {
 	c.JSON(200, service.ProviderPresets())
 }
 
-// fetchModels queries /v1/models for any OpenAI-compatible endpoint
+// fetchModels lists the models of an endpoint (deployments for Azure OpenAI)
 func (s *Server) fetchModels(c *gin.Context) {
 	var req FetchModelsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -415,9 +509,20 @@ func (s *Server) fetchModels(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement model fetching logic
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.cfg.Proxy != "" {
+		if proxyURL, err := url.Parse(s.cfg.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: transport}
 
-	c.JSON(200, []string{"model-1", "model-2"})
+	models, err := llm.ListModels(c.Request.Context(), client, req.Provider, req.APIBase, req.APIKey)
+	if err != nil {
+		c.JSON(502, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, models)
 }
 
 // updateStream toggles streaming mode
@@ -537,7 +642,14 @@ func (s *Server) reloadEngine() error {
 		_ = s.svc.SaveTargetAuth(configName, token)
 	}
 
-	llmClient := llm.NewAutoClient(s.cfg.LLM.APIBase, s.cfg.LLM.APIKey, s.cfg.LLM.Model, s.cfg.Proxy, s.cfg.LLM.IsStream())
+	llmClient, err := bootstrap.NewLLMClient(s.cfg)
+	if err != nil {
+		return err
+	}
+	toolPolicy, err := bootstrap.NewPolicy(s.cfg)
+	if err != nil {
+		return err
+	}
 
 	if s.convMgr == nil {
 		convDir := ""
@@ -548,12 +660,24 @@ func (s *Server) reloadEngine() error {
 	}
 
 	s.engine = engine.New(engine.Config{
-		LLM:          llmClient,
-		Executor:     res.Router,
-		Tools:        res.Tools,
-		SystemPrompt: res.SystemPrompt,
-		MaxCtxTokens: s.cfg.LLM.MaxCtxTokens,
-		ConvMgr:      s.convMgr,
+		LLM:           llmClient,
+		Executor:      res.Router,
+		Tools:         res.Tools,
+		SystemPrompt:  res.SystemPrompt,
+		MaxCtxTokens:  s.cfg.LLM.MaxCtxTokens,
+		Compact:       s.cfg.LLM.SummarizesContext(),
+		TokenCounter:  tokenizer.ForModel(s.cfg.LLM.Model),
+		Model:         s.cfg.LLM.Model,
+		Prices:        bootstrap.NewPriceTable(s.cfg),
+		ToolWorkers:   s.cfg.Tools.Concurrency,
+		ResultLimit:   s.cfg.Tools.ResultLimit,
+		ResultLimits:  s.cfg.Tools.ResultLimits,
+		LoopThreshold: s.cfg.Tools.LoopThreshold,
+		RetrieveTools: s.cfg.Tools.Retrieval.TopK,
+		Embedder:      bootstrap.NewEmbedder(s.cfg),
+		Budget:        bootstrap.TurnBudget(s.cfg),
+		Policy:        toolPolicy,
+		ConvMgr:       s.convMgr,
 	})
 	return nil
 }
@@ -615,11 +739,13 @@ func (s *Server) stopChat(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "chat stopped"})
 }
 
-// confirmTool approves or rejects a dangerous tool call.
+// confirmTool approves or rejects a tool call awaiting confirmation. An
+// approval may carry edited arguments, which must match the tool's schema.
 func (s *Server) confirmTool(c *gin.Context) {
 	var req struct {
 		SessionID string `json:"session_id" binding:"required"`
 		Approved  bool   `json:"approved"`
+		Arguments string `json:"arguments"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
@@ -635,8 +761,18 @@ func (s *Server) confirmTool(c *gin.Context) {
 		return
 	}
 
+	if req.Approved && req.Arguments != "" {
+		session.mu.Lock()
+		tool := session.pending
+		session.mu.Unlock()
+		if problems := s.engine.ValidateToolArguments(tool, req.Arguments); len(problems) > 0 {
+			c.JSON(422, gin.H{"error": "invalid arguments", "problems": problems})
+			return
+		}
+	}
+
 	select {
-	case session.confirmCh <- req.Approved:
+	case session.confirmCh <- engine.ConfirmResponse{Approved: req.Approved, Arguments: req.Arguments}:
 		c.JSON(200, gin.H{"message": "confirmation sent"})
 	default:
 		c.JSON(409, gin.H{"error": "no pending confirmation"})
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ZacharyZcR/NLUI/config"
 	"github.com/ZacharyZcR/NLUI/core/conversation"
+	"github.com/ZacharyZcR/NLUI/core/llm"
 	"github.com/ZacharyZcR/NLUI/engine"
 	"github.com/ZacharyZcR/NLUI/service"
 	"github.com/gin-contrib/cors"
@@ -17,7 +21,10 @@ import (
 
 type chatSession struct {
 	cancel    context.CancelFunc
-	confirmCh chan bool
+	confirmCh chan engine.ConfirmResponse
+
+	mu      sync.Mutex
+	pending string // tool awaiting confirmation, for validating edited arguments
 }
 
 type Server struct {
@@ -31,10 +38,22 @@ type Server struct {
 }
 
 type ChatRequest struct {
-	ConversationID string `json:"conversation_id"`
-	Message        string `json:"message"`
+	ConversationID string       `json:"conversation_id"`
+	Message        string       `json:"message"`
+	Attachments    []Attachment `json:"attachments,omitempty"`
+	DryRun         bool         `json:"dry_run,omitempty"` // preview tool requests instead of sending them
+}
+
+// Attachment is an image or file sent with a chat message, as an https URL
+// or a data URL (data:<mime>;base64,<data>).
+type Attachment struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename,omitempty"`
 }
 
+// maxAttachmentBytes caps each uploaded file in multipart chat requests.
+const maxAttachmentBytes = 20 << 20
+
 func New(cfg *config.Config, eng *engine.Engine, configPath string) *Server {
 	s := &Server{
 		cfg:      cfg,
@@ -74,6 +93,9 @@ func New(cfg *config.Config, eng *engine.Engine, configPath string) *Server {
 		api.GET("/conversations/:id", s.getConversation)
 		api.DELETE("/conversations/:id", s.deleteConversation)
 
+		// Usage & cost
+		api.GET("/usage", s.usageReport)
+
 		// Phase 1: Targets Management
 		api.GET("/targets", s.listTargets)
 		api.POST("/targets", s.addTarget)
@@ -87,6 +109,9 @@ func New(cfg *config.Config, eng *engine.Engine, configPath string) *Server {
 		api.GET("/tools/sources", s.listToolSources)
 		api.GET("/conversations/:id/tools", s.getConversationTools)
 		api.PUT("/conversations/:id/tools", s.updateConversationTools)
+		api.GET("/conversations/:id/budget", s.getConversationBudget)
+		api.PUT("/conversations/:id/budget", s.updateConversationBudget)
+		api.PUT("/conversations/:id/dry_run", s.updateConversationDryRun)
 
 		// Phase 3: Message Editing & Regeneration
 		api.PUT("/conversations/:id/messages/:index", s.editMessage)
@@ -127,15 +152,23 @@ func (s *Server) info(c *gin.Context) {
 }
 
 func (s *Server) chat(c *gin.Context) {
-	var req ChatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	req, err := bindChatRequest(c)
+	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	if req.Message == "" {
+	if req.Message == "" && len(req.Attachments) == 0 {
 		c.JSON(400, gin.H{"error": "message is required"})
 		return
 	}
+	parts := make([]llm.ContentPart, 0, len(req.Attachments))
+	for _, a := range req.Attachments {
+		if a.URL == "" {
+			c.JSON(400, gin.H{"error": "attachment url is required"})
+			return
+		}
+		parts = append(parts, llm.PartFromURL(a.URL, a.Filename))
+	}
 
 	authToken := ""
 	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
@@ -145,7 +178,10 @@ func (s *Server) chat(c *gin.Context) {
 	// Create session
 	sessionID := generateSessionID()
 	ctx, cancel := context.WithCancel(c.Request.Context())
-	session := &chatSession{cancel: cancel, confirmCh: make(chan bool, 1)}
+	if req.DryRun {
+		ctx = engine.WithDryRun(ctx)
+	}
+	session := &chatSession{cancel: cancel, confirmCh: make(chan engine.ConfirmResponse, 1)}
 	s.sessionsMu.Lock()
 	s.sessions[sessionID] = session
 	s.sessionsMu.Unlock()
@@ -166,24 +202,29 @@ func (s *Server) chat(c *gin.Context) {
 		c.Writer.Flush()
 	}
 
-	confirm := func(toolName, argsJSON string) bool {
+	confirm := func(req engine.ConfirmRequest) engine.ConfirmResponse {
+		session.mu.Lock()
+		session.pending = req.Name
+		session.mu.Unlock()
 		if data, err := json.Marshal(gin.H{
 			"session_id": sessionID,
-			"name":       toolName,
-			"arguments":  argsJSON,
+			"id":         req.ID,
+			"name":       req.Name,
+			"arguments":  req.Arguments,
+			"rule":       req.Rule,
 		}); err == nil {
 			fmt.Fprintf(c.Writer, "event: tool_confirm\ndata: %s\n\n", string(data))
 			c.Writer.Flush()
 		}
 		select {
-		case approved := <-session.confirmCh:
-			return approved
+		case resp := <-session.confirmCh:
+			return resp
 		case <-ctx.Done():
-			return false
+			return engine.ConfirmResponse{}
 		}
 	}
 
-	convID, err := s.engine.Chat(ctx, req.ConversationID, req.Message, authToken, confirm, func(event engine.Event) {
+	convID, err := s.engine.ChatWithAttachments(ctx, req.ConversationID, req.Message, parts, authToken, confirm, func(event engine.Event) {
 		if data, err := json.Marshal(event.Data); err == nil {
 			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, string(data))
 			c.Writer.Flush()
@@ -203,6 +244,48 @@ func (s *Server) chat(c *gin.Context) {
 	}
 }
 
+// bindChatRequest accepts either a JSON body (attachments as data URLs) or a
+// multipart form with "message", "conversation_id" and uploaded "files".
+func bindChatRequest(c *gin.Context) (ChatRequest, error) {
+	var req ChatRequest
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		err := c.ShouldBindJSON(&req)
+		return req, err
+	}
+
+	req.ConversationID = c.PostForm("conversation_id")
+	req.Message = c.PostForm("message")
+	req.DryRun = c.PostForm("dry_run") == "true"
+	form, err := c.MultipartForm()
+	if err != nil {
+		return req, err
+	}
+	for _, fh := range form.File["files"] {
+		if fh.Size > maxAttachmentBytes {
+			return req, fmt.Errorf("attachment %s exceeds %d MB", fh.Filename, maxAttachmentBytes>>20)
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return req, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return req, err
+		}
+		mimeType := fh.Header.Get("Content-Type")
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			mimeType = http.DetectContentType(data)
+		}
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		req.Attachments = append(req.Attachments, Attachment{
+			URL:      llm.DataURL(mimeType, data),
+			Filename: fh.Filename,
+		})
+	}
+	return req, nil
+}
+
 func (s *Server) listConversations(c *gin.Context) {
 	c.JSON(200, s.engine.ListConversations())
 }
@@ -232,3 +315,26 @@ func (s *Server) deleteConversation(c *gin.Context) {
 	s.engine.DeleteConversation(c.Param("id"))
 	c.Status(204)
 }
+
+// usageReport sums token usage and cost by day, model and conversation.
+// Optional from/to query parameters (YYYY-MM-DD, inclusive) limit the range.
+func (s *Server) usageReport(c *gin.Context) {
+	var from, to time.Time
+	if v := c.Query("from"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid from date, want YYYY-MM-DD"})
+			return
+		}
+		from = d
+	}
+	if v := c.Query("to"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid to date, want YYYY-MM-DD"})
+			return
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+	c.JSON(200, s.engine.UsageReport(from, to))
+}
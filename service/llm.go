@@ -8,11 +8,35 @@ type ProviderInfo struct {
 	Models  []string `json:"models"`
 }
 
-func (s *Service) SaveLLMConfig(apiBase, apiKey, model string) error {
+// LLMUpdate changes the backend settings. Nil fields keep their current
+// value, so clients that don't know about provider or Azure settings can't
+// wipe them.
+type LLMUpdate struct {
+	Provider   *string
+	APIBase    string
+	APIKey     string
+	Model      string
+	Deployment *string
+	APIVersion *string
+	Sampling   config.SamplingUpdate
+}
+
+// SaveLLMConfig stores the backend settings.
+func (s *Service) SaveLLMConfig(u LLMUpdate) error {
 	return s.ModifyConfig(func(cfg *config.Config) error {
-		cfg.LLM.APIBase = apiBase
-		cfg.LLM.APIKey = apiKey
-		cfg.LLM.Model = model
+		if u.Provider != nil {
+			cfg.LLM.Provider = *u.Provider
+		}
+		cfg.LLM.APIBase = u.APIBase
+		cfg.LLM.APIKey = u.APIKey
+		cfg.LLM.Model = u.Model
+		if u.Deployment != nil {
+			cfg.LLM.Deployment = *u.Deployment
+		}
+		if u.APIVersion != nil {
+			cfg.LLM.APIVersion = *u.APIVersion
+		}
+		cfg.LLM.SamplingConfig = cfg.LLM.SamplingConfig.Apply(u.Sampling)
 		return nil
 	})
 }